@@ -21,6 +21,7 @@ var (
 	localSKStr  = flag.String("sk", "", "Local SecKey")
 	passcode    = flag.String("passcode", "", "Passcode to authenticate connection")
 	killswitch  = flag.Bool("killswitch", false, "If set, the Internet won't be restored during reconnection attempts")
+	datagram    = flag.Bool("datagram", true, "If set, traffic is passed as datagrams if server supports it")
 )
 
 func main() {
@@ -96,6 +97,7 @@ func main() {
 		Passcode:   *passcode,
 		Killswitch: *killswitch,
 		ServerPK:   serverPK,
+		Datagram:   *datagram,
 	}
 	vpnClient, err := vpn.NewClient(vpnClientCfg, appClient)
 	if err != nil {
@@ -20,6 +20,8 @@ import (
 const (
 	netType = appnet.TypeSkynet
 	vpnPort = routing.Port(skyenv.VPNServerPort)
+	// vpnDatagramPort is the port VPN clients pass their traffic as datagrams to.
+	vpnDatagramPort = routing.Port(skyenv.VPNServerDatagramPort)
 )
 
 var (
@@ -72,6 +74,13 @@ func main() {
 
 	log.Infof("Got app listener, bound to %d", vpnPort)
 
+	pc, err := appClient.ListenPacket(netType, vpnDatagramPort)
+	if err != nil {
+		log.WithError(err).Warnf("Error listening datagrams on port %d, traffic goes over streams only", vpnDatagramPort)
+	} else {
+		log.Infof("Got app packet conn, bound to %d", vpnDatagramPort)
+	}
+
 	srvCfg := vpn.ServerConfig{
 		Passcode: *passcode,
 		Secure:   *secure,
@@ -86,6 +95,14 @@ func main() {
 		}
 	}()
 
+	if pc != nil {
+		go func() {
+			if err := srv.ServeDatagrams(pc); err != nil {
+				log.WithError(err).Errorln("Error serving datagrams")
+			}
+		}()
+	}
+
 	errCh := make(chan error)
 	go func() {
 		if err := srv.Serve(l); err != nil {
@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 	"github.com/sirupsen/logrus"
 	"github.com/skycoin/dmsg/buildinfo"
 	"github.com/skycoin/dmsg/cmdutil"
@@ -23,6 +26,7 @@ import (
 
 var (
 	metricsAddr  string
+	adminAddr    string
 	syslogAddr   string
 	tag          string
 	cfgFromStdin bool
@@ -30,6 +34,7 @@ var (
 
 func init() {
 	rootCmd.Flags().StringVarP(&metricsAddr, "metrics", "m", "", "address to bind metrics API to")
+	rootCmd.Flags().StringVar(&adminAddr, "admin", "", "address to bind admin API listing in-flight requests to")
 	rootCmd.Flags().StringVar(&syslogAddr, "syslog", "", "syslog server address. E.g. localhost:514")
 	rootCmd.Flags().StringVar(&tag, "tag", "setup_node", "logging tag")
 	rootCmd.Flags().BoolVarP(&cfgFromStdin, "stdin", "i", false, "read config from STDIN")
@@ -105,6 +110,7 @@ var rootCmd = &cobra.Command{
 		}
 
 		m := prepareMetrics(log)
+		serveAdmin(log, sn)
 
 		ctx, cancel := cmdutil.SignalContext(context.Background(), log)
 		defer cancel()
@@ -129,6 +135,24 @@ func prepareMetrics(log logrus.FieldLogger) setupmetrics.Metrics {
 	return m
 }
 
+func serveAdmin(log logrus.FieldLogger, sn *setup.Node) {
+	if adminAddr == "" {
+		return
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Method(http.MethodGet, "/requests", sn.Requests())
+
+	log.WithField("addr", adminAddr).Info("Serving admin API.")
+
+	go func() {
+		if err := http.ListenAndServe(adminAddr, r); err != nil {
+			log.WithError(err).Error("Admin API stopped serving.")
+		}
+	}()
+}
+
 // Execute executes root CLI command.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
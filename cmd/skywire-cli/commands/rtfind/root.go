@@ -2,9 +2,12 @@ package rtfind
 
 import (
 	"fmt"
+	"net"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
 
@@ -12,17 +15,27 @@ import (
 	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/routing"
 	"github.com/skycoin/skywire/pkg/skyenv"
+	"github.com/skycoin/skywire/pkg/visor"
 )
 
-var frAddr string
+var logger = logging.MustGetLogger("skywire-cli")
+
+var frAddr, rpcAddr string
 var frMinHops, frMaxHops uint16
 var timeout time.Duration
+var excludePKs, tpTypes []string
+var scoring string
 
 func init() {
 	RootCmd.Flags().StringVar(&frAddr, "addr", skyenv.DefaultRouteFinderAddr, "address in which to contact route finder service")
 	RootCmd.Flags().Uint16Var(&frMinHops, "min-hops", 1, "min hops for the returning routeFinderRoutesCmd")
 	RootCmd.Flags().Uint16Var(&frMaxHops, "max-hops", 1000, "max hops for the returning routeFinderRoutesCmd")
 	RootCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "timeout for remote server requests")
+	RootCmd.Flags().StringSliceVar(&excludePKs, "exclude", nil, "public keys of visors routes may not go through")
+	RootCmd.Flags().StringSliceVar(&tpTypes, "tp-types", nil, "types of transports routes may go via (any type if not set)")
+	RootCmd.Flags().StringVar(&scoring, "scoring", "", "order of the returned routes (hops|latency)")
+	RootCmd.Flags().StringVar(&rpcAddr, "rpc", "localhost:3435",
+		"RPC address of the visor whose probe stats latency scoring uses")
 }
 
 // RootCmd is the command that queries the route finder.
@@ -39,11 +52,75 @@ var RootCmd = &cobra.Command{
 		forward := [2]cipher.PubKey{srcPK, dstPK}
 		backward := [2]cipher.PubKey{dstPK, srcPK}
 		ctx := context.Background()
+		prefs := routePreferences()
 		routes, err := rfc.FindRoutes(ctx, []routing.PathEdges{forward, backward},
-			&rfclient.RouteOptions{MinHops: frMinHops, MaxHops: frMaxHops})
+			&rfclient.RouteOptions{MinHops: frMinHops, MaxHops: frMaxHops, RoutePreferences: prefs})
 		internal.Catch(err)
 
-		fmt.Println("forward: ", routes[forward][0])
-		fmt.Println("reverse: ", routes[backward][0])
+		// route finder may not support all of the preferences
+		fwd, rev := prefs.FilterPaths(routes[forward]), prefs.FilterPaths(routes[backward])
+		if len(fwd) == 0 || len(rev) == 0 {
+			internal.Catch(rfclient.ErrTransportNotFound)
+		}
+
+		var weight rfclient.WeightFunc
+		if prefs.Scoring == rfclient.ScoreLatency {
+			weight = localWeights()
+		}
+
+		prefs.SortPaths(fwd, weight)
+		prefs.SortPaths(rev, weight)
+
+		fmt.Println("forward: ", fwd[0])
+		fmt.Println("reverse: ", rev[0])
 	},
 }
+
+func routePreferences() rfclient.RoutePreferences {
+	mode, err := rfclient.ParseScoringMode(scoring)
+	internal.Catch(err)
+
+	prefs := rfclient.RoutePreferences{
+		ExcludePKs: make([]cipher.PubKey, len(excludePKs)),
+		TpTypes:    tpTypes,
+		Scoring:    mode,
+	}
+
+	for i, s := range excludePKs {
+		internal.Catch(prefs.ExcludePKs[i].Set(s))
+	}
+
+	return prefs
+}
+
+// localWeights returns weights of the transports measured by the local visor. Transports
+// weigh the default weight if the visor is unreachable.
+func localWeights() rfclient.WeightFunc {
+	const rpcDialTimeout = time.Second * 5
+
+	conn, err := net.DialTimeout("tcp", rpcAddr, rpcDialTimeout)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to connect to the visor, transports aren't measured.")
+		return nil
+	}
+
+	rpc := visor.NewRPCClient(logger, conn, visor.RPCPrefix, 0)
+
+	tps, err := rpc.Transports(nil, nil, false, time.Time{})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get transports of the visor, transports aren't measured.")
+		return nil
+	}
+
+	weights := make(map[uuid.UUID]time.Duration, len(tps))
+	for _, tp := range tps {
+		if tp.Probe != nil {
+			weights[tp.ID] = tp.Probe.Weight()
+		}
+	}
+
+	return func(tpID uuid.UUID) (time.Duration, bool) {
+		w, ok := weights[tpID]
+		return w, ok
+	}
+}
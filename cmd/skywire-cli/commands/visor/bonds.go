@@ -0,0 +1,77 @@
+package visor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
+	"github.com/skycoin/skywire/pkg/visor"
+)
+
+func init() {
+	RootCmd.AddCommand(
+		lsBondsCmd,
+		addBondCmd,
+		rmBondCmd,
+	)
+}
+
+var lsBondsCmd = &cobra.Command{
+	Use:   "ls-bonds",
+	Short: "Lists the bonds of transports",
+	Run: func(_ *cobra.Command, _ []string) {
+		bonds, err := rpcClient().Bonds()
+		internal.Catch(err)
+		printBonds(bonds...)
+	},
+}
+
+var bondTypes []string
+
+func init() {
+	addBondCmd.Flags().StringSliceVar(&bondTypes, "types", bondTypes,
+		"comma-separated; if specified, only transports of given types are bonded")
+}
+
+var addBondCmd = &cobra.Command{
+	Use:   "add-bond <remote-public-key>",
+	Short: "Bonds transports to the remote visor, bond id may be used in routing rules as a transport id",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		pk := internal.ParsePK("remote-public-key", args[0])
+		bond, err := rpcClient().AddBond(pk, bondTypes)
+		internal.Catch(err)
+		printBonds(bond)
+	},
+}
+
+var rmBondCmd = &cobra.Command{
+	Use:   "rm-bond <bond-id>",
+	Short: "Removes bond with given id, transports of the bond are kept",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		id := internal.ParseUUID("bond-id", args[0])
+		internal.Catch(rpcClient().RemoveBond(id))
+		fmt.Println("OK")
+	},
+}
+
+func printBonds(bonds ...*visor.BondSummary) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+	_, err := fmt.Fprintln(w, "id\tremote\ttypes\tmembers\tis_up")
+	internal.Catch(err)
+	for _, b := range bonds {
+		types := "any"
+		if len(b.Types) > 0 {
+			types = strings.Join(b.Types, ",")
+		}
+
+		_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%v\n", b.ID, b.Remote, types, len(b.Members), b.IsUp)
+		internal.Catch(err)
+	}
+	internal.Catch(w.Flush())
+}
@@ -22,17 +22,18 @@ func init() {
 		ruleCmd,
 		rmRuleCmd,
 		addRuleCmd,
+		traceRouteCmd,
 	)
 }
 
 var lsRulesCmd = &cobra.Command{
 	Use:   "ls-rules",
-	Short: "Lists the local visor's routing rules",
+	Short: "Lists the local visor's routing rules along with their forwarding statistics",
 	Run: func(_ *cobra.Command, _ []string) {
-		rules, err := rpcClient().RoutingRules()
+		entries, err := rpcClient().RoutingRules()
 		internal.Catch(err)
 
-		printRoutingRules(rules...)
+		printRuleEntries(entries...)
 	},
 }
 
@@ -118,31 +119,89 @@ var addRuleCmd = &cobra.Command{
 	},
 }
 
-func printRoutingRules(rules ...routing.Rule) {
-	printConsumeRule := func(w io.Writer, id routing.RouteID, s *routing.RuleSummary) {
-		_, err := fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", id, s.Type,
-			s.ConsumeFields.RouteDescriptor.SrcPort, s.ConsumeFields.RouteDescriptor.DstPort,
-			s.ConsumeFields.RouteDescriptor.DstPK, "-", "-", "-", s.KeepAlive)
+var traceRouteCmd = &cobra.Command{
+	Use:   "traceroute <route-id>",
+	Short: "Traces a route group via the route ID of its consume rule",
+	Long: "Traces a route group via the route ID of its consume rule (see ls-rules).\n" +
+		"Timestamps are taken from the clocks of the hops, so only the ones of the local visor are directly comparable.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		id := routing.RouteID(parseUint("route-id", args[0], 32))
+
+		hops, err := rpcClient().TraceRoute(id)
 		internal.Catch(err)
-	}
-	printFwdRule := func(w io.Writer, id routing.RouteID, s *routing.RuleSummary) {
-		_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", id, s.Type, "-",
-			"-", "-", "-", s.IntermediaryForwardFields.NextRID, s.IntermediaryForwardFields.NextTID, s.KeepAlive)
+
+		printTraceHops(hops)
+	},
+}
+
+func printTraceHops(hops []routing.TraceHop) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+	_, err := fmt.Fprintln(w, "hop\tpk\ttime\tsince-start")
+	internal.Catch(err)
+
+	for i, hop := range hops {
+		_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i, hop.PK, hop.Time.Format(time.RFC3339Nano),
+			hop.Time.Sub(hops[0].Time))
 		internal.Catch(err)
 	}
+
+	internal.Catch(w.Flush())
+}
+
+func printRoutingRules(rules ...routing.Rule) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
 	_, err := fmt.Fprintln(w, "id\ttype\tlocal-port\tremote-port\tremote-pk\tresp-id\tnext-route-id\tnext-transport-id\texpire-at")
 	internal.Catch(err)
 	for _, rule := range rules {
-		if rule.Summary().ConsumeFields != nil {
-			printConsumeRule(w, rule.KeyRouteID(), rule.Summary())
-		} else {
-			printFwdRule(w, rule.NextRouteID(), rule.Summary())
+		printRuleFields(w, rule)
+		_, err := fmt.Fprintln(w)
+		internal.Catch(err)
+	}
+	internal.Catch(w.Flush())
+}
+
+func printRuleEntries(entries ...routing.RuleEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+	_, err := fmt.Fprintln(w, "id\ttype\tlocal-port\tremote-port\tremote-pk\tresp-id\tnext-route-id\tnext-transport-id\texpire-at\t"+
+		"packets\tbytes\tlast-error")
+	internal.Catch(err)
+	for _, entry := range entries {
+		printRuleFields(w, entry.Rule)
+
+		lastErr := "-"
+		if entry.Stats.LastError != "" {
+			lastErr = fmt.Sprintf("%s (%s ago)", entry.Stats.LastError,
+				time.Since(entry.Stats.LastErrorAt).Truncate(time.Second))
 		}
+
+		_, err := fmt.Fprintf(w, "\t%d\t%d\t%s\n", entry.Stats.Packets, entry.Stats.Bytes, lastErr)
+		internal.Catch(err)
 	}
 	internal.Catch(w.Flush())
 }
 
+// printRuleFields prints fields of the `rule` without the trailing line break.
+func printRuleFields(w io.Writer, rule routing.Rule) {
+	s := rule.Summary()
+
+	var err error
+	switch {
+	case s.ConsumeFields != nil:
+		_, err = fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s", rule.KeyRouteID(), s.Type,
+			s.ConsumeFields.RouteDescriptor.SrcPort, s.ConsumeFields.RouteDescriptor.DstPort,
+			s.ConsumeFields.RouteDescriptor.DstPK, "-", "-", "-", s.KeepAlive)
+	case s.ForwardFields != nil:
+		_, err = fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\t%d\t%s\t%s", rule.KeyRouteID(), s.Type,
+			s.ForwardFields.RouteDescriptor.SrcPort, s.ForwardFields.RouteDescriptor.DstPort,
+			s.ForwardFields.RouteDescriptor.DstPK, "-", s.ForwardFields.NextRID, s.ForwardFields.NextTID, s.KeepAlive)
+	default:
+		_, err = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s", rule.KeyRouteID(), s.Type, "-",
+			"-", "-", "-", s.IntermediaryForwardFields.NextRID, s.IntermediaryForwardFields.NextTID, s.KeepAlive)
+	}
+	internal.Catch(err)
+}
+
 func parseUint(name, v string, bitSize int) uint64 {
 	i, err := strconv.ParseUint(v, 10, bitSize)
 	internal.Catch(err, fmt.Sprintf("failed to parse <%s>:", name))
@@ -0,0 +1,97 @@
+package visor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+func init() {
+	RootCmd.AddCommand(
+		tpPolicyCmd,
+		setTpPolicyCmd,
+	)
+}
+
+var tpPolicyCmd = &cobra.Command{
+	Use:   "tp-policy",
+	Short: "Returns the policy incoming transports are accepted by",
+	Run: func(_ *cobra.Command, _ []string) {
+		policy, err := rpcClient().TransportPolicy()
+		internal.Catch(err)
+		printTpPolicy(policy)
+	},
+}
+
+var (
+	allowPKs     cipher.PubKeys
+	denyPKs      cipher.PubKeys
+	allowTypes   []string
+	denyTypes    []string
+	maxPerRemote int
+)
+
+func init() {
+	setTpPolicyCmd.Flags().Var(&allowPKs, "allow-pks", "comma-separated; if specified, only transports from given visors are accepted")
+	setTpPolicyCmd.Flags().Var(&denyPKs, "deny-pks", "comma-separated; transports from given visors are rejected")
+	setTpPolicyCmd.Flags().StringSliceVar(&allowTypes, "allow-types", allowTypes, "comma-separated; if specified, only transports of given types are accepted")
+	setTpPolicyCmd.Flags().StringSliceVar(&denyTypes, "deny-types", denyTypes, "comma-separated; transports of given types are rejected")
+	setTpPolicyCmd.Flags().IntVar(&maxPerRemote, "max-per-remote", 0, "maximum number of transports per remote visor, 0 means no limit")
+}
+
+var setTpPolicyCmd = &cobra.Command{
+	Use:   "set-tp-policy",
+	Short: "Sets the policy incoming transports are accepted by, the previous policy is replaced",
+	Run: func(_ *cobra.Command, _ []string) {
+		policy := transport.AcceptPolicy{
+			AllowPKs:     allowPKs,
+			DenyPKs:      denyPKs,
+			AllowTypes:   allowTypes,
+			DenyTypes:    denyTypes,
+			MaxPerRemote: maxPerRemote,
+		}
+		internal.Catch(rpcClient().SetTransportPolicy(policy))
+		printTpPolicy(policy)
+	},
+}
+
+func printTpPolicy(policy transport.AcceptPolicy) {
+	orAny := func(s []string) string {
+		if len(s) == 0 {
+			return "any"
+		}
+		return strings.Join(s, ",")
+	}
+	orNone := func(s []string) string {
+		if len(s) == 0 {
+			return "none"
+		}
+		return strings.Join(s, ",")
+	}
+	maxTps := "no limit"
+	if policy.MaxPerRemote > 0 {
+		maxTps = fmt.Sprint(policy.MaxPerRemote)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+	_, err := fmt.Fprintf(w, "allow_pks\t%s\ndeny_pks\t%s\nallow_types\t%s\ndeny_types\t%s\nmax_per_remote\t%s\n",
+		orAny(pkStrings(policy.AllowPKs)), orNone(pkStrings(policy.DenyPKs)),
+		orAny(policy.AllowTypes), orNone(policy.DenyTypes), maxTps)
+	internal.Catch(err)
+	internal.Catch(w.Flush())
+}
+
+func pkStrings(pks []cipher.PubKey) []string {
+	out := make([]string, 0, len(pks))
+	for _, pk := range pks {
+		out = append(out, pk.String())
+	}
+	return out
+}
@@ -7,12 +7,11 @@ import (
 	"text/tabwriter"
 	"time"
 
-	"github.com/skycoin/dmsg"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/spf13/cobra"
 
 	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
-	"github.com/skycoin/skywire/pkg/snet/directtp/tptypes"
+	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/visor"
 )
 
@@ -54,12 +53,18 @@ var lsTpCmd = &cobra.Command{
 	Use:   "ls-tp",
 	Short: "Lists the available transports with optional filter flags",
 	Run: func(_ *cobra.Command, _ []string) {
-		transports, err := rpcClient().Transports(filterTypes, filterPubKeys, showLogs)
+		transports, err := rpcClient().Transports(filterTypes, filterPubKeys, showLogs, time.Time{})
 		internal.Catch(err)
 		printTransports(transports...)
 	},
 }
 
+var tpSince time.Duration
+
+func init() {
+	tpCmd.Flags().DurationVar(&tpSince, "since", 0, "if specified, shows hourly traffic of the transport over the given period, e.g. 24h")
+}
+
 var tpCmd = &cobra.Command{
 	Use:   "tp <transport-id>",
 	Short: "Returns summary of given transport by id",
@@ -69,6 +74,14 @@ var tpCmd = &cobra.Command{
 		tp, err := rpcClient().Transport(tpID)
 		internal.Catch(err)
 		printTransports(tp)
+
+		if tpSince > 0 {
+			now := time.Now()
+			history, err := rpcClient().TransportHistory(tpID, now.Add(-tpSince), now)
+			internal.Catch(err)
+			fmt.Println()
+			printTransportHistory(history)
+		}
 	},
 }
 
@@ -76,19 +89,22 @@ var (
 	transportType string
 	public        bool
 	timeout       time.Duration
+	persist       bool
 )
 
 func init() {
 	const (
-		typeFlagUsage = "type of transport to add; if unspecified, cli will attempt to establish a transport " +
-			"in the following order: stcp, stcpr, sudph, dmsg"
+		typeFlagUsage = "type of transport to add; if unspecified or 'auto', the visor picks the best available type " +
+			"in the following order: sudph, stcpr, stcp (if the remote is in the pk table), dmsg"
 		publicFlagUsage  = "whether to make the transport public"
 		timeoutFlagUsage = "if specified, sets an operation timeout"
+		persistFlagUsage = "whether to save the transport to the config, so the visor keeps it established across restarts"
 	)
 
 	addTpCmd.Flags().StringVar(&transportType, "type", "", typeFlagUsage)
 	addTpCmd.Flags().BoolVar(&public, "public", true, publicFlagUsage)
 	addTpCmd.Flags().DurationVarP(&timeout, "timeout", "t", 0, timeoutFlagUsage)
+	addTpCmd.Flags().BoolVar(&persist, "persist", false, persistFlagUsage)
 }
 
 var addTpCmd = &cobra.Command{
@@ -98,43 +114,49 @@ var addTpCmd = &cobra.Command{
 	Run: func(_ *cobra.Command, args []string) {
 		pk := internal.ParsePK("remote-public-key", args[0])
 
-		var tp *visor.TransportSummary
-		var err error
-
-		if transportType != "" {
-			tp, err = rpcClient().AddTransport(pk, transportType, public, timeout)
-			if err != nil {
-				logger.WithError(err).Fatalf("Failed to establish %v transport", transportType)
-			}
-
-			if !tp.IsUp {
-				logger.Fatalf("Established %v transport to %v with ID %v, but it isn't up", transportType, pk, tp.ID)
-			}
-
-			logger.Infof("Established %v transport to %v", transportType, pk)
-		} else {
-			transportTypes := []string{
-				tptypes.STCP,
-				tptypes.STCPR,
-				tptypes.SUDPH,
-				dmsg.Type,
-			}
-
-			for _, transportType := range transportTypes {
-				tp, err = rpcClient().AddTransport(pk, transportType, public, timeout)
-				if err == nil {
-					logger.Infof("Established %v transport to %v", transportType, pk)
-					break
-				}
-
-				logger.WithError(err).Warnf("Failed to establish %v transport", transportType)
-			}
+		if transportType == "" {
+			transportType = transport.AutoType
+		}
+
+		tp, err := rpcClient().AddTransport(pk, transportType, public, timeout)
+		if err != nil {
+			logger.WithError(err).Fatalf("Failed to establish %v transport", transportType)
+		}
+
+		if persist {
+			savePersistentTransport(transport.PersistentTransport{PK: pk, Type: transportType})
+		}
+
+		if !tp.IsUp {
+			logger.Fatalf("Established %v transport to %v with ID %v, but it isn't up", tp.Type, pk, tp.ID)
 		}
 
+		logger.Infof("Established %v transport to %v", tp.Type, pk)
+
 		printTransports(tp)
 	},
 }
 
+// savePersistentTransport adds the transport to the persistent transports of the visor unless it's there already.
+func savePersistentTransport(pt transport.PersistentTransport) {
+	pts, err := rpcClient().PersistentTransports()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to obtain persistent transports")
+	}
+
+	for _, v := range pts {
+		if v == pt {
+			return
+		}
+	}
+
+	if err := rpcClient().SetPersistentTransports(append(pts, pt)); err != nil {
+		logger.WithError(err).Fatal("Failed to save persistent transport")
+	}
+
+	logger.Infof("Saved %v transport to %v as persistent", pt.Type, pt.PK)
+}
+
 var rmTpCmd = &cobra.Command{
 	Use:   "rm-tp <transport-id>",
 	Short: "Removes transport with given id",
@@ -149,7 +171,7 @@ var rmTpCmd = &cobra.Command{
 func printTransports(tps ...*visor.TransportSummary) {
 	sortTransports(tps...)
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
-	_, err := fmt.Fprintln(w, "type\tid\tremote\tmode\tis_up")
+	_, err := fmt.Fprintln(w, "type\tid\tremote\tmode\tis_up\trtt\tloss")
 	internal.Catch(err)
 	for _, tp := range tps {
 		tpMode := "regular"
@@ -157,12 +179,37 @@ func printTransports(tps ...*visor.TransportSummary) {
 			tpMode = "setup"
 		}
 
-		_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\n", tp.Type, tp.ID, tp.Remote, tpMode, tp.IsUp)
+		rtt, loss := "-", "-"
+		if tp.Probe != nil {
+			rtt = tp.Probe.RTT.Round(time.Millisecond).String()
+			loss = fmt.Sprintf("%.0f%%", tp.Probe.Loss*100)
+		}
+
+		_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\t%s\n", tp.Type, tp.ID, tp.Remote, tpMode, tp.IsUp, rtt, loss)
 		internal.Catch(err)
 	}
 	internal.Catch(w.Flush())
 }
 
+func printTransportHistory(history []transport.LogBucket) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', tabwriter.TabIndent)
+	_, err := fmt.Fprintln(w, "start\trecv\tsent")
+	internal.Catch(err)
+
+	var recv, sent uint64
+	for _, b := range history {
+		_, err = fmt.Fprintf(w, "%s\t%d\t%d\n", b.Start.Local().Format(time.RFC3339), b.RecvBytes, b.SentBytes)
+		internal.Catch(err)
+
+		recv += b.RecvBytes
+		sent += b.SentBytes
+	}
+
+	_, err = fmt.Fprintf(w, "total\t%d\t%d\n", recv, sent)
+	internal.Catch(err)
+	internal.Catch(w.Flush())
+}
+
 func sortTransports(tps ...*visor.TransportSummary) {
 	sort.Slice(tps, func(i, j int) bool {
 		return tps[i].ID.String() < tps[j].ID.String()
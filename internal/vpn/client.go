@@ -317,11 +317,23 @@ func (c *Client) setupTUN(tunIP, tunGateway net.IP) error {
 }
 
 func (c *Client) serveConn(conn net.Conn) error {
-	tunIP, tunGateway, err := c.shakeHands(conn)
+	tunIP, tunGateway, datagramPort, err := c.shakeHands(conn)
 	if err != nil {
 		return fmt.Errorf("error during client/server handshake: %w", err)
 	}
 
+	pc, pcRemote, err := c.dialDatagrams(conn, datagramPort)
+	if err != nil {
+		return fmt.Errorf("error negotiating datagrams: %w", err)
+	}
+	if pc != nil {
+		defer func() {
+			if err := pc.Close(); err != nil {
+				fmt.Printf("Error closing app packet conn: %v\n", err)
+			}
+		}()
+	}
+
 	fmt.Printf("Performed handshake with %s\n", conn.RemoteAddr())
 	fmt.Printf("Local TUN IP: %s\n", tunIP.String())
 	fmt.Printf("Local TUN gateway: %s\n", tunGateway.String())
@@ -386,6 +398,45 @@ func (c *Client) serveConn(conn net.Conn) error {
 
 	connToTunDoneCh := make(chan struct{})
 	tunToConnCh := make(chan struct{})
+	if pc != nil {
+		// stream connection only controls the session, so it's done once either side closes it
+		connDoneCh := make(chan struct{})
+		go func() {
+			defer close(connDoneCh)
+
+			if err := waitConnClosed(conn); err != nil {
+				fmt.Printf("VPN server connection is closed: %v\n", err)
+			}
+		}()
+		go func() {
+			defer close(connToTunDoneCh)
+
+			if err := copyFromPacketConn(tun, pc); err != nil {
+				fmt.Printf("Error resending datagrams from VPN server to TUN %s: %v\n", tun.Name(), err)
+			}
+		}()
+		go func() {
+			defer close(tunToConnCh)
+
+			if err := copyToPacketConn(pc, pcRemote, tun); err != nil {
+				fmt.Printf("Error resending datagrams from TUN %s to VPN server: %v\n", tun.Name(), err)
+			}
+		}()
+
+		select {
+		case <-connDoneCh:
+		case <-connToTunDoneCh:
+		case <-tunToConnCh:
+		case <-c.closeC:
+		}
+
+		if err := c.setSysPrivileges(); err != nil {
+			fmt.Printf("Failed to setup system privileges for cleanup: %v\n", err)
+		}
+
+		return nil
+	}
+
 	// read all system traffic and pass it to the remote VPN server
 	go func() {
 		defer close(connToTunDoneCh)
@@ -625,10 +676,10 @@ func stcpEntitiesFromEnv() ([]net.IP, error) {
 	return stcpEntities, nil
 }
 
-func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error) {
+func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, datagramPort routing.Port, err error) {
 	unavailableIPs, err := LocalNetworkInterfaceIPs()
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting unavailable private IPs: %w", err)
+		return nil, nil, 0, fmt.Errorf("error getting unavailable private IPs: %w", err)
 	}
 
 	unavailableIPs = append(unavailableIPs, c.defaultGateway)
@@ -636,6 +687,7 @@ func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error)
 	cHello := ClientHello{
 		UnavailablePrivateIPs: unavailableIPs,
 		Passcode:              c.cfg.Passcode,
+		Datagram:              c.cfg.Datagram,
 	}
 
 	const handshakeTimeout = 5 * time.Second
@@ -643,21 +695,65 @@ func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error)
 	fmt.Printf("Sending client hello: %v\n", cHello)
 
 	if err := WriteJSONWithTimeout(conn, &cHello, handshakeTimeout); err != nil {
-		return nil, nil, fmt.Errorf("error sending client hello: %w", err)
+		return nil, nil, 0, fmt.Errorf("error sending client hello: %w", err)
 	}
 
 	var sHello ServerHello
 	if err := ReadJSONWithTimeout(conn, &sHello, handshakeTimeout); err != nil {
-		return nil, nil, fmt.Errorf("error reading server hello: %w", err)
+		return nil, nil, 0, fmt.Errorf("error reading server hello: %w", err)
 	}
 
 	fmt.Printf("Got server hello: %v", sHello)
 
 	if sHello.Status != HandshakeStatusOK {
-		return nil, nil, fmt.Errorf("got status %d (%s) from the server", sHello.Status, sHello.Status)
+		return nil, nil, 0, fmt.Errorf("got status %d (%s) from the server", sHello.Status, sHello.Status)
+	}
+
+	return sHello.TUNIP, sHello.TUNGateway, sHello.DatagramPort, nil
+}
+
+// dialDatagrams dials server datagram `port` and lets server know the local one. Returned
+// packet conn is nil if server doesn't support datagrams or dial fails, in which case traffic
+// goes over the stream `conn`.
+func (c *Client) dialDatagrams(conn net.Conn, port routing.Port) (net.PacketConn, net.Addr, error) {
+	if port == 0 {
+		return nil, nil, nil
+	}
+
+	const handshakeTimeout = 5 * time.Second
+
+	remote := appnet.Addr{
+		Net:    appnet.TypeSkynet,
+		PubKey: c.cfg.ServerPK,
+		Port:   port,
+	}
+
+	var dHello DatagramHello
+
+	pc, err := c.appCl.DialPacket(remote)
+	if err != nil {
+		fmt.Printf("Failed to dial VPN server datagram port %d, falling back to stream: %v\n", port, err)
+	} else {
+		dHello.Port = pc.LocalAddr().(appnet.Addr).Port
+	}
+
+	if err := WriteJSONWithTimeout(conn, &dHello, handshakeTimeout); err != nil {
+		if pc != nil {
+			if err := pc.Close(); err != nil {
+				fmt.Printf("Error closing app packet conn: %v\n", err)
+			}
+		}
+
+		return nil, nil, fmt.Errorf("error sending datagram hello: %w", err)
 	}
 
-	return sHello.TUNIP, sHello.TUNGateway, nil
+	if pc == nil {
+		return nil, nil, nil
+	}
+
+	fmt.Printf("Passing traffic as datagrams via %s\n", remote)
+
+	return pc, remote, nil
 }
 
 func (c *Client) releaseSysPrivileges() {
@@ -7,4 +7,6 @@ type ClientConfig struct {
 	Passcode   string
 	Killswitch bool
 	ServerPK   cipher.PubKey
+	// Datagram makes client pass traffic as datagrams, if server supports it.
+	Datagram bool
 }
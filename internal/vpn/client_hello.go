@@ -6,4 +6,5 @@ import "net"
 type ClientHello struct {
 	UnavailablePrivateIPs []net.IP `json:"unavailable_private_ips"`
 	Passcode              string   `json:"passcode"`
+	Datagram              bool     `json:"datagram,omitempty"`
 }
@@ -0,0 +1,51 @@
+package vpn
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+)
+
+// datagramBufSize is large enough to fit any IP packet read from TUN.
+const datagramBufSize = 1 << 16
+
+// copyToPacketConn reads IP packets from `src` and sends each of them as a single datagram
+// to `addr` over `dst`.
+func copyToPacketConn(dst net.PacketConn, addr net.Addr, src io.Reader) error {
+	buf := make([]byte, datagramBufSize)
+
+	for {
+		n, err := src.Read(buf)
+		if err != nil {
+			return fmt.Errorf("error reading IP packet: %w", err)
+		}
+
+		if _, err := dst.WriteTo(buf[:n], addr); err != nil {
+			return fmt.Errorf("error writing datagram: %w", err)
+		}
+	}
+}
+
+// copyFromPacketConn reads datagrams from `src` and writes each of them as a single
+// IP packet to `dst`.
+func copyFromPacketConn(dst io.Writer, src net.PacketConn) error {
+	buf := make([]byte, datagramBufSize)
+
+	for {
+		n, _, err := src.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("error reading datagram: %w", err)
+		}
+
+		if _, err := dst.Write(buf[:n]); err != nil {
+			return fmt.Errorf("error writing IP packet: %w", err)
+		}
+	}
+}
+
+// waitConnClosed blocks until the session control `conn` is closed by either side.
+func waitConnClosed(conn net.Conn) error {
+	_, err := io.Copy(ioutil.Discard, conn)
+	return err
+}
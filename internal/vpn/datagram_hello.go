@@ -0,0 +1,10 @@
+package vpn
+
+import "github.com/skycoin/skywire/pkg/routing"
+
+// DatagramHello is a message sent by client after it dialed server datagram port. It
+// lets server match datagrams with the session. Zero port means that client failed to
+// dial and the session traffic goes over the stream connection.
+type DatagramHello struct {
+	Port routing.Port `json:"port"`
+}
@@ -6,8 +6,12 @@ import (
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skywire/pkg/app/appnet"
+	"github.com/skycoin/skywire/pkg/routing"
 )
 
 // Server is a VPN server.
@@ -15,6 +19,9 @@ type Server struct {
 	cfg                        ServerConfig
 	lisMx                      sync.Mutex
 	lis                        net.Listener
+	pcMx                       sync.Mutex
+	pc                         net.PacketConn
+	sessions                   map[appnet.Addr]io.Writer // TUNs of the datagram sessions keyed by client address
 	log                        logrus.FieldLogger
 	serveOnce                  sync.Once
 	ipGen                      *IPGenerator
@@ -28,9 +35,10 @@ type Server struct {
 // NewServer creates VPN server instance.
 func NewServer(cfg ServerConfig, l logrus.FieldLogger) (*Server, error) {
 	s := &Server{
-		cfg:   cfg,
-		log:   l,
-		ipGen: NewIPGenerator(),
+		cfg:      cfg,
+		log:      l,
+		ipGen:    NewIPGenerator(),
+		sessions: make(map[appnet.Addr]io.Writer),
 	}
 
 	defaultNetworkIfc, err := DefaultNetworkInterface()
@@ -153,19 +161,139 @@ func (s *Server) Serve(l net.Listener) error {
 	return serveErr
 }
 
+// ServeDatagrams reads datagrams from `pc` and passes them to TUNs of the corresponding sessions.
+// Clients which request datagrams during the handshake get the `pc` port to pass traffic to.
+func (s *Server) ServeDatagrams(pc net.PacketConn) error {
+	s.pcMx.Lock()
+	if s.pc != nil {
+		s.pcMx.Unlock()
+		return errors.New("already serving datagrams")
+	}
+	s.pc = pc
+	s.pcMx.Unlock()
+
+	buf := make([]byte, datagramBufSize)
+
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read client datagram: %w", err)
+		}
+
+		remote, err := appnet.ConvertAddr(addr)
+		if err != nil {
+			s.log.WithError(err).Warnf("Dropping datagram from %s", addr)
+			continue
+		}
+
+		s.pcMx.Lock()
+		tun, ok := s.sessions[remote]
+		s.pcMx.Unlock()
+
+		if !ok {
+			s.log.Debugf("Dropping datagram from %s: no session", remote)
+			continue
+		}
+
+		if _, err := tun.Write(buf[:n]); err != nil {
+			s.log.WithError(err).Errorf("Error resending datagram from VPN client %s to TUN", remote)
+		}
+	}
+}
+
 // Close shuts server down.
 func (s *Server) Close() error {
+	s.pcMx.Lock()
+	var pcErr error
+	if s.pc != nil {
+		pcErr = s.pc.Close()
+		s.pc = nil
+	}
+	s.pcMx.Unlock()
+
 	s.lisMx.Lock()
 	defer s.lisMx.Unlock()
 
 	if s.lis == nil {
-		return nil
+		return pcErr
 	}
 
 	err := s.lis.Close()
 	s.lis = nil
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	return pcErr
+}
+
+// datagramPort returns the port server accepts datagrams on, zero port means
+// that datagrams are not served.
+func (s *Server) datagramPort() routing.Port {
+	s.pcMx.Lock()
+	defer s.pcMx.Unlock()
+
+	if s.pc == nil {
+		return 0
+	}
+
+	addr, ok := s.pc.LocalAddr().(appnet.Addr)
+	if !ok {
+		return 0
+	}
+
+	return addr.Port
+}
+
+// addSession starts passing datagrams of `remote` to `tun`. It returns the packet conn
+// to send datagrams to the client over.
+func (s *Server) addSession(remote appnet.Addr, tun io.Writer) (net.PacketConn, error) {
+	s.pcMx.Lock()
+	defer s.pcMx.Unlock()
+
+	if s.pc == nil {
+		return nil, errors.New("datagrams are not served")
+	}
+
+	if _, ok := s.sessions[remote]; ok {
+		return nil, fmt.Errorf("session for %s already exists", remote)
+	}
+
+	s.sessions[remote] = tun
+
+	return s.pc, nil
+}
+
+func (s *Server) removeSession(remote appnet.Addr) {
+	s.pcMx.Lock()
+	defer s.pcMx.Unlock()
+
+	delete(s.sessions, remote)
+}
+
+// readDatagramHello reads the client datagram port and returns the client datagram address.
+// Zero address is returned if client falls back to the stream connection.
+func (s *Server) readDatagramHello(conn net.Conn) (appnet.Addr, error) {
+	const handshakeTimeout = 5 * time.Second
+
+	var dHello DatagramHello
+	if err := ReadJSONWithTimeout(conn, &dHello, handshakeTimeout); err != nil {
+		return appnet.Addr{}, fmt.Errorf("error reading datagram hello: %w", err)
+	}
+
+	if dHello.Port == 0 {
+		return appnet.Addr{}, nil
+	}
+
+	remote, err := appnet.ConvertAddr(conn.RemoteAddr())
+	if err != nil {
+		return appnet.Addr{}, err
+	}
+
+	remote.Port = dHello.Port
+
+	return remote, nil
 }
 
 func (s *Server) closeConn(conn net.Conn) {
@@ -177,13 +305,21 @@ func (s *Server) closeConn(conn net.Conn) {
 func (s *Server) serveConn(conn net.Conn) {
 	defer s.closeConn(conn)
 
-	tunIP, tunGateway, allowTrafficToLocalNet, err := s.shakeHands(conn)
+	tunIP, tunGateway, datagrams, allowTrafficToLocalNet, err := s.shakeHands(conn)
 	if err != nil {
 		s.log.WithError(err).Errorf("Error negotiating with client %s", conn.RemoteAddr())
 		return
 	}
 	defer allowTrafficToLocalNet()
 
+	var datagramRemote appnet.Addr
+	if datagrams {
+		if datagramRemote, err = s.readDatagramHello(conn); err != nil {
+			s.log.WithError(err).Errorf("Error negotiating datagrams with client %s", conn.RemoteAddr())
+			return
+		}
+	}
+
 	tun, err := newTUNDevice()
 	if err != nil {
 		s.log.WithError(err).Errorln("Error allocating TUN interface")
@@ -203,6 +339,11 @@ func (s *Server) serveConn(conn net.Conn) {
 		return
 	}
 
+	if !datagramRemote.PubKey.Null() {
+		s.serveDatagrams(conn, tun, datagramRemote)
+		return
+	}
+
 	connToTunDoneCh := make(chan struct{})
 	tunToConnCh := make(chan struct{})
 	go func() {
@@ -227,10 +368,44 @@ func (s *Server) serveConn(conn net.Conn) {
 	}
 }
 
-func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVPN func(), err error) {
+// serveDatagrams passes session traffic as datagrams, while `conn` is only used to track the session.
+func (s *Server) serveDatagrams(conn net.Conn, tun TUNDevice, remote appnet.Addr) {
+	pc, err := s.addSession(remote, tun)
+	if err != nil {
+		s.log.WithError(err).Errorf("Error adding datagram session for %s", remote)
+		return
+	}
+	defer s.removeSession(remote)
+
+	s.log.Infof("Passing traffic of VPN client %s as datagrams", remote)
+
+	connDoneCh := make(chan struct{})
+	tunToConnCh := make(chan struct{})
+	go func() {
+		defer close(connDoneCh)
+
+		if err := waitConnClosed(conn); err != nil {
+			s.log.WithError(err).Debugf("VPN client %s connection is closed", conn.RemoteAddr())
+		}
+	}()
+	go func() {
+		defer close(tunToConnCh)
+
+		if err := copyToPacketConn(pc, remote, tun); err != nil {
+			s.log.WithError(err).Errorf("Error resending datagrams from TUN %s to VPN client", tun.Name())
+		}
+	}()
+
+	select {
+	case <-connDoneCh:
+	case <-tunToConnCh:
+	}
+}
+
+func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, datagrams bool, unsecureVPN func(), err error) {
 	var cHello ClientHello
 	if err := ReadJSON(conn, &cHello); err != nil {
-		return nil, nil, nil, fmt.Errorf("error reading client hello: %w", err)
+		return nil, nil, false, nil, fmt.Errorf("error reading client hello: %w", err)
 	}
 
 	// default value
@@ -240,27 +415,27 @@ func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVP
 
 	if s.cfg.Passcode != "" && cHello.Passcode != s.cfg.Passcode {
 		s.sendServerErrHello(conn, HandshakeStatusForbidden)
-		return nil, nil, nil, errors.New("got wrong passcode from client")
+		return nil, nil, false, nil, errors.New("got wrong passcode from client")
 	}
 
 	for _, ip := range cHello.UnavailablePrivateIPs {
 		if err := s.ipGen.Reserve(ip); err != nil {
 			// this happens only on malformed IP
 			s.sendServerErrHello(conn, HandshakeStatusBadRequest)
-			return nil, nil, nil, fmt.Errorf("error reserving IP %s: %w", ip.String(), err)
+			return nil, nil, false, nil, fmt.Errorf("error reserving IP %s: %w", ip.String(), err)
 		}
 	}
 
 	subnet, err := s.ipGen.Next()
 	if err != nil {
 		s.sendServerErrHello(conn, HandshakeNoFreeIPs)
-		return nil, nil, nil, fmt.Errorf("error getting free subnet IP: %w", err)
+		return nil, nil, false, nil, fmt.Errorf("error getting free subnet IP: %w", err)
 	}
 
 	subnetOctets, err := fetchIPv4Octets(subnet)
 	if err != nil {
 		s.sendServerErrHello(conn, HandshakeStatusInternalError)
-		return nil, nil, nil, fmt.Errorf("error breaking IP into octets: %w", err)
+		return nil, nil, false, nil, fmt.Errorf("error breaking IP into octets: %w", err)
 	}
 
 	// basically IP address comprised of `subnetOctets` items is the IP address of the subnet,
@@ -280,7 +455,7 @@ func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVP
 	if s.cfg.Secure {
 		if err := BlockIPToLocalNetwork(cTUNIP, sTUNIP); err != nil {
 			s.sendServerErrHello(conn, HandshakeStatusInternalError)
-			return nil, nil, nil,
+			return nil, nil, false, nil,
 				fmt.Errorf("error securing local network for IP %s: %w", cTUNIP, err)
 		}
 
@@ -297,12 +472,16 @@ func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVP
 		TUNGateway: cTUNGateway,
 	}
 
+	if cHello.Datagram {
+		sHello.DatagramPort = s.datagramPort()
+	}
+
 	if err := WriteJSON(conn, &sHello); err != nil {
 		unsecureVPN()
-		return nil, nil, nil, fmt.Errorf("error finishing hadnshake: error sending server hello: %w", err)
+		return nil, nil, false, nil, fmt.Errorf("error finishing hadnshake: error sending server hello: %w", err)
 	}
 
-	return sTUNIP, sTUNGateway, unsecureVPN, nil
+	return sTUNIP, sTUNGateway, sHello.DatagramPort != 0, unsecureVPN, nil
 }
 
 func (s *Server) sendServerErrHello(conn net.Conn, status HandshakeStatus) {
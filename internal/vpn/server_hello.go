@@ -1,10 +1,17 @@
 package vpn
 
-import "net"
+import (
+	"net"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
 
 // ServerHello is a message sent by server during the Client/Server handshake.
 type ServerHello struct {
 	Status     HandshakeStatus `json:"status"`
 	TUNIP      net.IP          `json:"tun_ip"`
 	TUNGateway net.IP          `json:"tun_gateway"`
+	// DatagramPort is the port server accepts datagrams on. It's set only if client
+	// requested datagrams and server supports them.
+	DatagramPort routing.Port `json:"datagram_port,omitempty"`
 }
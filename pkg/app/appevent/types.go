@@ -3,8 +3,9 @@ package appevent
 // AllTypes returns all event types.
 func AllTypes() map[string]bool {
 	return map[string]bool{
-		TCPDial:  true,
-		TCPClose: true,
+		TCPDial:         true,
+		TCPClose:        true,
+		DataCapExceeded: true,
 	}
 }
 
@@ -31,3 +32,19 @@ type TCPCloseData struct {
 
 // Type returns the TCPClose type.
 func (TCPCloseData) Type() string { return TCPClose }
+
+// DataCapExceeded represents an event of exceeded transport data cap.
+const DataCapExceeded = "data_cap_exceeded"
+
+// DataCapExceededData contains data cap exceeded event data.
+type DataCapExceededData struct {
+	TpType   string `json:"tp_type,omitempty"`
+	RemotePK string `json:"remote_pk,omitempty"`
+	Period   string `json:"period"`
+	Used     uint64 `json:"used"`
+	Limit    uint64 `json:"limit"`
+	Action   string `json:"action"`
+}
+
+// Type returns the DataCapExceeded type.
+func (DataCapExceededData) Type() string { return DataCapExceeded }
@@ -47,6 +47,8 @@ func (a Addr) PK() cipher.PubKey {
 // to `Addr` if possible.
 func ConvertAddr(addr net.Addr) (Addr, error) {
 	switch a := addr.(type) {
+	case Addr:
+		return a, nil
 	case dmsg.Addr:
 		return Addr{
 			Net:    TypeDmsg,
@@ -26,6 +26,21 @@ func TestConvertAddr(t *testing.T) {
 		addr net.Addr
 		want want
 	}{
+		{
+			name: "ok - app addr",
+			addr: Addr{
+				Net:    TypeSkynet,
+				PubKey: pk,
+				Port:   routing.Port(port),
+			},
+			want: want{
+				addr: Addr{
+					Net:    TypeSkynet,
+					PubKey: pk,
+					Port:   routing.Port(port),
+				},
+			},
+		},
 		{
 			name: "ok - dmsg addr",
 			addr: dmsg.Addr{
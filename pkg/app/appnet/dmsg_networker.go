@@ -43,3 +43,23 @@ func (n *DmsgNetworker) Listen(addr Addr) (net.Listener, error) {
 func (n *DmsgNetworker) ListenContext(_ context.Context, addr Addr) (net.Listener, error) {
 	return n.dmsgC.Listen(uint16(addr.Port))
 }
+
+// DialPacket is not supported by dmsg network.
+func (n *DmsgNetworker) DialPacket(Addr) (net.PacketConn, error) {
+	return nil, ErrDatagramsNotSupported
+}
+
+// DialPacketContext is not supported by dmsg network.
+func (n *DmsgNetworker) DialPacketContext(context.Context, Addr) (net.PacketConn, error) {
+	return nil, ErrDatagramsNotSupported
+}
+
+// ListenPacket is not supported by dmsg network.
+func (n *DmsgNetworker) ListenPacket(Addr) (net.PacketConn, error) {
+	return nil, ErrDatagramsNotSupported
+}
+
+// ListenPacketContext is not supported by dmsg network.
+func (n *DmsgNetworker) ListenPacketContext(context.Context, Addr) (net.PacketConn, error) {
+	return nil, ErrDatagramsNotSupported
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net"
 	"sync"
+
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 )
 
 //go:generate mockery -name Networker -case underscore -inpkg
@@ -14,6 +16,10 @@ var (
 	ErrNoSuchNetworker = errors.New("no such networker")
 	// ErrNetworkerAlreadyExists is being returned when there's already one with such Network type.
 	ErrNetworkerAlreadyExists = errors.New("networker already exists")
+	// ErrDatagramsNotSupported is being returned when the network doesn't support datagrams.
+	ErrDatagramsNotSupported = errors.New("datagrams are not supported by the network")
+	// ErrPreferencesNotSupported is being returned when the network doesn't support route preferences.
+	ErrPreferencesNotSupported = errors.New("route preferences are not supported by the network")
 )
 
 // nolint: gochecknoglobals
@@ -60,11 +66,21 @@ func ClearNetworkers() {
 }
 
 // Networker defines basic network operations, such as Dial/Listen.
+// DialPacket/ListenPacket are the datagram counterparts of Dial/Listen.
 type Networker interface {
 	Dial(addr Addr) (net.Conn, error)
 	DialContext(ctx context.Context, addr Addr) (net.Conn, error)
 	Listen(addr Addr) (net.Listener, error)
 	ListenContext(ctx context.Context, addr Addr) (net.Listener, error)
+	DialPacket(addr Addr) (net.PacketConn, error)
+	DialPacketContext(ctx context.Context, addr Addr) (net.PacketConn, error)
+	ListenPacket(addr Addr) (net.PacketConn, error)
+	ListenPacketContext(ctx context.Context, addr Addr) (net.PacketConn, error)
+}
+
+// PreferencesDialer is implemented by networkers which are able to dial with route preferences.
+type PreferencesDialer interface {
+	DialWithPreferences(ctx context.Context, addr Addr, prefs rfclient.RoutePreferences) (net.Conn, error)
 }
 
 // Dial dials the remote `addr`.
@@ -82,6 +98,25 @@ func DialContext(ctx context.Context, addr Addr) (net.Conn, error) {
 	return n.DialContext(ctx, addr)
 }
 
+// DialWithPreferences dials the remote `addr` via the routes satisfying `prefs`.
+func DialWithPreferences(ctx context.Context, addr Addr, prefs rfclient.RoutePreferences) (net.Conn, error) {
+	n, err := ResolveNetworker(addr.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefs.IsEmpty() {
+		return n.DialContext(ctx, addr)
+	}
+
+	pd, ok := n.(PreferencesDialer)
+	if !ok {
+		return nil, ErrPreferencesNotSupported
+	}
+
+	return pd.DialWithPreferences(ctx, addr, prefs)
+}
+
 // Listen starts listening on the local `addr`.
 func Listen(addr Addr) (net.Listener, error) {
 	return ListenContext(context.Background(), addr)
@@ -96,3 +131,33 @@ func ListenContext(ctx context.Context, addr Addr) (net.Listener, error) {
 
 	return networker.ListenContext(ctx, addr)
 }
+
+// DialPacket dials the remote `addr` for datagrams.
+func DialPacket(addr Addr) (net.PacketConn, error) {
+	return DialPacketContext(context.Background(), addr)
+}
+
+// DialPacketContext dials the remote `addr` for datagrams with the context.
+func DialPacketContext(ctx context.Context, addr Addr) (net.PacketConn, error) {
+	n, err := ResolveNetworker(addr.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.DialPacketContext(ctx, addr)
+}
+
+// ListenPacket starts listening for datagrams on the local `addr`.
+func ListenPacket(addr Addr) (net.PacketConn, error) {
+	return ListenPacketContext(context.Background(), addr)
+}
+
+// ListenPacketContext starts listening for datagrams on the local `addr` with the context.
+func ListenPacketContext(ctx context.Context, addr Addr) (net.PacketConn, error) {
+	networker, err := ResolveNetworker(addr.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	return networker.ListenPacketContext(ctx, addr)
+}
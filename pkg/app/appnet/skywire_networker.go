@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/skycoin/dmsg/netutil"
 
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/router"
 	"github.com/skycoin/skywire/pkg/routing"
 )
@@ -44,7 +45,13 @@ func (r *SkywireNetworker) Dial(addr Addr) (net.Conn, error) {
 }
 
 // DialContext dials remote `addr` via `skynet` with context.
-func (r *SkywireNetworker) DialContext(ctx context.Context, addr Addr) (conn net.Conn, err error) {
+func (r *SkywireNetworker) DialContext(ctx context.Context, addr Addr) (net.Conn, error) {
+	return r.DialWithPreferences(ctx, addr, rfclient.RoutePreferences{})
+}
+
+// DialWithPreferences dials remote `addr` via `skynet` with context, routes satisfy `prefs`.
+// The dialed route group is reliable if remote supports it.
+func (r *SkywireNetworker) DialWithPreferences(ctx context.Context, addr Addr, prefs rfclient.RoutePreferences) (conn net.Conn, err error) {
 	localPort, freePort, err := r.porter.ReserveEphemeral(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -57,7 +64,11 @@ func (r *SkywireNetworker) DialContext(ctx context.Context, addr Addr) (conn net
 		}
 	}()
 
-	conn, err = r.r.DialRoutes(ctx, addr.PubKey, routing.Port(localPort), addr.Port, router.DefaultDialOptions())
+	opts := router.DefaultDialOptions()
+	opts.Reliable = true
+	opts.Preferences = prefs
+
+	conn, err = r.r.DialRoutes(ctx, addr.PubKey, routing.Port(localPort), addr.Port, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +104,79 @@ func (r *SkywireNetworker) ListenContext(ctx context.Context, addr Addr) (net.Li
 	lis.freePort = freePort
 	lis.freePortMx.Unlock()
 
+	r.startServing(ctx)
+
+	return lis, nil
+}
+
+// DialPacket dials remote `addr` for datagrams via `skynet`.
+func (r *SkywireNetworker) DialPacket(addr Addr) (net.PacketConn, error) {
+	return r.DialPacketContext(context.Background(), addr)
+}
+
+// DialPacketContext dials remote `addr` for datagrams via `skynet` with context.
+// The returned connection may only be written to the dialed remote.
+func (r *SkywireNetworker) DialPacketContext(ctx context.Context, addr Addr) (pc net.PacketConn, err error) {
+	localPort, freePort, err := r.porter.ReserveEphemeral(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure ports are freed on error.
+	defer func() {
+		if err != nil {
+			freePort()
+		}
+	}()
+
+	opts := router.DefaultDialOptions()
+	opts.Datagram = true
+
+	conn, err := r.r.DialRoutes(ctx, addr.PubKey, routing.Port(localPort), addr.Port, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newSkywirePacketConn(r.log, Addr{
+		Net:    TypeSkynet,
+		PubKey: conn.LocalAddr().(routing.Addr).PubKey,
+		Port:   routing.Port(localPort),
+	})
+	c.freePort = freePort
+
+	if err := c.addConn(conn); err != nil {
+		r.close(conn)
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ListenPacket starts listening for datagrams on local `addr` in the skynet.
+func (r *SkywireNetworker) ListenPacket(addr Addr) (net.PacketConn, error) {
+	return r.ListenPacketContext(context.Background(), addr)
+}
+
+// ListenPacketContext starts listening for datagrams on local `addr` in the skynet with context.
+func (r *SkywireNetworker) ListenPacketContext(ctx context.Context, addr Addr) (net.PacketConn, error) {
+	c := newSkywirePacketConn(r.log, addr)
+
+	ok, freePort := r.porter.Reserve(uint16(addr.Port), c)
+	if !ok {
+		return nil, ErrPortAlreadyBound
+	}
+
+	c.freePortMx.Lock()
+	c.freePort = freePort
+	c.freePortMx.Unlock()
+
+	r.startServing(ctx)
+
+	return c, nil
+}
+
+// startServing starts accepting route groups unless it's already done.
+func (r *SkywireNetworker) startServing(ctx context.Context) {
 	if atomic.CompareAndSwapInt32(&r.isServing, 0, 1) {
 		go func() {
 			if err := r.serveRouteGroup(ctx); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
@@ -100,8 +184,6 @@ func (r *SkywireNetworker) ListenContext(ctx context.Context, addr Addr) (net.Li
 			}
 		}()
 	}
-
-	return lis, nil
 }
 
 // serveRouteGroup accepts and serves routes.
@@ -113,8 +195,16 @@ func (r *SkywireNetworker) serveRouteGroup(ctx context.Context) error {
 
 		conn, err := r.r.AcceptRoutes(ctx)
 		if err != nil {
-			log.WithError(err).Info("Stopped accepting routes.")
-			return err
+			// router reports it's closed with an operation error, failures of a single route group are skipped
+			var opErr *net.OpError
+			if ctx.Err() != nil || errors.As(err, &opErr) {
+				log.WithError(err).Info("Stopped accepting routes.")
+				return err
+			}
+
+			log.WithError(err).Warn("Failed to accept route group.")
+
+			continue
 		}
 
 		log.
@@ -127,6 +217,7 @@ func (r *SkywireNetworker) serveRouteGroup(ctx context.Context) error {
 }
 
 // serveRG passes accepted router group to the corresponding listener.
+// Datagram route groups are passed to the datagram connection listening on the port.
 func (r *SkywireNetworker) serve(conn net.Conn) {
 	localAddr, ok := conn.LocalAddr().(routing.Addr)
 	if !ok {
@@ -136,23 +227,46 @@ func (r *SkywireNetworker) serve(conn net.Conn) {
 		return
 	}
 
-	lisIfc, ok := r.porter.PortValue(uint16(localAddr.Port))
+	nrg, ok := conn.(*router.NoiseRouteGroup)
 	if !ok {
 		r.close(conn)
-		r.log.Errorf("no listener on port %d", localAddr.Port)
+		r.log.Error("wrong type of accepted conn")
 
 		return
 	}
 
-	lis, ok := lisIfc.(*skywireListener)
+	lisIfc, ok := r.porter.PortValue(uint16(localAddr.Port))
 	if !ok {
-		r.close(conn)
-		r.log.Errorf("wrong type of listener on port %d", localAddr.Port)
+		r.closeWithCode(nrg, routing.CloseNoListener)
+		r.log.Errorf("no listener on port %d", localAddr.Port)
 
 		return
 	}
 
-	lis.putConn(conn)
+	switch lis := lisIfc.(type) {
+	case *skywireListener:
+		if nrg.IsDatagram() {
+			break
+		}
+
+		lis.putConn(conn)
+
+		return
+	case *SkywirePacketConn:
+		if !nrg.IsDatagram() {
+			break
+		}
+
+		if err := lis.addConn(conn); err != nil {
+			r.close(conn)
+			r.log.WithError(err).Errorf("failed to serve datagram route group on port %d", localAddr.Port)
+		}
+
+		return
+	}
+
+	r.closeWithCode(nrg, routing.CloseNoListener)
+	r.log.Errorf("wrong type of listener on port %d", localAddr.Port)
 }
 
 // closeRG closes router group and logs error if any.
@@ -162,6 +276,13 @@ func (r *SkywireNetworker) close(closer io.Closer) {
 	}
 }
 
+// closeWithCode closes route group with the close `code` and logs error if any.
+func (r *SkywireNetworker) closeWithCode(nrg *router.NoiseRouteGroup, code routing.CloseCode) {
+	if err := nrg.CloseWithCode(code); err != nil {
+		r.log.Error(err)
+	}
+}
+
 // skywireListener is a listener for skynet.
 // Implements net.Listener.
 type skywireListener struct {
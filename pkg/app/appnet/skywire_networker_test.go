@@ -0,0 +1,65 @@
+package appnet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
+	"github.com/skycoin/skywire/pkg/router"
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+func TestSkywireNetworker_DialOptions(t *testing.T) {
+	remotePK, _ := cipher.GenerateKeyPair()
+	remote := Addr{Net: TypeSkynet, PubKey: remotePK, Port: 2}
+	prefs := rfclient.RoutePreferences{TpTypes: []string{"stcp"}}
+	errDial := errors.New("dial error")
+
+	tt := []struct {
+		name string
+		dial func(n *SkywireNetworker) error
+		want func(opts *router.DialOptions) bool
+	}{
+		{
+			name: "stream",
+			dial: func(n *SkywireNetworker) error {
+				_, err := n.DialWithPreferences(context.Background(), remote, prefs)
+				return err
+			},
+			want: func(opts *router.DialOptions) bool {
+				return opts.Reliable && !opts.Datagram && assert.ObjectsAreEqual(prefs, opts.Preferences)
+			},
+		},
+		{
+			name: "datagram",
+			dial: func(n *SkywireNetworker) error {
+				_, err := n.DialPacketContext(context.Background(), remote)
+				return err
+			},
+			want: func(opts *router.DialOptions) bool {
+				return !opts.Reliable && opts.Datagram
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := &router.MockRouter{}
+			r.On("DialRoutes", mock.Anything, remotePK, mock.Anything, routing.Port(2), mock.MatchedBy(tc.want)).
+				Return(nil, errDial)
+
+			n := NewSkywireNetworker(logging.MustGetLogger("skywire_networker"), r).(*SkywireNetworker)
+
+			require.Equal(t, errDial, tc.dial(n))
+			r.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package appnet
+
+import (
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skywire/pkg/util/deadline"
+)
+
+var (
+	// ErrUnknownRemote is returned when writing to the remote which has no datagram route group
+	// to the local port.
+	ErrUnknownRemote = errors.New("no datagram route group to the remote")
+)
+
+// timeoutError is returned when deadline of the datagram connection is exceeded.
+// Implements net.Error.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// datagram is a message read from one of the route groups of `SkywirePacketConn`.
+type datagram struct {
+	b    []byte
+	addr Addr
+}
+
+// SkywirePacketConn is a datagram connection for skynet. It consists of the datagram route groups
+// of a single local port: a dialed one has the route group to the dialed remote, while a listening
+// one gets a route group per each of the remotes dialing its port.
+// Implements net.PacketConn.
+type SkywirePacketConn struct {
+	log   logrus.FieldLogger
+	local Addr
+
+	conns         map[Addr]net.Conn // keyed by remote address
+	writeDeadline time.Time
+	connsMx       sync.Mutex
+
+	readCh       chan datagram
+	readDeadline deadline.PipeDeadline
+
+	freePort   func()
+	freePortMx sync.RWMutex
+	closed     chan struct{}
+	once       sync.Once
+}
+
+// readChBufSize is the number of datagrams buffered for the reader, the rest are dropped.
+const readChBufSize = 1024
+
+func newSkywirePacketConn(log logrus.FieldLogger, local Addr) *SkywirePacketConn {
+	return &SkywirePacketConn{
+		log:          log,
+		local:        local,
+		conns:        make(map[Addr]net.Conn),
+		readCh:       make(chan datagram, readChBufSize),
+		readDeadline: deadline.MakePipeDeadline(),
+		closed:       make(chan struct{}),
+	}
+}
+
+// ReadFrom reads a single datagram from any of the remotes.
+func (c *SkywirePacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case <-c.readDeadline.Wait():
+		return 0, nil, timeoutError{}
+	case <-c.closed:
+		return 0, nil, errors.New("reading from closed connection")
+	case d := <-c.readCh:
+		return copy(p, d.b), d.addr, nil
+	}
+}
+
+// WriteTo writes `p` as a single datagram to the remote `addr`.
+func (c *SkywirePacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	remote, err := convertRemoteAddr(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	c.connsMx.Lock()
+	conn, ok := c.conns[remote]
+	c.connsMx.Unlock()
+
+	if !ok {
+		return 0, ErrUnknownRemote
+	}
+
+	return conn.Write(p)
+}
+
+// Close closes connection along with all of its route groups.
+func (c *SkywirePacketConn) Close() error {
+	var conns []net.Conn
+
+	c.once.Do(func() {
+		close(c.closed)
+
+		c.connsMx.Lock()
+		for remote, conn := range c.conns {
+			conns = append(conns, conn)
+			delete(c.conns, remote)
+		}
+		c.connsMx.Unlock()
+
+		c.freePortMx.RLock()
+		defer c.freePortMx.RUnlock()
+		if c.freePort != nil {
+			c.freePort()
+		}
+	})
+
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil {
+			c.log.WithError(err).Debug("Failed to close datagram route group.")
+		}
+	}
+
+	return nil
+}
+
+// LocalAddr returns local address.
+func (c *SkywirePacketConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// SetDeadline sets read and write deadlines.
+func (c *SkywirePacketConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets read deadline.
+func (c *SkywirePacketConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+	return nil
+}
+
+// SetWriteDeadline sets write deadline for all of the route groups.
+func (c *SkywirePacketConn) SetWriteDeadline(t time.Time) error {
+	c.connsMx.Lock()
+	defer c.connsMx.Unlock()
+
+	c.writeDeadline = t
+
+	for _, conn := range c.conns {
+		if err := conn.SetWriteDeadline(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addConn adds datagram route group to the remote. Route group which was previously
+// used for the same remote gets closed.
+func (c *SkywirePacketConn) addConn(conn net.Conn) error {
+	remote, err := convertRemoteAddr(conn.RemoteAddr())
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetWriteDeadline(c.writeDeadlineValue()); err != nil {
+		return err
+	}
+
+	c.connsMx.Lock()
+	select {
+	case <-c.closed:
+		c.connsMx.Unlock()
+		return errors.New("connection is closed")
+	default:
+	}
+
+	old, ok := c.conns[remote]
+	c.conns[remote] = conn
+	c.connsMx.Unlock()
+
+	if ok {
+		c.closeConn(remote, old)
+	}
+
+	go c.readLoop(remote, conn)
+
+	return nil
+}
+
+func (c *SkywirePacketConn) readLoop(remote Addr, conn net.Conn) {
+	defer c.closeConn(remote, conn)
+
+	buf := make([]byte, math.MaxUint16)
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			c.log.WithError(err).Debugf("Stopped reading datagrams from %s.", remote)
+			return
+		}
+
+		b := make([]byte, n)
+		copy(b, buf[:n])
+
+		// datagrams may get lost anyway, so slow reader doesn't stall the route group
+		select {
+		case <-c.closed:
+			return
+		case c.readCh <- datagram{b: b, addr: remote}:
+		default:
+			c.log.Debugf("Dropped datagram from %s, read buffer is full.", remote)
+		}
+	}
+}
+
+// closeConn closes route group to the `remote` and removes it if it's still in use.
+func (c *SkywirePacketConn) closeConn(remote Addr, conn net.Conn) {
+	c.connsMx.Lock()
+	if c.conns[remote] == conn {
+		delete(c.conns, remote)
+	}
+	c.connsMx.Unlock()
+
+	if err := conn.Close(); err != nil {
+		c.log.WithError(err).Debugf("Failed to close datagram route group to %s.", remote)
+	}
+}
+
+func (c *SkywirePacketConn) writeDeadlineValue() time.Time {
+	c.connsMx.Lock()
+	defer c.connsMx.Unlock()
+
+	return c.writeDeadline
+}
+
+// convertRemoteAddr converts `addr` to the skynet `Addr`.
+func convertRemoteAddr(addr net.Addr) (Addr, error) {
+	a, err := ConvertAddr(addr)
+	if err != nil {
+		return Addr{}, err
+	}
+
+	if a.Net != TypeSkynet {
+		return Addr{}, ErrUnknownAddrType
+	}
+
+	return a, nil
+}
@@ -0,0 +1,104 @@
+package appnet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+// routeGroupConn imitates datagram route group to the `remote`.
+type routeGroupConn struct {
+	net.Conn
+	remote routing.Addr
+}
+
+func (c *routeGroupConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func newTestPacketConn(t *testing.T) (c *SkywirePacketConn, remote Addr, remoteConn net.Conn) {
+	localPK, _ := cipher.GenerateKeyPair()
+	remotePK, _ := cipher.GenerateKeyPair()
+
+	c = newSkywirePacketConn(logging.MustGetLogger("packet_conn"), Addr{Net: TypeSkynet, PubKey: localPK, Port: 1})
+
+	conn, remoteConn := net.Pipe()
+	require.NoError(t, c.addConn(&routeGroupConn{Conn: conn, remote: routing.Addr{PubKey: remotePK, Port: 2}}))
+
+	return c, Addr{Net: TypeSkynet, PubKey: remotePK, Port: 2}, remoteConn
+}
+
+func TestSkywirePacketConn_ReadFromWriteTo(t *testing.T) {
+	c, remote, remoteConn := newTestPacketConn(t)
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	_, err := remoteConn.Write([]byte("datagram"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 16)
+
+	n, addr, err := c.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, "datagram", string(buf[:n]))
+	require.Equal(t, remote, addr)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.WriteTo([]byte("reply"), remote)
+		errCh <- err
+	}()
+
+	n, err = remoteConn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "reply", string(buf[:n]))
+	require.NoError(t, <-errCh)
+
+	unknownPK, _ := cipher.GenerateKeyPair()
+	_, err = c.WriteTo([]byte("reply"), Addr{Net: TypeSkynet, PubKey: unknownPK, Port: 2})
+	require.Equal(t, ErrUnknownRemote, err)
+
+	require.NoError(t, c.SetReadDeadline(time.Now()))
+	_, _, err = c.ReadFrom(buf)
+	require.Equal(t, timeoutError{}, err)
+}
+
+func TestSkywirePacketConn_slowReader(t *testing.T) {
+	c, _, remoteConn := newTestPacketConn(t)
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 0; i < readChBufSize*2; i++ {
+			if _, err := remoteConn.Write([]byte{byte(i)}); err != nil {
+				return
+			}
+		}
+	}()
+
+	// route group keeps being read while nobody reads datagrams, the ones which don't fit are dropped
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("route group got stalled by the slow reader")
+	}
+
+	buf := make([]byte, 1)
+
+	for i := 0; i < readChBufSize; i++ {
+		n, _, err := c.ReadFrom(buf)
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, buf[:n])
+	}
+}
@@ -8,6 +8,7 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	appnet "github.com/skycoin/skywire/pkg/app/appnet"
+	rfclient "github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	routing "github.com/skycoin/skywire/pkg/routing"
 )
 
@@ -100,6 +101,62 @@ func (_m *MockRPCIngressClient) Dial(remote appnet.Addr) (uint16, routing.Port,
 	return r0, r1, r2
 }
 
+// DialWithPreferences provides a mock function with given fields: remote, prefs
+func (_m *MockRPCIngressClient) DialWithPreferences(remote appnet.Addr, prefs rfclient.RoutePreferences) (uint16, routing.Port, error) {
+	ret := _m.Called(remote, prefs)
+
+	var r0 uint16
+	if rf, ok := ret.Get(0).(func(appnet.Addr, rfclient.RoutePreferences) uint16); ok {
+		r0 = rf(remote, prefs)
+	} else {
+		r0 = ret.Get(0).(uint16)
+	}
+
+	var r1 routing.Port
+	if rf, ok := ret.Get(1).(func(appnet.Addr, rfclient.RoutePreferences) routing.Port); ok {
+		r1 = rf(remote, prefs)
+	} else {
+		r1 = ret.Get(1).(routing.Port)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(appnet.Addr, rfclient.RoutePreferences) error); ok {
+		r2 = rf(remote, prefs)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// DialPacket provides a mock function with given fields: remote
+func (_m *MockRPCIngressClient) DialPacket(remote appnet.Addr) (uint16, routing.Port, error) {
+	ret := _m.Called(remote)
+
+	var r0 uint16
+	if rf, ok := ret.Get(0).(func(appnet.Addr) uint16); ok {
+		r0 = rf(remote)
+	} else {
+		r0 = ret.Get(0).(uint16)
+	}
+
+	var r1 routing.Port
+	if rf, ok := ret.Get(1).(func(appnet.Addr) routing.Port); ok {
+		r1 = rf(remote)
+	} else {
+		r1 = ret.Get(1).(routing.Port)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(appnet.Addr) error); ok {
+		r2 = rf(remote)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // Listen provides a mock function with given fields: local
 func (_m *MockRPCIngressClient) Listen(local appnet.Addr) (uint16, error) {
 	ret := _m.Called(local)
@@ -121,6 +178,27 @@ func (_m *MockRPCIngressClient) Listen(local appnet.Addr) (uint16, error) {
 	return r0, r1
 }
 
+// ListenPacket provides a mock function with given fields: local
+func (_m *MockRPCIngressClient) ListenPacket(local appnet.Addr) (uint16, error) {
+	ret := _m.Called(local)
+
+	var r0 uint16
+	if rf, ok := ret.Get(0).(func(appnet.Addr) uint16); ok {
+		r0 = rf(local)
+	} else {
+		r0 = ret.Get(0).(uint16)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(appnet.Addr) error); ok {
+		r1 = rf(local)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Read provides a mock function with given fields: connID, b
 func (_m *MockRPCIngressClient) Read(connID uint16, b []byte) (int, error) {
 	ret := _m.Called(connID, b)
@@ -142,6 +220,34 @@ func (_m *MockRPCIngressClient) Read(connID uint16, b []byte) (int, error) {
 	return r0, r1
 }
 
+// ReadFrom provides a mock function with given fields: connID, b
+func (_m *MockRPCIngressClient) ReadFrom(connID uint16, b []byte) (int, appnet.Addr, error) {
+	ret := _m.Called(connID, b)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(uint16, []byte) int); ok {
+		r0 = rf(connID, b)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 appnet.Addr
+	if rf, ok := ret.Get(1).(func(uint16, []byte) appnet.Addr); ok {
+		r1 = rf(connID, b)
+	} else {
+		r1 = ret.Get(1).(appnet.Addr)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(uint16, []byte) error); ok {
+		r2 = rf(connID, b)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // SetDeadline provides a mock function with given fields: connID, d
 func (_m *MockRPCIngressClient) SetDeadline(connID uint16, d time.Time) error {
 	ret := _m.Called(connID, d)
@@ -204,3 +310,24 @@ func (_m *MockRPCIngressClient) Write(connID uint16, b []byte) (int, error) {
 
 	return r0, r1
 }
+
+// WriteTo provides a mock function with given fields: connID, b, remote
+func (_m *MockRPCIngressClient) WriteTo(connID uint16, b []byte, remote appnet.Addr) (int, error) {
+	ret := _m.Called(connID, b, remote)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(uint16, []byte, appnet.Addr) int); ok {
+		r0 = rf(connID, b, remote)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint16, []byte, appnet.Addr) error); ok {
+		r1 = rf(connID, b, remote)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/skycoin/skywire/pkg/app/appcommon"
 	"github.com/skycoin/skywire/pkg/app/appnet"
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/routing"
 )
 
@@ -15,6 +16,7 @@ import (
 // RPCIngressClient describes RPC interface to communicate with the server.
 type RPCIngressClient interface {
 	Dial(remote appnet.Addr) (connID uint16, localPort routing.Port, err error)
+	DialWithPreferences(remote appnet.Addr, prefs rfclient.RoutePreferences) (connID uint16, localPort routing.Port, err error)
 	Listen(local appnet.Addr) (uint16, error)
 	Accept(lisID uint16) (connID uint16, remote appnet.Addr, err error)
 	Write(connID uint16, b []byte) (int, error)
@@ -24,6 +26,10 @@ type RPCIngressClient interface {
 	SetDeadline(connID uint16, d time.Time) error
 	SetReadDeadline(connID uint16, d time.Time) error
 	SetWriteDeadline(connID uint16, d time.Time) error
+	DialPacket(remote appnet.Addr) (connID uint16, localPort routing.Port, err error)
+	ListenPacket(local appnet.Addr) (connID uint16, err error)
+	WriteTo(connID uint16, b []byte, remote appnet.Addr) (int, error)
+	ReadFrom(connID uint16, b []byte) (int, appnet.Addr, error)
 }
 
 // rpcIngressClient implements `RPCIngressClient`.
@@ -47,6 +53,29 @@ func (c *rpcIngressClient) Dial(remote appnet.Addr) (connID uint16, localPort ro
 		return 0, 0, err
 	}
 
+	if err := resp.Err.ToError(); err != nil {
+		return 0, 0, err
+	}
+
+	return resp.ConnID, resp.LocalPort, nil
+}
+
+// DialWithPreferences sends `DialWithPreferences` command to the server.
+func (c *rpcIngressClient) DialWithPreferences(remote appnet.Addr, prefs rfclient.RoutePreferences) (connID uint16, localPort routing.Port, err error) {
+	req := DialReq{
+		Remote:      remote,
+		Preferences: prefs,
+	}
+
+	var resp DialResp
+	if err := c.rpc.Call(c.formatMethod("DialWithPreferences"), &req, &resp); err != nil {
+		return 0, 0, err
+	}
+
+	if err := resp.Err.ToError(); err != nil {
+		return 0, 0, err
+	}
+
 	return resp.ConnID, resp.LocalPort, nil
 }
 
@@ -144,6 +173,65 @@ func (c *rpcIngressClient) SetWriteDeadline(id uint16, t time.Time) error {
 	return c.rpc.Call(c.formatMethod("SetWriteDeadline"), &req, nil)
 }
 
+// DialPacket sends `DialPacket` command to the server.
+func (c *rpcIngressClient) DialPacket(remote appnet.Addr) (connID uint16, localPort routing.Port, err error) {
+	var resp DialResp
+	if err := c.rpc.Call(c.formatMethod("DialPacket"), &remote, &resp); err != nil {
+		return 0, 0, err
+	}
+
+	if err := resp.Err.ToError(); err != nil {
+		return 0, 0, err
+	}
+
+	return resp.ConnID, resp.LocalPort, nil
+}
+
+// ListenPacket sends `ListenPacket` command to the server.
+func (c *rpcIngressClient) ListenPacket(local appnet.Addr) (uint16, error) {
+	var connID uint16
+	if err := c.rpc.Call(c.formatMethod("ListenPacket"), &local, &connID); err != nil {
+		return 0, err
+	}
+
+	return connID, nil
+}
+
+// WriteTo sends `WriteTo` command to the server.
+func (c *rpcIngressClient) WriteTo(connID uint16, b []byte, remote appnet.Addr) (int, error) {
+	req := WriteToReq{
+		ConnID: connID,
+		B:      b,
+		Remote: remote,
+	}
+
+	var resp WriteResp
+	if err := c.rpc.Call(c.formatMethod("WriteTo"), &req, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.N, resp.Err.ToError()
+}
+
+// ReadFrom sends `ReadFrom` command to the server.
+func (c *rpcIngressClient) ReadFrom(connID uint16, b []byte) (int, appnet.Addr, error) {
+	req := ReadReq{
+		ConnID: connID,
+		BufLen: len(b),
+	}
+
+	var resp ReadFromResp
+	if err := c.rpc.Call(c.formatMethod("ReadFrom"), &req, &resp); err != nil {
+		return 0, appnet.Addr{}, err
+	}
+
+	if resp.N != 0 {
+		copy(b[:resp.N], resp.B[:resp.N])
+	}
+
+	return resp.N, resp.Remote, resp.Err.ToError()
+}
+
 // formatMethod formats complete RPC method signature.
 func (c *rpcIngressClient) formatMethod(method string) string {
 	const methodFmt = "%s.%s"
@@ -79,6 +79,33 @@ func TestRPCClient_Dial(t *testing.T) {
 		require.Equal(t, connID, uint16(0))
 		require.Equal(t, localPort, routing.Port(0))
 	})
+
+	t.Run("policy rejection", func(t *testing.T) {
+		s := prepRPCServer(t, NewRPCGateway(nil))
+		rpcL, lisCleanup := prepListener(t)
+		defer lisCleanup()
+		go s.Accept(rpcL)
+
+		cl := prepRPCClient(t, rpcL.Addr().Network(), rpcL.Addr().String())
+
+		_, _, _, remote := prepAddrs()
+
+		dialCtx := context.Background()
+		var dialConn net.Conn
+		dialErr := fmt.Errorf("%w: data cap", &routing.CloseError{Code: routing.ClosePolicyRejected})
+
+		n := &appnet.MockNetworker{}
+		n.On("DialContext", dialCtx, remote).Return(dialConn, dialErr)
+
+		appnet.ClearNetworkers()
+		err := appnet.AddNetworker(appnet.TypeDmsg, n)
+		require.NoError(t, err)
+
+		connID, localPort, err := cl.Dial(remote)
+		require.Equal(t, &routing.CloseError{Code: routing.ClosePolicyRejected}, err)
+		require.Equal(t, connID, uint16(0))
+		require.Equal(t, localPort, routing.Port(0))
+	})
 }
 
 func TestRPCClient_Listen(t *testing.T) {
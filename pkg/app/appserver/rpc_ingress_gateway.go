@@ -1,6 +1,7 @@
 package appserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/idmanager"
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/routing"
 	"github.com/skycoin/skywire/pkg/util/rpcutil"
 )
@@ -27,6 +29,10 @@ type RPCIOErr struct {
 	IsNetErr       bool
 	IsTimeoutErr   bool
 	IsTemporaryErr bool
+	// route group close errors are passed along with their close code,
+	// so that apps may decide whether to redial.
+	IsCloseErr bool
+	CloseCode  routing.CloseCode
 }
 
 // ToError converts `*RPCIOErr` to `error`.
@@ -35,6 +41,10 @@ func (e *RPCIOErr) ToError() error {
 		return nil
 	}
 
+	if e.IsCloseErr {
+		return &routing.CloseError{Code: e.CloseCode}
+	}
+
 	if !e.IsNetErr {
 		switch e.Text {
 		case io.EOF.Error():
@@ -58,10 +68,18 @@ func (e *RPCIOErr) ToError() error {
 // RPCIngressGateway is a RPC interface for the app server.
 type RPCIngressGateway struct {
 	lm  *idmanager.Manager // contains listeners associated with their IDs
-	cm  *idmanager.Manager // contains connections associated with their IDs
+	cm  *idmanager.Manager // contains both stream and datagram connections associated with their IDs
 	log *logging.Logger
 }
 
+// anyConn is implemented by both stream and datagram connections.
+type anyConn interface {
+	Close() error
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
 // NewRPCGateway constructs new server RPC interface.
 func NewRPCGateway(log *logging.Logger) *RPCIngressGateway {
 	if log == nil {
@@ -78,21 +96,44 @@ func NewRPCGateway(log *logging.Logger) *RPCIngressGateway {
 type DialResp struct {
 	ConnID    uint16
 	LocalPort routing.Port
+	// route group close errors, e.g. policy rejections, are passed typed within the response.
+	Err *RPCIOErr
+}
+
+// DialReq contains request parameters for `DialWithPreferences`.
+type DialReq struct {
+	Remote      appnet.Addr
+	Preferences rfclient.RoutePreferences
 }
 
 // Dial dials to the remote.
 func (r *RPCIngressGateway) Dial(remote *appnet.Addr, resp *DialResp) (err error) {
 	defer rpcutil.LogCall(r.log, "Dial", remote)(resp, &err)
 
+	return r.dial(func() (net.Conn, error) {
+		return appnet.Dial(*remote)
+	}, resp)
+}
+
+// DialWithPreferences dials to the remote via the routes satisfying the preferences.
+func (r *RPCIngressGateway) DialWithPreferences(req *DialReq, resp *DialResp) (err error) {
+	defer rpcutil.LogCall(r.log, "DialWithPreferences", req)(resp, &err)
+
+	return r.dial(func() (net.Conn, error) {
+		return appnet.DialWithPreferences(context.Background(), req.Remote, req.Preferences)
+	}, resp)
+}
+
+func (r *RPCIngressGateway) dial(dial func() (net.Conn, error), resp *DialResp) error {
 	reservedConnID, free, err := r.cm.ReserveNextID()
 	if err != nil {
 		return err
 	}
 
-	conn, err := appnet.Dial(*remote)
+	conn, err := dial()
 	if err != nil {
 		free()
-		return err
+		return dialErr(err, resp)
 	}
 
 	wrappedConn, err := appnet.WrapConn(conn)
@@ -117,6 +158,64 @@ func (r *RPCIngressGateway) Dial(remote *appnet.Addr, resp *DialResp) (err error
 	return nil
 }
 
+// DialPacket dials to the remote for datagrams.
+func (r *RPCIngressGateway) DialPacket(remote *appnet.Addr, resp *DialResp) (err error) {
+	defer rpcutil.LogCall(r.log, "DialPacket", remote)(resp, &err)
+
+	reservedConnID, free, err := r.cm.ReserveNextID()
+	if err != nil {
+		return err
+	}
+
+	conn, err := appnet.DialPacket(*remote)
+	if err != nil {
+		free()
+		return dialErr(err, resp)
+	}
+
+	if err := r.cm.Set(*reservedConnID, conn); err != nil {
+		if cErr := conn.Close(); cErr != nil {
+			r.log.WithError(cErr).Error("Error closing packet conn.")
+		}
+		free()
+		return err
+	}
+
+	localAddr := conn.LocalAddr().(appnet.Addr)
+
+	resp.ConnID = *reservedConnID
+	resp.LocalPort = localAddr.Port
+
+	return nil
+}
+
+// ListenPacket starts listening for datagrams. Returned ID is the one of the datagram connection.
+func (r *RPCIngressGateway) ListenPacket(local *appnet.Addr, connID *uint16) (err error) {
+	defer rpcutil.LogCall(r.log, "ListenPacket", local)(connID, &err)
+
+	reservedConnID, free, err := r.cm.ReserveNextID()
+	if err != nil {
+		return err
+	}
+
+	conn, err := appnet.ListenPacket(*local)
+	if err != nil {
+		free()
+		return err
+	}
+
+	if err := r.cm.Set(*reservedConnID, conn); err != nil {
+		if cErr := conn.Close(); cErr != nil {
+			r.log.WithError(cErr).Error("Error closing packet conn.")
+		}
+		free()
+		return err
+	}
+
+	*connID = *reservedConnID
+	return nil
+}
+
 // Listen starts listening.
 func (r *RPCIngressGateway) Listen(local *appnet.Addr, lisID *uint16) (err error) {
 	defer rpcutil.LogCall(r.log, "Listen", local)(lisID, &err)
@@ -261,6 +360,61 @@ func (r *RPCIngressGateway) Read(req *ReadReq, resp *ReadResp) error {
 	return nil
 }
 
+// WriteToReq contains arguments for `WriteTo`.
+type WriteToReq struct {
+	ConnID uint16
+	B      []byte
+	Remote appnet.Addr
+}
+
+// WriteTo writes a datagram to the remote via the datagram connection.
+func (r *RPCIngressGateway) WriteTo(req *WriteToReq, resp *WriteResp) error {
+	conn, err := r.getPacketConn(req.ConnID)
+	if err != nil {
+		return err
+	}
+
+	resp.N, err = conn.WriteTo(req.B, req.Remote)
+	resp.Err = ioErrToRPCIOErr(err)
+
+	// avoid error in RPC pipeline, error is included in response body
+	return nil
+}
+
+// ReadFromResp contains response parameters for `ReadFrom`.
+type ReadFromResp struct {
+	B      []byte
+	N      int
+	Remote appnet.Addr
+	Err    *RPCIOErr
+}
+
+// ReadFrom reads a datagram from the datagram connection specified by `connID`.
+func (r *RPCIngressGateway) ReadFrom(req *ReadReq, resp *ReadFromResp) error {
+	conn, err := r.getPacketConn(req.ConnID)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, req.BufLen)
+
+	var addr net.Addr
+	resp.N, addr, err = conn.ReadFrom(buf)
+	if resp.N != 0 {
+		resp.B = make([]byte, resp.N)
+		copy(resp.B, buf[:resp.N])
+	}
+
+	if remote, ok := addr.(appnet.Addr); ok {
+		resp.Remote = remote
+	}
+
+	resp.Err = ioErrToRPCIOErr(err)
+
+	// avoid error in RPC pipeline, error is included in response body
+	return nil
+}
+
 // CloseConn closes connection specified by `connID`.
 func (r *RPCIngressGateway) CloseConn(connID *uint16, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "CloseConn", connID)(nil, &err)
@@ -293,7 +447,7 @@ type DeadlineReq struct {
 
 // SetDeadline sets deadline for connection specified by `connID`.
 func (r *RPCIngressGateway) SetDeadline(req *DeadlineReq, _ *struct{}) error {
-	conn, err := r.getConn(req.ConnID)
+	conn, err := r.getAnyConn(req.ConnID)
 	if err != nil {
 		return err
 	}
@@ -303,7 +457,7 @@ func (r *RPCIngressGateway) SetDeadline(req *DeadlineReq, _ *struct{}) error {
 
 // SetReadDeadline sets read deadline for connection specified by `connID`.
 func (r *RPCIngressGateway) SetReadDeadline(req *DeadlineReq, _ *struct{}) error {
-	conn, err := r.getConn(req.ConnID)
+	conn, err := r.getAnyConn(req.ConnID)
 	if err != nil {
 		return err
 	}
@@ -313,7 +467,7 @@ func (r *RPCIngressGateway) SetReadDeadline(req *DeadlineReq, _ *struct{}) error
 
 // SetWriteDeadline sets read deadline for connection specified by `connID`.
 func (r *RPCIngressGateway) SetWriteDeadline(req *DeadlineReq, _ *struct{}) error {
-	conn, err := r.getConn(req.ConnID)
+	conn, err := r.getAnyConn(req.ConnID)
 	if err != nil {
 		return err
 	}
@@ -332,15 +486,15 @@ func (r *RPCIngressGateway) popListener(lisID uint16) (net.Listener, error) {
 	return idmanager.AssertListener(lisIfc)
 }
 
-// popConn gets conn from the manager by `connID` and removes it.
+// popConn gets either stream or datagram conn from the manager by `connID` and removes it.
 // Handles type assertion.
-func (r *RPCIngressGateway) popConn(connID uint16) (net.Conn, error) {
+func (r *RPCIngressGateway) popConn(connID uint16) (anyConn, error) {
 	connIfc, err := r.cm.Pop(connID)
 	if err != nil {
 		return nil, fmt.Errorf("no conn: %w", err)
 	}
 
-	return idmanager.AssertConn(connIfc)
+	return assertAnyConn(connIfc)
 }
 
 // getListener gets listener from the manager by `lisID`. Handles type assertion.
@@ -363,6 +517,46 @@ func (r *RPCIngressGateway) getConn(connID uint16) (net.Conn, error) {
 	return idmanager.AssertConn(connIfc)
 }
 
+// getPacketConn gets datagram conn from the manager by `connID`. Handles type assertion.
+func (r *RPCIngressGateway) getPacketConn(connID uint16) (net.PacketConn, error) {
+	connIfc, ok := r.cm.Get(connID)
+	if !ok {
+		return nil, fmt.Errorf("no conn with key %d", connID)
+	}
+
+	return idmanager.AssertPacketConn(connIfc)
+}
+
+// getAnyConn gets either stream or datagram conn from the manager by `connID`. Handles type assertion.
+func (r *RPCIngressGateway) getAnyConn(connID uint16) (anyConn, error) {
+	connIfc, ok := r.cm.Get(connID)
+	if !ok {
+		return nil, fmt.Errorf("no conn with key %d", connID)
+	}
+
+	return assertAnyConn(connIfc)
+}
+
+func assertAnyConn(v interface{}) (anyConn, error) {
+	if conn, err := idmanager.AssertConn(v); err == nil {
+		return conn, nil
+	}
+
+	return idmanager.AssertPacketConn(v)
+}
+
+// dialErr puts route group close errors to `resp`, so they reach the app typed.
+// The rest of errors are returned as is.
+func dialErr(err error, resp *DialResp) error {
+	var closeErr *routing.CloseError
+	if errors.As(err, &closeErr) {
+		resp.Err = ioErrToRPCIOErr(err)
+		return nil
+	}
+
+	return err
+}
+
 func ioErrToRPCIOErr(err error) *RPCIOErr {
 	if err == nil {
 		return nil
@@ -372,6 +566,14 @@ func ioErrToRPCIOErr(err error) *RPCIOErr {
 		Text: err.Error(),
 	}
 
+	var closeErr *routing.CloseError
+	if errors.As(err, &closeErr) {
+		rpcIOErr.IsCloseErr = true
+		rpcIOErr.CloseCode = closeErr.Code
+
+		return rpcIOErr
+	}
+
 	if netErr, ok := err.(net.Error); ok {
 		rpcIOErr.IsNetErr = true
 		rpcIOErr.IsTimeoutErr = netErr.Timeout()
@@ -491,6 +491,10 @@ func TestRPCGateway_Read(t *testing.T) {
 	t.Run("read error", func(t *testing.T) {
 		testRPCGatewayReadError(t, l, readBuf)
 	})
+
+	t.Run("close error", func(t *testing.T) {
+		testRPCGatewayReadCloseError(t, l, readBuf)
+	})
 }
 
 func testRPCGatewayReadOK(t *testing.T, l *logging.Logger, readBuf []byte) {
@@ -583,6 +587,145 @@ func testRPCGatewayReadError(t *testing.T, l *logging.Logger, readBuf []byte) {
 	require.Equal(t, wantResp, resp)
 }
 
+func testRPCGatewayReadCloseError(t *testing.T, l *logging.Logger, readBuf []byte) {
+	rpc := NewRPCGateway(l)
+
+	readErr := fmt.Errorf("noise: %w", &routing.CloseError{Code: routing.CloseKeepAliveTimeout})
+
+	conn := &appcommon.MockConn{}
+	conn.On("Read", readBuf).Return(0, readErr)
+
+	connID := addConn(t, rpc, conn)
+
+	req := ReadReq{
+		ConnID: connID,
+		BufLen: len(readBuf),
+	}
+
+	var resp ReadResp
+	err := rpc.Read(&req, &resp)
+	require.NoError(t, err)
+	require.NotNil(t, resp.Err)
+	require.True(t, resp.Err.IsCloseErr)
+	require.Equal(t, routing.CloseKeepAliveTimeout, resp.Err.CloseCode)
+
+	var closeErr *routing.CloseError
+	require.True(t, errors.As(resp.Err.ToError(), &closeErr))
+	require.Equal(t, routing.CloseKeepAliveTimeout, closeErr.Code)
+	require.True(t, closeErr.Transient())
+}
+
+// testPacketConn is a datagram connection which passes datagrams via channels.
+type testPacketConn struct {
+	net.PacketConn
+	readCh  chan []byte
+	writeCh chan []byte
+	remote  appnet.Addr
+	err     error
+}
+
+func (c *testPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	if c.err != nil {
+		return 0, nil, c.err
+	}
+
+	return copy(p, <-c.readCh), c.remote, nil
+}
+
+func (c *testPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	if addr != c.remote {
+		return 0, appnet.ErrUnknownRemote
+	}
+
+	c.writeCh <- p
+
+	return len(p), nil
+}
+
+func TestRPCGateway_WriteTo(t *testing.T) {
+	l := logging.MustGetLogger("rpc_gateway")
+
+	remote := prepAddr(appnet.TypeSkynet)
+	writeBuff := []byte{1, 1, 1, 1, 1}
+
+	t.Run("ok", func(t *testing.T) {
+		rpc := NewRPCGateway(l)
+
+		conn := &testPacketConn{writeCh: make(chan []byte, 1), remote: remote}
+		connID := addPacketConn(t, rpc, conn)
+
+		var resp WriteResp
+		require.NoError(t, rpc.WriteTo(&WriteToReq{ConnID: connID, B: writeBuff, Remote: remote}, &resp))
+		require.Equal(t, WriteResp{N: len(writeBuff)}, resp)
+		require.Equal(t, writeBuff, <-conn.writeCh)
+	})
+
+	t.Run("unknown remote", func(t *testing.T) {
+		rpc := NewRPCGateway(l)
+
+		connID := addPacketConn(t, rpc, &testPacketConn{remote: remote})
+
+		var resp WriteResp
+		require.NoError(t, rpc.WriteTo(&WriteToReq{ConnID: connID, B: writeBuff, Remote: prepAddr(appnet.TypeSkynet)}, &resp))
+		require.Zero(t, resp.N)
+		require.NotNil(t, resp.Err)
+		require.Equal(t, appnet.ErrUnknownRemote.Error(), resp.Err.Text)
+	})
+
+	t.Run("not a packet conn", func(t *testing.T) {
+		rpc := NewRPCGateway(l)
+
+		connID := addConn(t, rpc, &appcommon.MockConn{})
+
+		var resp WriteResp
+		require.Error(t, rpc.WriteTo(&WriteToReq{ConnID: connID, B: writeBuff, Remote: remote}, &resp))
+	})
+}
+
+func TestRPCGateway_ReadFrom(t *testing.T) {
+	l := logging.MustGetLogger("rpc_gateway")
+
+	remote := prepAddr(appnet.TypeSkynet)
+
+	t.Run("ok", func(t *testing.T) {
+		rpc := NewRPCGateway(l)
+
+		conn := &testPacketConn{readCh: make(chan []byte, 1), remote: remote}
+		conn.readCh <- []byte("datagram")
+		connID := addPacketConn(t, rpc, conn)
+
+		var resp ReadFromResp
+		require.NoError(t, rpc.ReadFrom(&ReadReq{ConnID: connID, BufLen: 4}, &resp))
+
+		// the rest of the datagram is discarded
+		require.Equal(t, ReadFromResp{B: []byte("data"), N: 4, Remote: remote}, resp)
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		rpc := NewRPCGateway(l)
+
+		readErr := errors.New("read error")
+		connID := addPacketConn(t, rpc, &testPacketConn{err: readErr})
+
+		var resp ReadFromResp
+		require.NoError(t, rpc.ReadFrom(&ReadReq{ConnID: connID, BufLen: 4}, &resp))
+		require.Equal(t, ReadFromResp{Err: &RPCIOErr{Text: readErr.Error()}}, resp)
+	})
+
+	t.Run("no such conn", func(t *testing.T) {
+		rpc := NewRPCGateway(l)
+
+		var resp ReadFromResp
+		err := rpc.ReadFrom(&ReadReq{ConnID: 1, BufLen: 4}, &resp)
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "no conn"))
+	})
+}
+
 func TestRPCGateway_SetWriteDeadline(t *testing.T) {
 	l := logging.MustGetLogger("rpc_gateway")
 
@@ -1045,3 +1188,13 @@ func addListener(t *testing.T, rpc *RPCIngressGateway, lis net.Listener) uint16
 
 	return *lisID
 }
+
+func addPacketConn(t *testing.T, rpc *RPCIngressGateway, conn net.PacketConn) uint16 {
+	connID, _, err := rpc.cm.ReserveNextID()
+	require.NoError(t, err)
+
+	err = rpc.cm.Set(*connID, conn)
+	require.NoError(t, err)
+
+	return *connID
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/appserver"
 	"github.com/skycoin/skywire/pkg/app/idmanager"
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/routing"
 )
 
@@ -23,6 +24,7 @@ type Client struct {
 	rpcC    appserver.RPCIngressClient
 	lm      *idmanager.Manager // contains listeners associated with their IDs
 	cm      *idmanager.Manager // contains connections associated with their IDs
+	pcm     *idmanager.Manager // contains datagram connections associated with their IDs
 	closers []io.Closer        // additional things to close on close
 }
 
@@ -54,6 +56,7 @@ func NewClientFromConfig(log logrus.FieldLogger, conf appcommon.ProcConfig, subs
 		rpcC:    appserver.NewRPCIngressClient(rpc.NewClient(conn), conf.ProcKey),
 		lm:      idmanager.New(),
 		cm:      idmanager.New(),
+		pcm:     idmanager.New(),
 		closers: closers,
 	}, nil
 }
@@ -70,6 +73,22 @@ func (c *Client) Dial(remote appnet.Addr) (net.Conn, error) {
 		return nil, err
 	}
 
+	return c.addConn(remote, connID, localPort)
+}
+
+// DialWithPreferences dials the remote visor using `remote` via the routes satisfying `prefs`.
+// Preferences are only supported by skynet.
+func (c *Client) DialWithPreferences(remote appnet.Addr, prefs rfclient.RoutePreferences) (net.Conn, error) {
+	connID, localPort, err := c.rpcC.DialWithPreferences(remote, prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addConn(remote, connID, localPort)
+}
+
+func (c *Client) addConn(remote appnet.Addr, connID uint16, localPort routing.Port) (net.Conn, error) {
+
 	conn := &Conn{
 		id:  connID,
 		rpc: c.rpcC,
@@ -143,12 +162,71 @@ func (c *Client) Listen(n appnet.Type, port routing.Port) (net.Listener, error)
 	return listener, nil
 }
 
+// DialPacket dials the remote visor using `remote` for datagrams.
+// Datagrams of the returned connection may only be written to `remote`.
+func (c *Client) DialPacket(remote appnet.Addr) (net.PacketConn, error) {
+	connID, localPort, err := c.rpcC.DialPacket(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addPacketConn(connID, appnet.Addr{
+		Net:    remote.Net,
+		PubKey: c.conf.VisorPK,
+		Port:   localPort,
+	})
+}
+
+// ListenPacket listens on the specified `port` for the incoming datagrams.
+func (c *Client) ListenPacket(n appnet.Type, port routing.Port) (net.PacketConn, error) {
+	local := appnet.Addr{
+		Net:    n,
+		PubKey: c.conf.VisorPK,
+		Port:   port,
+	}
+
+	connID, err := c.rpcC.ListenPacket(local)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addPacketConn(connID, local)
+}
+
+func (c *Client) addPacketConn(connID uint16, local appnet.Addr) (net.PacketConn, error) {
+	conn := &PacketConn{
+		id:    connID,
+		rpc:   c.rpcC,
+		local: local,
+	}
+
+	conn.freeConnMx.Lock()
+
+	free, err := c.pcm.Add(connID, conn)
+	if err != nil {
+		conn.freeConnMx.Unlock()
+
+		if err := conn.Close(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			c.log.WithError(err).Error("Received unexpected error when closing packet conn.")
+		}
+
+		return nil, err
+	}
+
+	conn.freeConn = free
+
+	conn.freeConnMx.Unlock()
+
+	return conn, nil
+}
+
 // Close closes client/server communication entirely. It closes all open
 // listeners and connections.
 func (c *Client) Close() {
 	var (
-		listeners []net.Listener
-		conns     []net.Conn
+		listeners   []net.Listener
+		conns       []net.Conn
+		packetConns []net.PacketConn
 	)
 
 	// Fill listeners and connections.
@@ -170,6 +248,15 @@ func (c *Client) Close() {
 		conns = append(conns, conn)
 		return true
 	})
+	c.pcm.DoRange(func(_ uint16, v interface{}) bool {
+		conn, err := idmanager.AssertPacketConn(v)
+		if err != nil {
+			c.log.Error(err)
+			return true
+		}
+		packetConns = append(packetConns, conn)
+		return true
+	})
 
 	// Close everything.
 	for _, lis := range listeners {
@@ -182,6 +269,11 @@ func (c *Client) Close() {
 			c.log.WithError(err).Error("Error closing conn.")
 		}
 	}
+	for _, conn := range packetConns {
+		if err := conn.Close(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			c.log.WithError(err).Error("Error closing packet conn.")
+		}
+	}
 	for _, v := range c.closers {
 		if err := v.Close(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
 			c.log.WithError(err).Error("Error closing closer.")
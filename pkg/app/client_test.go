@@ -125,6 +125,59 @@ func TestClient_Dial(t *testing.T) {
 	})
 }
 
+func TestClient_DialPacket(t *testing.T) {
+	l := logging.MustGetLogger("app2_client")
+	visorPK, _ := cipher.GenerateKeyPair()
+
+	remotePK, _ := cipher.GenerateKeyPair()
+	remote := appnet.Addr{
+		Net:    appnet.TypeSkynet,
+		PubKey: remotePK,
+		Port:   routing.Port(120),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		dialConnID := uint16(1)
+		dialLocalPort := routing.Port(1)
+		var dialErr error
+
+		rpc := &appserver.MockRPCIngressClient{}
+		rpc.On("DialPacket", remote).Return(dialConnID, dialLocalPort, dialErr)
+
+		cl := prepClient(l, visorPK, rpc)
+
+		conn, err := cl.DialPacket(remote)
+		require.NoError(t, err)
+
+		appConn, ok := conn.(*PacketConn)
+		require.True(t, ok)
+
+		require.Equal(t, dialConnID, appConn.id)
+		require.Equal(t, appnet.Addr{
+			Net:    remote.Net,
+			PubKey: visorPK,
+			Port:   dialLocalPort,
+		}, appConn.LocalAddr())
+		require.NotNil(t, appConn.freeConn)
+
+		_, ok = cl.pcm.Get(appConn.id)
+		require.True(t, ok)
+	})
+
+	t.Run("dial error", func(t *testing.T) {
+		dialErr := errors.New("dial error")
+
+		rpc := &appserver.MockRPCIngressClient{}
+		rpc.On("DialPacket", remote).Return(uint16(0), routing.Port(0), dialErr)
+
+		cl := prepClient(l, visorPK, rpc)
+
+		conn, err := cl.DialPacket(remote)
+		require.Equal(t, dialErr, err)
+		require.Nil(t, conn)
+	})
+}
+
 func TestClient_Listen(t *testing.T) {
 	l := logging.MustGetLogger("app2_client")
 	visorPK, _ := cipher.GenerateKeyPair()
@@ -304,5 +357,6 @@ func prepClient(l *logging.Logger, visorPK cipher.PubKey, rpc appserver.RPCIngre
 		rpcC: rpc,
 		lm:   idmanager.New(),
 		cm:   idmanager.New(),
+		pcm:  idmanager.New(),
 	}
 }
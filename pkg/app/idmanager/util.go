@@ -24,3 +24,13 @@ func AssertConn(v interface{}) (net.Conn, error) {
 
 	return conn, nil
 }
+
+// AssertPacketConn asserts that `v` is of type `net.PacketConn`.
+func AssertPacketConn(v interface{}) (net.PacketConn, error) {
+	conn, ok := v.(net.PacketConn)
+	if !ok {
+		return nil, errors.New("wrong type of value stored for packet conn")
+	}
+
+	return conn, nil
+}
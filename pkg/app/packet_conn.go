@@ -0,0 +1,77 @@
+package app
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire/pkg/app/appnet"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+)
+
+// PacketConn is a datagram connection from app client to the server.
+// Implements `net.PacketConn`.
+type PacketConn struct {
+	id         uint16
+	rpc        appserver.RPCIngressClient
+	local      appnet.Addr
+	freeConn   func() bool
+	freeConnMx sync.RWMutex
+}
+
+// ReadFrom reads a single datagram from connection.
+func (c *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, remote, err := c.rpc.ReadFrom(c.id, b)
+	if err != nil {
+		return n, nil, err
+	}
+
+	return n, remote, nil
+}
+
+// WriteTo writes `b` as a single datagram to the remote `addr`.
+func (c *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	remote, ok := addr.(appnet.Addr)
+	if !ok {
+		return 0, appnet.ErrUnknownAddrType
+	}
+
+	return c.rpc.WriteTo(c.id, b, remote)
+}
+
+// Close closes connection.
+func (c *PacketConn) Close() error {
+	c.freeConnMx.RLock()
+	defer c.freeConnMx.RUnlock()
+
+	if c.freeConn != nil {
+		if freed := c.freeConn(); !freed {
+			return errors.New("conn is already closed")
+		}
+
+		return c.rpc.CloseConn(c.id)
+	}
+
+	return nil
+}
+
+// LocalAddr returns local address of connection.
+func (c *PacketConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// SetDeadline sets read and write deadlines for connection.
+func (c *PacketConn) SetDeadline(t time.Time) error {
+	return c.rpc.SetDeadline(c.id, t)
+}
+
+// SetReadDeadline sets read deadline for connection.
+func (c *PacketConn) SetReadDeadline(t time.Time) error {
+	return c.rpc.SetReadDeadline(c.id, t)
+}
+
+// SetWriteDeadline sets write deadline for connection.
+func (c *PacketConn) SetWriteDeadline(t time.Time) error {
+	return c.rpc.SetWriteDeadline(c.id, t)
+}
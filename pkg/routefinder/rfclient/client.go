@@ -29,6 +29,7 @@ var ErrTransportNotFound = errors.New("transport not found")
 type RouteOptions struct {
 	MinHops uint16
 	MaxHops uint16
+	RoutePreferences
 }
 
 // FindRoutesRequest parses json body for /routes endpoint request
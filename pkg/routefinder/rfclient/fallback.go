@@ -0,0 +1,38 @@
+package rfclient
+
+import (
+	"context"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+type fallbackClient struct {
+	primary  Client
+	fallback Client
+}
+
+// NewWithFallback constructs new Client which finds routes via `primary` and turns to `fallback`
+// whenever `primary` fails. `ErrTransportNotFound` of `primary` is returned as is.
+func NewWithFallback(primary, fallback Client) Client {
+	return &fallbackClient{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+// FindRoutes finds routes via primary client, if it fails, routes are found via fallback client.
+func (c *fallbackClient) FindRoutes(ctx context.Context, rts []routing.PathEdges, opts *RouteOptions) (map[routing.PathEdges][][]routing.Hop, error) {
+	paths, err := c.primary.FindRoutes(ctx, rts, opts)
+	if err == nil || err == ErrTransportNotFound {
+		return paths, err
+	}
+
+	log.WithError(err).Warn("Failed to find routes via route finder, falling back...")
+
+	return c.fallback.FindRoutes(ctx, rts, opts)
+}
+
+// Health checks health of the primary client.
+func (c *fallbackClient) Health(ctx context.Context) (int, error) {
+	return c.primary.Health(ctx)
+}
@@ -0,0 +1,250 @@
+package rfclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+const (
+	// localMaxHops limits the depth of the local search regardless of the requested `MaxHops`,
+	// each visor on the way costs a request to the transport discovery.
+	localMaxHops = 4
+	// localMaxLookups limits the number of transport discovery requests per found route.
+	localMaxLookups = 64
+	// localMaxPaths is the max number of paths returned for a single pair of edges.
+	localMaxPaths = 16
+)
+
+// LocalTransportsFunc returns entries of the transports of the local visor which are up,
+// along with their probe stats if there are any.
+type LocalTransportsFunc func() []*transport.EntryWithStatus
+
+type localClient struct {
+	pk    cipher.PubKey
+	tps   LocalTransportsFunc
+	tpDis transport.DiscoveryClient
+}
+
+// NewLocal constructs new Client which computes routes locally. The graph is built out
+// of the transports of the local visor `pk` and the transports of other visors known
+// to the transport discovery. It's meant to be used when the route finder is unreachable.
+func NewLocal(pk cipher.PubKey, tps LocalTransportsFunc, tpDis transport.DiscoveryClient) Client {
+	return &localClient{
+		pk:    pk,
+		tps:   tps,
+		tpDis: tpDis,
+	}
+}
+
+// FindRoutes returns up to `localMaxPaths` shortest paths for each of `rts`, paths have from
+// `MinHops` to `MaxHops` hops and don't visit any visor twice. Exclusions and transport types of
+// the preferences are honoured. Paths are scored by hops, unless latency scoring is requested.
+// Then the probe stats of transports are used as edge weights, transports which aren't measured
+// weigh `DefaultTransportWeight`.
+func (c *localClient) FindRoutes(ctx context.Context, rts []routing.PathEdges, opts *RouteOptions) (map[routing.PathEdges][][]routing.Hop, error) {
+	minHops, maxHops := uint16(0), uint16(localMaxHops)
+	var prefs RoutePreferences
+
+	if opts != nil {
+		prefs = opts.RoutePreferences
+
+		minHops = opts.MinHops
+		if opts.MaxHops != 0 && opts.MaxHops < maxHops {
+			maxHops = opts.MaxHops
+		}
+	}
+
+	g := &localGraph{
+		c:       c,
+		edges:   make(map[cipher.PubKey][]routing.Hop),
+		weights: make(map[uuid.UUID]time.Duration),
+	}
+
+	paths := make(map[routing.PathEdges][][]routing.Hop, len(rts))
+
+	for _, rt := range rts {
+		found, err := g.shortestPaths(ctx, rt[0], rt[1], int(minHops), int(maxHops), &prefs)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(found) == 0 {
+			return nil, ErrTransportNotFound
+		}
+
+		prefs.SortPaths(found, g.weight)
+
+		paths[rt] = found
+	}
+
+	return paths, nil
+}
+
+// Health always reports local route finder as healthy.
+func (c *localClient) Health(_ context.Context) (int, error) {
+	return http.StatusOK, nil
+}
+
+// localGraph is a lazily built graph of visors, edges of each visor are obtained on the first use.
+type localGraph struct {
+	c       *localClient
+	local   []*transport.EntryWithStatus // transports of the local visor, obtained once
+	edges   map[cipher.PubKey][]routing.Hop
+	weights map[uuid.UUID]time.Duration // of the measured transports
+	lookups int
+}
+
+// localTransports returns transports of the local visor.
+func (g *localGraph) localTransports() []*transport.EntryWithStatus {
+	if g.local == nil {
+		g.local = g.c.tps()
+		if g.local == nil {
+			g.local = []*transport.EntryWithStatus{}
+		}
+	}
+
+	return g.local
+}
+
+// outgoing returns hops going out of visor `pk`. Transports of the local visor are taken
+// from the transport manager, the ones of the other visors - from the transport discovery.
+// Local transports are edges in both directions, so the ones to `pk` are used even if
+// the transport discovery doesn't know them. Visors which can't be looked up are treated
+// as having no transports besides the local ones.
+func (g *localGraph) outgoing(ctx context.Context, pk cipher.PubKey) ([]routing.Hop, error) {
+	if hops, ok := g.edges[pk]; ok {
+		return hops, nil
+	}
+
+	// the local transports are seen first, as their measurements are the most recent ones
+	tps := g.localTransports()
+
+	if pk != g.c.pk && g.lookups < localMaxLookups {
+		g.lookups++
+
+		remoteTps, err := g.c.tpDis.GetTransportsByEdge(ctx, pk)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			log.WithError(err).Debugf("Failed to get transports of %s", pk)
+		}
+
+		tps = append(tps[:len(tps):len(tps)], remoteTps...)
+	}
+
+	hops := make([]routing.Hop, 0, len(tps))
+	seen := make(map[uuid.UUID]struct{}, len(tps))
+
+	for _, tp := range tps {
+		if tp == nil || !tp.IsUp || tp.Entry == nil || !tp.Entry.HasEdge(pk) {
+			continue
+		}
+
+		if _, ok := seen[tp.Entry.ID]; ok {
+			continue
+		}
+		seen[tp.Entry.ID] = struct{}{}
+
+		if _, ok := g.weights[tp.Entry.ID]; !ok && tp.Probe != nil {
+			g.weights[tp.Entry.ID] = tp.Probe.Weight()
+		}
+
+		hops = append(hops, routing.Hop{
+			TpID: tp.Entry.ID,
+			From: pk,
+			To:   tp.Entry.RemoteEdge(pk),
+		})
+	}
+
+	g.edges[pk] = hops
+
+	return hops, nil
+}
+
+// shortestPaths does a breadth-first search of the paths from `src` to `dst` satisfying `prefs`,
+// so paths are returned in the order of the number of hops.
+func (g *localGraph) shortestPaths(ctx context.Context, src, dst cipher.PubKey, minHops, maxHops int,
+	prefs *RoutePreferences) ([][]routing.Hop, error) {
+	var paths [][]routing.Hop
+
+	queue := [][]routing.Hop{nil}
+
+	for len(queue) > 0 && len(paths) < localMaxPaths {
+		path := queue[0]
+		queue = queue[1:]
+
+		if len(path) == maxHops {
+			continue
+		}
+
+		from := src
+		if len(path) > 0 {
+			from = path[len(path)-1].To
+		}
+
+		hops, err := g.outgoing(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hop := range hops {
+			if pathVisits(path, src, hop.To) || !prefs.allowsTransport(hop) {
+				continue
+			}
+
+			if hop.To != dst && prefs.excludes(hop.To) {
+				continue
+			}
+
+			next := make([]routing.Hop, len(path), len(path)+1)
+			copy(next, path)
+			next = append(next, hop)
+
+			if hop.To == dst {
+				if len(next) >= minHops {
+					paths = append(paths, next)
+				}
+
+				continue
+			}
+
+			queue = append(queue, next)
+		}
+	}
+
+	if len(paths) > localMaxPaths {
+		paths = paths[:localMaxPaths]
+	}
+
+	return paths, nil
+}
+
+// weight returns the weight of the transport of `tpID` if it's measured.
+func (g *localGraph) weight(tpID uuid.UUID) (time.Duration, bool) {
+	w, ok := g.weights[tpID]
+	return w, ok
+}
+
+// pathVisits checks whether path starting at `src` visits visor `pk`.
+func pathVisits(path []routing.Hop, src, pk cipher.PubKey) bool {
+	if pk == src {
+		return true
+	}
+
+	for _, hop := range path {
+		if hop.To == pk {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package rfclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+func TestLocalClient_FindRoutes(t *testing.T) {
+	ctx := context.Background()
+	dc := transport.NewDiscoveryMock()
+
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+	pkD, _ := cipher.GenerateKeyPair()
+	pkE, _ := cipher.GenerateKeyPair()
+
+	register := func(pk1, pk2 cipher.PubKey) *transport.Entry {
+		entry := transport.NewEntry(pk1, pk2, "stcpr", true)
+		require.NoError(t, dc.RegisterTransports(ctx, &transport.SignedEntry{Entry: entry}))
+		return entry
+	}
+
+	// A - B - D is the shortest way from A to D, A - C - B - D is the longer one.
+	tpAB := register(pkA, pkB)
+	tpAC := register(pkA, pkC)
+	tpCB := register(pkC, pkB)
+	tpBD := register(pkB, pkD)
+
+	// transport discovery doesn't know the A - E transport.
+	tpAE := transport.NewEntry(pkA, pkE, "stcpr", true)
+
+	var probeAB *transport.ProbeStats
+
+	localTps := func() []*transport.EntryWithStatus {
+		return []*transport.EntryWithStatus{
+			{Entry: tpAB, IsUp: true, Probe: probeAB},
+			{Entry: tpAC, IsUp: true, Probe: &transport.ProbeStats{RTT: 10 * time.Millisecond, Samples: 1}},
+			{Entry: tpAE, IsUp: true},
+		}
+	}
+
+	c := NewLocal(pkA, localTps, dc)
+
+	fwd := routing.PathEdges{pkA, pkD}
+	rev := routing.PathEdges{pkD, pkA}
+
+	paths, err := c.FindRoutes(ctx, []routing.PathEdges{fwd, rev}, &RouteOptions{MinHops: 0, MaxHops: 50})
+	require.NoError(t, err)
+
+	require.Equal(t, [][]routing.Hop{
+		{{TpID: tpAB.ID, From: pkA, To: pkB}, {TpID: tpBD.ID, From: pkB, To: pkD}},
+		{{TpID: tpAC.ID, From: pkA, To: pkC}, {TpID: tpCB.ID, From: pkC, To: pkB}, {TpID: tpBD.ID, From: pkB, To: pkD}},
+	}, paths[fwd])
+
+	require.Len(t, paths[rev], 2)
+	require.Equal(t, []routing.Hop{
+		{TpID: tpBD.ID, From: pkD, To: pkB}, {TpID: tpAB.ID, From: pkB, To: pkA},
+	}, paths[rev][0])
+
+	paths, err = c.FindRoutes(ctx, []routing.PathEdges{fwd}, &RouteOptions{MinHops: 3, MaxHops: 3})
+	require.NoError(t, err)
+	require.Len(t, paths[fwd], 1)
+	require.Len(t, paths[fwd][0], 3)
+
+	_, err = c.FindRoutes(ctx, []routing.PathEdges{fwd}, &RouteOptions{MinHops: 0, MaxHops: 1})
+	require.Equal(t, ErrTransportNotFound, err)
+
+	prefs := RoutePreferences{ExcludePKs: []cipher.PubKey{pkC}}
+	paths, err = c.FindRoutes(ctx, []routing.PathEdges{fwd}, &RouteOptions{MaxHops: 50, RoutePreferences: prefs})
+	require.NoError(t, err)
+	require.Len(t, paths[fwd], 1)
+	require.Len(t, paths[fwd][0], 2)
+
+	prefs = RoutePreferences{TpTypes: []string{"dmsg"}}
+	_, err = c.FindRoutes(ctx, []routing.PathEdges{fwd}, &RouteOptions{MaxHops: 50, RoutePreferences: prefs})
+	require.Equal(t, ErrTransportNotFound, err)
+
+	// The longer path goes first once the shortest one is slow.
+	prefs = RoutePreferences{Scoring: ScoreLatency}
+	paths, err = c.FindRoutes(ctx, []routing.PathEdges{fwd}, &RouteOptions{MaxHops: 50, RoutePreferences: prefs})
+	require.NoError(t, err)
+	require.Len(t, paths[fwd][0], 2)
+
+	probeAB = &transport.ProbeStats{RTT: time.Second, Samples: 1}
+	paths, err = c.FindRoutes(ctx, []routing.PathEdges{fwd}, &RouteOptions{MaxHops: 50, RoutePreferences: prefs})
+	require.NoError(t, err)
+	require.Len(t, paths[fwd][0], 3)
+	require.Len(t, paths[fwd][1], 2)
+
+	// local transports are edges in both directions.
+	paths, err = c.FindRoutes(ctx, []routing.PathEdges{{pkA, pkE}, {pkE, pkA}}, &RouteOptions{MaxHops: 50})
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{{{TpID: tpAE.ID, From: pkA, To: pkE}}}, paths[routing.PathEdges{pkA, pkE}])
+	require.Equal(t, [][]routing.Hop{{{TpID: tpAE.ID, From: pkE, To: pkA}}}, paths[routing.PathEdges{pkE, pkA}])
+}
@@ -0,0 +1,160 @@
+package rfclient
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+// ScoringMode defines the order in which found routes are returned, the best routes come first.
+type ScoringMode string
+
+const (
+	// ScoreDefault leaves the order of routes to the route finder.
+	ScoreDefault ScoringMode = ""
+	// ScoreHops prefers routes of the least number of hops.
+	ScoreHops ScoringMode = "hops"
+	// ScoreLatency prefers routes of the lowest measured latency.
+	ScoreLatency ScoringMode = "latency"
+)
+
+// DefaultTransportWeight is the weight of transports which aren't measured.
+const DefaultTransportWeight = 100 * time.Millisecond
+
+// WeightFunc returns the weight of the transport of `tpID` as an edge of the visor graph,
+// it returns false if the transport isn't measured.
+type WeightFunc func(tpID uuid.UUID) (time.Duration, bool)
+
+// ParseScoringMode parses scoring mode from string.
+func ParseScoringMode(s string) (ScoringMode, error) {
+	switch mode := ScoringMode(s); mode {
+	case ScoreDefault, ScoreHops, ScoreLatency:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid scoring mode %q, valid values: %s, %s", s, ScoreHops, ScoreLatency)
+	}
+}
+
+// RoutePreferences narrows down the routes found by the route finder.
+// 'ExcludePKs' lists visors which may not be used as intermediaries.
+// 'TpTypes' lists types of transports routes may go via, any type is allowed if it's empty.
+// 'Scoring' defines the order of the found routes.
+type RoutePreferences struct {
+	ExcludePKs []cipher.PubKey `json:",omitempty"`
+	TpTypes    []string        `json:",omitempty"`
+	Scoring    ScoringMode     `json:",omitempty"`
+}
+
+// IsEmpty checks whether there are no preferences set.
+func (p *RoutePreferences) IsEmpty() bool {
+	return len(p.ExcludePKs) == 0 && len(p.TpTypes) == 0 && p.Scoring == ScoreDefault
+}
+
+// AllowsPath checks whether `path` satisfies the preferences.
+func (p *RoutePreferences) AllowsPath(path []routing.Hop) bool {
+	for i, hop := range path {
+		if i > 0 && p.excludes(hop.From) {
+			return false
+		}
+
+		if !p.allowsTransport(hop) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterPaths returns the paths of `paths` which satisfy the preferences, the order is preserved.
+func (p *RoutePreferences) FilterPaths(paths [][]routing.Hop) [][]routing.Hop {
+	if len(p.ExcludePKs) == 0 && len(p.TpTypes) == 0 {
+		return paths
+	}
+
+	res := make([][]routing.Hop, 0, len(paths))
+
+	for _, path := range paths {
+		if p.AllowsPath(path) {
+			res = append(res, path)
+		}
+	}
+
+	return res
+}
+
+// SortPaths orders `paths` according to the scoring mode, paths of the same score keep their order.
+// ScoreHops orders paths by the number of hops, ScoreLatency - by the sum of the weights of their transports,
+// transports which aren't measured weigh `DefaultTransportWeight`. The order is kept with ScoreDefault.
+func (p *RoutePreferences) SortPaths(paths [][]routing.Hop, weight WeightFunc) {
+	scores := make([]time.Duration, len(paths))
+
+	switch p.Scoring {
+	case ScoreHops:
+		for i, path := range paths {
+			scores[i] = time.Duration(len(path))
+		}
+	case ScoreLatency:
+		for i, path := range paths {
+			for _, hop := range path {
+				w, ok := time.Duration(0), false
+				if weight != nil {
+					w, ok = weight(hop.TpID)
+				}
+
+				if !ok {
+					w = DefaultTransportWeight
+				}
+
+				scores[i] += w
+			}
+		}
+	default:
+		return
+	}
+
+	sort.Stable(byScore{paths: paths, scores: scores})
+}
+
+type byScore struct {
+	paths  [][]routing.Hop
+	scores []time.Duration
+}
+
+func (s byScore) Len() int           { return len(s.paths) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] < s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.paths[i], s.paths[j] = s.paths[j], s.paths[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+func (p *RoutePreferences) excludes(pk cipher.PubKey) bool {
+	for _, excluded := range p.ExcludePKs {
+		if excluded == pk {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsTransport checks the type of the hop's transport. Transport ID is derived from the edges
+// and the type, so the type is checked without looking the transport up.
+func (p *RoutePreferences) allowsTransport(hop routing.Hop) bool {
+	if len(p.TpTypes) == 0 {
+		return true
+	}
+
+	for _, tpType := range p.TpTypes {
+		if transport.MakeTransportID(hop.From, hop.To, tpType) == hop.TpID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package rfclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+func TestRoutePreferences_FilterPaths(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+
+	hop := func(from, to cipher.PubKey, tpType string) routing.Hop {
+		return routing.Hop{TpID: transport.MakeTransportID(from, to, tpType), From: from, To: to}
+	}
+
+	direct := []routing.Hop{hop(pkA, pkC, "dmsg")}
+	viaB := []routing.Hop{hop(pkA, pkB, "stcpr"), hop(pkB, pkC, "stcpr")}
+	paths := [][]routing.Hop{direct, viaB}
+
+	tests := []struct {
+		name  string
+		prefs RoutePreferences
+		want  [][]routing.Hop
+	}{
+		{
+			name:  "no preferences",
+			prefs: RoutePreferences{},
+			want:  paths,
+		},
+		{
+			name:  "exclude intermediary",
+			prefs: RoutePreferences{ExcludePKs: []cipher.PubKey{pkB}},
+			want:  [][]routing.Hop{direct},
+		},
+		{
+			name:  "edges are never excluded",
+			prefs: RoutePreferences{ExcludePKs: []cipher.PubKey{pkA, pkC}},
+			want:  paths,
+		},
+		{
+			name:  "transport types",
+			prefs: RoutePreferences{TpTypes: []string{"stcpr", "sudph"}},
+			want:  [][]routing.Hop{viaB},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.prefs.FilterPaths(paths))
+		})
+	}
+}
+
+func TestRoutePreferences_SortPaths(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+
+	hop := func(from, to cipher.PubKey) routing.Hop {
+		return routing.Hop{TpID: transport.MakeTransportID(from, to, "stcpr"), From: from, To: to}
+	}
+
+	direct := []routing.Hop{hop(pkA, pkC)}
+	viaB := []routing.Hop{hop(pkA, pkB), hop(pkB, pkC)}
+
+	// the direct transport is slow, the ones via B aren't measured
+	weight := func(tpID uuid.UUID) (time.Duration, bool) {
+		if tpID == direct[0].TpID {
+			return time.Second, true
+		}
+
+		return 0, false
+	}
+
+	tests := []struct {
+		name    string
+		scoring ScoringMode
+		want    [][]routing.Hop
+	}{
+		{name: "default", scoring: ScoreDefault, want: [][]routing.Hop{viaB, direct}},
+		{name: "hops", scoring: ScoreHops, want: [][]routing.Hop{direct, viaB}},
+		{name: "latency", scoring: ScoreLatency, want: [][]routing.Hop{viaB, direct}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prefs := RoutePreferences{Scoring: tc.scoring}
+			paths := [][]routing.Hop{viaB, direct}
+
+			prefs.SortPaths(paths, weight)
+			require.Equal(t, tc.want, paths)
+		})
+	}
+}
+
+func TestParseScoringMode(t *testing.T) {
+	mode, err := ParseScoringMode("latency")
+	require.NoError(t, err)
+	require.Equal(t, ScoreLatency, mode)
+
+	_, err = ParseScoringMode("fastest")
+	require.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/skycoin/dmsg/noise"
+
+	"github.com/skycoin/skywire/pkg/snet/directtp/noisewrapper"
+)
+
+const (
+	// datagramNonceSize is the size of the nonce prefixing each of the encrypted datagrams.
+	datagramNonceSize = 8
+	// datagramReplayWindow is how far behind the highest nonce a datagram may still be accepted.
+	datagramReplayWindow = 1024
+)
+
+var (
+	// ErrDatagramReplayed is returned when datagram is either a duplicate or too old to be accepted.
+	ErrDatagramReplayed = errors.New("datagram replayed")
+)
+
+// datagramConn is a datagram route group wrapped with noise. Unlike a stream one, each datagram
+// is encrypted separately and carries its own nonce, so that lost or reordered datagrams
+// don't prevent the following ones from being decrypted.
+// Implements net.Conn.
+type datagramConn struct {
+	net.Conn
+
+	ns    *noise.Noise
+	encMx sync.Mutex
+
+	decMx   sync.Mutex
+	nonces  noise.NonceMap
+	highest uint64
+	readBuf []byte
+}
+
+// wrapDatagramConn performs noise handshake over `rg` and wraps it.
+func wrapDatagramConn(config noise.Config, rg *RouteGroup) (net.Conn, error) {
+	ns, err := noise.New(noise.HandshakeKK, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare datagram noise object: %w", err)
+	}
+
+	// handshake messages are framed just like the stream ones, each frame is a single datagram
+	if err := noise.NewReadWriter(rg, ns).Handshake(noisewrapper.HSTimeout); err != nil {
+		return nil, fmt.Errorf("error performing noise handshake: %w", err)
+	}
+
+	return &datagramConn{
+		Conn:    rg,
+		ns:      ns,
+		nonces:  make(noise.NonceMap),
+		readBuf: make([]byte, math.MaxUint16),
+	}, nil
+}
+
+// Read reads a single datagram. Datagrams which can't be decrypted are dropped.
+func (c *datagramConn) Read(p []byte) (int, error) {
+	c.decMx.Lock()
+	defer c.decMx.Unlock()
+
+	for {
+		n, err := c.Conn.Read(c.readBuf)
+		if err != nil {
+			return 0, err
+		}
+
+		plaintext, err := c.decrypt(c.readBuf[:n])
+		if err != nil {
+			continue
+		}
+
+		return copy(p, plaintext), nil
+	}
+}
+
+// Write writes `p` as a single datagram.
+func (c *datagramConn) Write(p []byte) (int, error) {
+	c.encMx.Lock()
+	defer c.encMx.Unlock()
+
+	if _, err := c.Conn.Write(c.ns.EncryptUnsafe(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// NOTE: should be called under the `c.decMx` lock.
+func (c *datagramConn) decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < datagramNonceSize {
+		return nil, noise.ErrInvalidCipherText
+	}
+
+	nonce := binary.BigEndian.Uint64(ciphertext)
+	if nonce+datagramReplayWindow <= c.highest {
+		return nil, ErrDatagramReplayed
+	}
+
+	plaintext, err := c.ns.DecryptWithNonceMap(c.nonces, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	c.nonces[nonce] = struct{}{}
+
+	if nonce > c.highest {
+		c.highest = nonce
+	}
+
+	// nonces which fell behind the window are rejected anyway, so there's no need to keep them
+	if len(c.nonces) > 2*datagramReplayWindow {
+		for n := range c.nonces {
+			if n+datagramReplayWindow <= c.highest {
+				delete(c.nonces, n)
+			}
+		}
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/dmsg/noise"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatagramConn_decrypt(t *testing.T) {
+	initNs, respNs := datagramNoisePair(t)
+
+	c := &datagramConn{
+		ns:     respNs,
+		nonces: make(noise.NonceMap),
+	}
+
+	first := initNs.EncryptUnsafe([]byte("first"))
+	second := initNs.EncryptUnsafe([]byte("second"))
+
+	// reordered datagrams are accepted
+	plaintext, err := c.decrypt(second)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), plaintext)
+
+	plaintext, err = c.decrypt(first)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), plaintext)
+
+	// duplicates are rejected
+	_, err = c.decrypt(first)
+	require.Error(t, err)
+
+	// tampered ones are rejected
+	tampered := initNs.EncryptUnsafe([]byte("third"))
+	tampered[len(tampered)-1] ^= 0xFF
+	_, err = c.decrypt(tampered)
+	require.Error(t, err)
+
+	// ones which fell behind the replay window are rejected
+	old := initNs.EncryptUnsafe([]byte("old"))
+
+	var last []byte
+	for i := 0; i < datagramReplayWindow; i++ {
+		last = initNs.EncryptUnsafe([]byte("new"))
+	}
+
+	_, err = c.decrypt(last)
+	require.NoError(t, err)
+
+	_, err = c.decrypt(old)
+	require.Equal(t, ErrDatagramReplayed, err)
+}
+
+func datagramNoisePair(t *testing.T) (initNs, respNs *noise.Noise) {
+	initPK, initSK := cipher.GenerateKeyPair()
+	respPK, respSK := cipher.GenerateKeyPair()
+
+	initNs, err := noise.New(noise.HandshakeKK, noise.Config{
+		LocalPK:   initPK,
+		LocalSK:   initSK,
+		RemotePK:  respPK,
+		Initiator: true,
+	})
+	require.NoError(t, err)
+
+	respNs, err = noise.New(noise.HandshakeKK, noise.Config{
+		LocalPK:  respPK,
+		LocalSK:  respSK,
+		RemotePK: initPK,
+	})
+	require.NoError(t, err)
+
+	msg, err := initNs.MakeHandshakeMessage()
+	require.NoError(t, err)
+	require.NoError(t, respNs.ProcessHandshakeMessage(msg))
+
+	msg, err = respNs.MakeHandshakeMessage()
+	require.NoError(t, err)
+	require.NoError(t, initNs.ProcessHandshakeMessage(msg))
+
+	require.True(t, initNs.HandshakeFinished())
+	require.True(t, respNs.HandshakeFinished())
+
+	return initNs, respNs
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"context"
+	"io"
+
+	"github.com/skycoin/dmsg/cipher"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+// dialRouteGroup sets up the routes of `req` via setup nodes. With `DirectSetup` routes are set up
+// by the router itself the same way the setup node does, local public key is returned as the setup node then.
+func (r *router) dialRouteGroup(ctx context.Context, req routing.BidirectionalRoute) (routing.EdgeRules, cipher.PubKey, error) {
+	if r.conf.DirectSetup == nil {
+		return r.conf.RouteGroupDialer.Dial(ctx, r.logger, r.n, r.conf.SetupNodes, req)
+	}
+
+	rules, err := r.conf.DirectSetup.DialDirect(ctx, r.logger, r.n, connServerFunc(r.serveRPC), req)
+
+	return rules, r.conf.PubKey, err
+}
+
+// connServerFunc serves connections via func, so each connection gets RPC session of its own.
+type connServerFunc func(conn io.ReadWriteCloser)
+
+// ServeConn implements setupclient.ConnServer.
+func (f connServerFunc) ServeConn(conn io.ReadWriteCloser) {
+	f(conn)
+}
+
+// directSetupPaths returns the paths of `paths` which may be set up directly, that is
+// all intermediaries are trusted. Paths are returned as is if direct setup is off.
+func (r *router) directSetupPaths(paths [][]routing.Hop) [][]routing.Hop {
+	if r.conf.DirectSetup == nil {
+		return paths
+	}
+
+	res := make([][]routing.Hop, 0, len(paths))
+
+	for _, path := range paths {
+		trusted := true
+
+		for i, hop := range path {
+			if i > 0 && !r.SetupIsTrusted(hop.From) {
+				trusted = false
+				break
+			}
+		}
+
+		if trusted {
+			res = append(res, path)
+		}
+	}
+
+	return res
+}
@@ -40,6 +40,26 @@ func (_m *MockRouter) AcceptRoutes(_a0 context.Context) (net.Conn, error) {
 	return r0, r1
 }
 
+// CheckRules provides a mock function with given fields: rules
+func (_m *MockRouter) CheckRules(rules ...routing.Rule) error {
+	_va := make([]interface{}, len(rules))
+	for _i := range rules {
+		_va[_i] = rules[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...routing.Rule) error); ok {
+		r0 = rf(rules...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Close provides a mock function with given fields:
 func (_m *MockRouter) Close() error {
 	ret := _m.Called()
@@ -96,6 +116,11 @@ func (_m *MockRouter) IntroduceRules(rules routing.EdgeRules) error {
 	return r0
 }
 
+// ReleaseKeys provides a mock function with given fields: ids
+func (_m *MockRouter) ReleaseKeys(ids []routing.RouteID) {
+	_m.Called(ids)
+}
+
 // ReserveKeys provides a mock function with given fields: n
 func (_m *MockRouter) ReserveKeys(n int) ([]routing.RouteID, error) {
 	ret := _m.Called(n)
@@ -119,6 +144,27 @@ func (_m *MockRouter) ReserveKeys(n int) ([]routing.RouteID, error) {
 	return r0, r1
 }
 
+// RouteGroupSetupNode provides a mock function with given fields: desc
+func (_m *MockRouter) RouteGroupSetupNode(desc routing.RouteDescriptor) (cipher.PubKey, bool) {
+	ret := _m.Called(desc)
+
+	var r0 cipher.PubKey
+	if rf, ok := ret.Get(0).(func(routing.RouteDescriptor) cipher.PubKey); ok {
+		r0 = rf(desc)
+	} else {
+		r0 = ret.Get(0).(cipher.PubKey)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(routing.RouteDescriptor) bool); ok {
+		r1 = rf(desc)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
 // RoutesCount provides a mock function with given fields:
 func (_m *MockRouter) RoutesCount() int {
 	ret := _m.Called()
@@ -156,6 +202,20 @@ func (_m *MockRouter) Rule(_a0 routing.RouteID) (routing.Rule, error) {
 	return r0, r1
 }
 
+// RuleStats provides a mock function with given fields: _a0
+func (_m *MockRouter) RuleStats(_a0 routing.RouteID) routing.RuleStats {
+	ret := _m.Called(_a0)
+
+	var r0 routing.RuleStats
+	if rf, ok := ret.Get(0).(func(routing.RouteID) routing.RuleStats); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(routing.RuleStats)
+	}
+
+	return r0
+}
+
 // Rules provides a mock function with given fields:
 func (_m *MockRouter) Rules() []routing.Rule {
 	ret := _m.Called()
@@ -220,6 +280,20 @@ func (_m *MockRouter) Serve(_a0 context.Context) error {
 	return r0
 }
 
+// IsSetupNode provides a mock function with given fields: _a0
+func (_m *MockRouter) IsSetupNode(_a0 cipher.PubKey) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(cipher.PubKey) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // SetupIsTrusted provides a mock function with given fields: _a0
 func (_m *MockRouter) SetupIsTrusted(_a0 cipher.PubKey) bool {
 	ret := _m.Called(_a0)
@@ -233,3 +307,26 @@ func (_m *MockRouter) SetupIsTrusted(_a0 cipher.PubKey) bool {
 
 	return r0
 }
+
+// TraceRoute provides a mock function with given fields: ctx, rid
+func (_m *MockRouter) TraceRoute(ctx context.Context, rid routing.RouteID) ([]routing.TraceHop, error) {
+	ret := _m.Called(ctx, rid)
+
+	var r0 []routing.TraceHop
+	if rf, ok := ret.Get(0).(func(context.Context, routing.RouteID) []routing.TraceHop); ok {
+		r0 = rf(ctx, rid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]routing.TraceHop)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, routing.RouteID) error); ok {
+		r1 = rf(ctx, rid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -44,6 +44,17 @@ func (nrg *NoiseRouteGroup) BandwidthSent() uint64 {
 	return nrg.rg.BandwidthSent()
 }
 
+// IsDatagram checks whether the route group keeps message boundaries.
+func (nrg *NoiseRouteGroup) IsDatagram() bool {
+	return nrg.rg.isDatagram()
+}
+
+// CloseWithCode closes route group sending the close `code` to remote. Unless `code` is
+// CloseRequested, further reads and writes fail with the corresponding '*routing.CloseError'.
+func (nrg *NoiseRouteGroup) CloseWithCode(code routing.CloseCode) error {
+	return nrg.rg.closeWithCode(code)
+}
+
 func (nrg *NoiseRouteGroup) isClosed() bool {
 	return nrg.rg.isClosed()
 }
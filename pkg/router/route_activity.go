@@ -0,0 +1,38 @@
+package router
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// routeActivity records when a route of a route group was last used to send and to receive packets.
+// Timestamps are unix nanoseconds, accessed atomically.
+type routeActivity struct {
+	sent int64
+	recv int64
+}
+
+func newRouteActivity() *routeActivity {
+	now := time.Now().UnixNano()
+
+	return &routeActivity{
+		sent: now,
+		recv: now,
+	}
+}
+
+func (a *routeActivity) markSent() {
+	atomic.StoreInt64(&a.sent, time.Now().UnixNano())
+}
+
+func (a *routeActivity) markRecv() {
+	atomic.StoreInt64(&a.recv, time.Now().UnixNano())
+}
+
+func (a *routeActivity) sinceSent() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&a.sent)))
+}
+
+func (a *routeActivity) sinceRecv() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&a.recv)))
+}
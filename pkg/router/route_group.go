@@ -12,9 +12,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
 	"github.com/skycoin/dmsg/ioutil"
 	"github.com/skycoin/skycoin/src/util/logging"
 
+	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/routing"
 	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/util/deadline"
@@ -38,6 +41,9 @@ var (
 	ErrRuleTransportMismatch = errors.New("rule/transport mismatch")
 	// ErrNoSuitableTransport is returned when no suitable transport was found.
 	ErrNoSuitableTransport = errors.New("no suitable transport")
+
+	// errDataDropped is returned when data is dropped as the reader's buffer is full.
+	errDataDropped = errors.New("data dropped, read buffer is full")
 )
 
 type timeoutError struct{}
@@ -48,11 +54,46 @@ func (timeoutError) Temporary() bool { return true }
 
 type sendServicePacketFn func(interval time.Duration)
 
+// WritePolicy defines how RouteGroup distributes writes among its forward routes.
+type WritePolicy byte
+
+const (
+	// WriteFailover writes via a single route, switching to the next one only when writing fails.
+	WriteFailover WritePolicy = iota
+	// WriteStripe spreads consecutive writes across all the routes in a round-robin manner.
+	// Packets may arrive out of order, so it only suits consumers that tolerate reordering.
+	WriteStripe
+)
+
+func (p WritePolicy) String() string {
+	switch p {
+	case WriteFailover:
+		return "failover"
+	case WriteStripe:
+		return "stripe"
+	default:
+		return fmt.Sprintf("Unknown(%d)", p)
+	}
+}
+
 // RouteGroupConfig configures RouteGroup.
 type RouteGroupConfig struct {
 	ReadChBufSize        int
 	KeepAliveInterval    time.Duration
 	NetworkProbeInterval time.Duration
+	WritePolicy          WritePolicy
+	// Reliable announces support of reliable delivery within the handshake.
+	// Data is sequenced, acknowledged and retransmitted if both edges announce it.
+	Reliable bool
+	// FlowControl announces support of credit-based flow control within the handshake.
+	// Credits are only honoured if both edges announce it and reliable delivery is negotiated,
+	// as credits of lost data are only given back once it's retransmitted.
+	FlowControl bool
+	// Datagram makes route group keep message boundaries, each Read returns a single message.
+	// It's announced within the handshake, so that the responder keeps them too.
+	Datagram bool
+	// Preferences are used to find replacements of the broken routes.
+	Preferences rfclient.RoutePreferences
 }
 
 // DefaultRouteGroupConfig returns default RouteGroup config.
@@ -62,6 +103,8 @@ func DefaultRouteGroupConfig() *RouteGroupConfig {
 		KeepAliveInterval:    defaultRouteGroupKeepAliveInterval,
 		NetworkProbeInterval: defaultNetworkProbeInterval,
 		ReadChBufSize:        defaultReadChBufSize,
+		FlowControl:          true,
+		WritePolicy:          WriteFailover,
 	}
 }
 
@@ -70,6 +113,8 @@ func DefaultRouteGroupConfig() *RouteGroupConfig {
 type RouteGroup struct {
 	// atomic requires 64-bit alignment for struct field access
 	lastSent int64
+	// index of the next route to be used by the striping write policy
+	stripeNext uint32
 
 	mu sync.Mutex
 
@@ -85,7 +130,8 @@ type RouteGroup struct {
 	// 'tps' is transports used for writing/forward rules.
 	// It should have the same number of elements as 'fwd'
 	// where each element corresponds with the adjacent element in 'fwd'.
-	tps []*transport.ManagedTransport
+	// Routes via bonds have their packets balanced over the bond members by the bond.
+	tps []transport.PacketWriter
 
 	// The following fields are used for writing:
 	// - fwd/tps should have the same number of elements.
@@ -94,6 +140,37 @@ type RouteGroup struct {
 	fwd []routing.Rule // forward rules (for writing)
 	rvs []routing.Rule // reverse rules (for reading)
 
+	// 'act' records when each of the routes was last used, it's aligned with 'fwd'/'rvs'.
+	act []*routeActivity
+
+	// 'remoteRvs' are key route IDs of the reverse rules of the routes on the remote edge,
+	// it's aligned with 'fwd'/'rvs'. Remote routes are referred to by them.
+	remoteRvs []routing.RouteID
+
+	// 'paths' are the hops of the routes keyed by the key route ID of their reverse rule.
+	// They're only known to the edge which dialed the route group, route repair avoids them.
+	paths map[routing.RouteID]routePath
+
+	// 'primary' is the index of the route currently preferred for writing.
+	// It's changed by the failover write policy once a write via it fails.
+	primary int
+
+	// 'remoteFlags' are the route group features announced by remote within the handshake.
+	remoteFlags routing.HandshakeFlags
+
+	// 'setupPK' is the setup node which set up the route group, it's only set for the dialed ones.
+	setupPK    cipher.PubKey
+	hasSetupPK bool
+
+	// 'rel' keeps track of sequenced data, it's nil unless reliable delivery is negotiated.
+	rel *reliableState
+
+	// 'flow' keeps track of credits, it's nil unless flow control and reliable delivery are negotiated.
+	flow *flowState
+
+	// 'datagram' is set if the route group keeps message boundaries.
+	datagram bool
+
 	// 'readCh' reads in incoming packets of this route group.
 	// - Router should serve call '(*transport.Manager).ReadPacket' in a loop,
 	//      and push to the appropriate '(RouteGroup).readCh'.
@@ -105,14 +182,24 @@ type RouteGroup struct {
 
 	networkStats *networkStats
 
+	// 'traces' are the TracePackets sent by this route group which are still awaited to come back.
+	traces    map[uint32]chan []routing.TraceHop
+	tracesMx  sync.Mutex
+	traceNext uint32
+
 	// used as a bool to indicate if this particular route group initiated close loop
-	closeInitiated   int32
+	closeInitiated int32
+	// number of close packets still expected to come back, one per route
+	closeAwaiting    int32
 	remoteClosedOnce sync.Once
 	remoteClosed     chan struct{}
 	closed           chan struct{}
 	// used to wait for all the `Close` packets to run through the loop and come back
 	closeDone sync.WaitGroup
 	once      sync.Once
+	// 'closeReason' is set to '*routing.CloseError' once the route group is closed with the code
+	// other than 'CloseRequested', either by remote, an intermediary or the local router.
+	closeReason atomic.Value
 }
 
 // NewRouteGroup creates a new RouteGroup.
@@ -126,7 +213,7 @@ func NewRouteGroup(cfg *RouteGroupConfig, rt routing.Table, desc routing.RouteDe
 		logger:             logging.MustGetLogger(fmt.Sprintf("RouteGroup %s", desc.String())),
 		desc:               desc,
 		rt:                 rt,
-		tps:                make([]*transport.ManagedTransport, 0),
+		tps:                make([]transport.PacketWriter, 0),
 		fwd:                make([]routing.Rule, 0),
 		rvs:                make([]routing.Rule, 0),
 		readCh:             make(chan []byte, cfg.ReadChBufSize),
@@ -137,6 +224,9 @@ func NewRouteGroup(cfg *RouteGroupConfig, rt routing.Table, desc routing.RouteDe
 		writeDeadline:      deadline.MakePipeDeadline(),
 		handshakeProcessed: make(chan struct{}),
 		networkStats:       newNetworkStats(),
+		traces:             make(map[uint32]chan []routing.TraceHop),
+		datagram:           cfg.Datagram,
+		paths:              make(map[routing.RouteID]routePath),
 	}
 
 	return rg
@@ -147,7 +237,7 @@ func NewRouteGroup(cfg *RouteGroupConfig, rt routing.Table, desc routing.RouteDe
 // to the appropriate RouteGroup via (*RouteGroup).readCh.
 func (rg *RouteGroup) Read(p []byte) (n int, err error) {
 	if rg.isClosed() {
-		return 0, io.ErrClosedPipe
+		return 0, rg.closeErrOr(io.ErrClosedPipe)
 	}
 
 	if rg.readDeadline.Closed() {
@@ -161,11 +251,18 @@ func (rg *RouteGroup) Read(p []byte) (n int, err error) {
 	return rg.read(p)
 }
 
-// Write writes payload to a RouteGroup
-// For the first version, only the first ForwardRule (fwd[0]) is used for writing.
+// Write writes payload to a RouteGroup.
+// Route used for writing is picked according to the configured WritePolicy.
+// If writing via the picked route fails, the remaining routes are tried in turn.
 func (rg *RouteGroup) Write(p []byte) (n int, err error) {
 	if rg.isClosed() {
-		return 0, io.ErrClosedPipe
+		return 0, rg.closeErrOr(io.ErrClosedPipe)
+	}
+
+	if rg.isRemoteClosed() {
+		if err := rg.closeErrOr(nil); err != nil {
+			return 0, err
+		}
 	}
 
 	if rg.writeDeadline.Closed() {
@@ -176,26 +273,58 @@ func (rg *RouteGroup) Write(p []byte) (n int, err error) {
 		return 0, nil
 	}
 
+	if rel := rg.reliability(); rel != nil {
+		return rg.writeReliable(rel, p)
+	}
+
+	return rg.writeData(p, rg.writeDeadline.Wait())
+}
+
+// writeData writes `p` as a single DataPacket via one of the routes.
+// Writing times out once `deadline` is closed, nil `deadline` never times out.
+func (rg *RouteGroup) writeData(p []byte, deadline <-chan struct{}) (n int, err error) {
 	rg.mu.Lock()
-	tp, err := rg.tp()
+	routes, err := rg.writeRoutes()
+	// we don't need to keep holding mutex from this point on
+	rg.mu.Unlock()
+
 	if err != nil {
-		rg.mu.Unlock()
 		return 0, err
 	}
 
-	rule, err := rg.rule()
-	if err != nil {
-		rg.mu.Unlock()
-		return 0, err
+	for i, route := range routes {
+		n, err = rg.write(p, route.tp, route.rule, deadline)
+		if err == nil {
+			if route.act != nil {
+				route.act.markSent()
+			}
+
+			if rg.cfg.WritePolicy == WriteFailover {
+				rg.setPrimary(route.idx)
+			}
+
+			return n, nil
+		}
+
+		if _, ok := err.(timeoutError); ok {
+			return 0, err
+		}
+
+		rg.logger.WithError(err).Warnf("Failed to write via transport %s [%d/%d]",
+			route.tp.TransportID(), i+1, len(routes))
 	}
-	// we don't need to keep holding mutex from this point on
-	rg.mu.Unlock()
 
-	return rg.write(p, tp, rule)
+	return 0, err
 }
 
 // Close closes a RouteGroup.
 func (rg *RouteGroup) Close() error {
+	return rg.closeWithCode(routing.CloseRequested)
+}
+
+// closeWithCode closes a RouteGroup sending `code` to remote. Unless `code` is CloseRequested,
+// further reads and writes fail with the corresponding '*routing.CloseError'.
+func (rg *RouteGroup) closeWithCode(code routing.CloseCode) error {
 	if rg.isClosed() {
 		return io.ErrClosedPipe
 	}
@@ -209,10 +338,12 @@ func (rg *RouteGroup) Close() error {
 
 	atomic.StoreInt32(&rg.closeInitiated, 1)
 
+	rg.setCloseReason(code)
+
 	rg.mu.Lock()
 	defer rg.mu.Unlock()
 
-	return rg.close(routing.CloseRequested)
+	return rg.close(code)
 }
 
 // LocalAddr returns destination address of underlying RouteDescriptor.
@@ -283,23 +414,37 @@ func (rg *RouteGroup) read(p []byte) (int, error) {
 	case <-rg.readDeadline.Wait():
 		return 0, timeoutError{}
 	case <-rg.closed:
-		return 0, io.ErrClosedPipe
+		return 0, rg.closeErrOr(io.ErrClosedPipe)
 	case data, ok := <-rg.readCh:
-		if !ok || len(data) == 0 {
+		if !ok {
+			return 0, rg.closeErrOr(io.EOF)
+		}
+
+		if len(data) == 0 {
 			// route group got closed or empty data received. Behavior on the empty
 			// data is equivalent to the behavior of `read()` unix syscall as described here:
 			// https://www.ibm.com/support/knowledgecenter/en/SSLTBW_2.4.0/com.ibm.zos.v2r4.bpxbd00/rtrea.htm
 			return 0, io.EOF
 		}
 
+		if flow := rg.flowControl(); flow != nil {
+			flow.consume()
+		}
+
 		rg.mu.Lock()
 		defer rg.mu.Unlock()
 
+		if rg.datagram {
+			// the rest of the message which doesn't fit into `p` is discarded, just like with UDP
+			return copy(p, data), nil
+		}
+
 		return ioutil.BufRead(&rg.readBuf, data, p)
 	}
 }
 
-func (rg *RouteGroup) write(data []byte, tp *transport.ManagedTransport, rule routing.Rule) (int, error) {
+func (rg *RouteGroup) write(data []byte, tp transport.PacketWriter, rule routing.Rule,
+	deadline <-chan struct{}) (int, error) {
 	packet, err := routing.MakeDataPacket(rule.NextRouteID(), data)
 	if err != nil {
 		return 0, err
@@ -314,7 +459,7 @@ func (rg *RouteGroup) write(data []byte, tp *transport.ManagedTransport, rule ro
 	defer cancel()
 
 	select {
-	case <-rg.writeDeadline.Wait():
+	case <-deadline:
 		return 0, timeoutError{}
 	case err := <-errCh:
 		if err != nil {
@@ -327,7 +472,7 @@ func (rg *RouteGroup) write(data []byte, tp *transport.ManagedTransport, rule ro
 	}
 }
 
-func (rg *RouteGroup) writePacketAsync(ctx context.Context, tp *transport.ManagedTransport, packet routing.Packet,
+func (rg *RouteGroup) writePacketAsync(ctx context.Context, tp transport.PacketWriter, packet routing.Packet,
 	ruleID routing.RouteID) chan error {
 	errCh := make(chan error)
 	go func() {
@@ -344,7 +489,7 @@ func (rg *RouteGroup) writePacketAsync(ctx context.Context, tp *transport.Manage
 	return errCh
 }
 
-func (rg *RouteGroup) writePacket(ctx context.Context, tp *transport.ManagedTransport, packet routing.Packet,
+func (rg *RouteGroup) writePacket(ctx context.Context, tp transport.PacketWriter, packet routing.Packet,
 	ruleID routing.RouteID) error {
 	err := tp.WritePacket(ctx, packet)
 	// note equality here. update activity only if there was NO error
@@ -361,53 +506,105 @@ func (rg *RouteGroup) writePacket(ctx context.Context, tp *transport.ManagedTran
 	return err
 }
 
-// rule fetches first available forward rule.
+// writeRoute is a forward rule along with the transport it refers to.
+type writeRoute struct {
+	idx  int
+	tp   transport.PacketWriter
+	rule routing.Rule
+	act  *routeActivity
+}
+
+// writeRoutes returns routes available for writing, ordered by preference
+// of the configured WritePolicy. Routes which transports are up and which
+// keep receiving packets from remote go first.
 // NOTE: not thread-safe.
-func (rg *RouteGroup) rule() (routing.Rule, error) {
+func (rg *RouteGroup) writeRoutes() ([]writeRoute, error) {
+	if len(rg.tps) == 0 {
+		return nil, ErrNoTransports
+	}
+
 	if len(rg.fwd) == 0 {
 		return nil, ErrNoRules
 	}
 
-	rule := rg.fwd[0]
+	if len(rg.fwd) != len(rg.tps) {
+		return nil, ErrRuleTransportMismatch
+	}
+
+	first := rg.primary
+	if rg.cfg.WritePolicy == WriteStripe {
+		first = int(atomic.AddUint32(&rg.stripeNext, 1)-1) % len(rg.tps)
+	}
+
+	up := make([]writeRoute, 0, len(rg.tps))
+	down := make([]writeRoute, 0, len(rg.tps))
+
+	for i := 0; i < len(rg.tps); i++ {
+		idx := (first + i) % len(rg.tps)
+
+		tp := rg.tps[idx]
+		if tp == nil {
+			continue
+		}
+
+		route := writeRoute{idx: idx, tp: tp, rule: rg.fwd[idx], act: rg.activity(idx)}
+		if tp.IsUp() && (route.act == nil || route.act.sinceRecv() < routeStaleTimeout) {
+			up = append(up, route)
+		} else {
+			down = append(down, route)
+		}
+	}
+
+	routes := append(up, down...)
+	if len(routes) == 0 {
+		return nil, ErrBadTransport
+	}
 
-	return rule, nil
+	return routes, nil
 }
 
-// tp fetches first available transport.
+// primaryRoute fetches the route currently preferred for writing.
 // NOTE: not thread-safe.
-func (rg *RouteGroup) tp() (*transport.ManagedTransport, error) {
-	if len(rg.tps) == 0 {
-		return nil, ErrNoTransports
+func (rg *RouteGroup) primaryRoute() (transport.PacketWriter, routing.Rule, bool) {
+	if len(rg.tps) == 0 || len(rg.fwd) == 0 || rg.primary >= len(rg.tps) || rg.primary >= len(rg.fwd) {
+		return nil, nil, false
 	}
 
-	tp := rg.tps[0]
+	return rg.tps[rg.primary], rg.fwd[rg.primary], true
+}
 
-	if tp == nil {
-		return nil, ErrBadTransport
+func (rg *RouteGroup) setPrimary(idx int) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if idx < len(rg.tps) && rg.primary != idx {
+		rg.logger.Infof("Switching primary route to the one via transport %s", rg.tps[idx].TransportID())
+		rg.primary = idx
 	}
+}
 
-	return tp, nil
+// routesCount returns the number of forward routes in the route group.
+func (rg *RouteGroup) routesCount() int {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return len(rg.fwd)
 }
 
 func (rg *RouteGroup) startOffServiceLoops() {
 	go rg.servicePacketLoop("keep-alive", rg.cfg.KeepAliveInterval, rg.keepAliveServiceFn)
 	go rg.servicePacketLoop("network probe", rg.cfg.NetworkProbeInterval, rg.networkProbeServiceFn)
+	rg.startReliableLoops()
+	rg.startCreditLoop()
 }
 
 func (rg *RouteGroup) sendNetworkProbe() error {
 	rg.mu.Lock()
-
-	if len(rg.tps) == 0 || len(rg.fwd) == 0 {
-		rg.mu.Unlock()
-		// if no transports, no rules, then no latency probe
-		return nil
-	}
-
-	tp := rg.tps[0]
-	rule := rg.fwd[0]
+	tp, rule, ok := rg.primaryRoute()
 	rg.mu.Unlock()
 
-	if tp == nil {
+	if !ok || tp == nil {
+		// if no transports, no rules, then no latency probe
 		return nil
 	}
 
@@ -423,6 +620,51 @@ func (rg *RouteGroup) sendNetworkProbe() error {
 	return nil
 }
 
+// trace sends a TracePacket via the primary route and awaits for it to come back
+// via the reverse route. The result includes the local visor as the first and the last hop.
+func (rg *RouteGroup) trace(ctx context.Context) ([]routing.TraceHop, error) {
+	rg.mu.Lock()
+	tp, rule, ok := rg.primaryRoute()
+	rg.mu.Unlock()
+
+	if !ok || tp == nil {
+		return nil, ErrNoTransports
+	}
+
+	traceID := atomic.AddUint32(&rg.traceNext, 1)
+	resultCh := make(chan []routing.TraceHop, 1)
+
+	rg.tracesMx.Lock()
+	rg.traces[traceID] = resultCh
+	rg.tracesMx.Unlock()
+
+	defer func() {
+		rg.tracesMx.Lock()
+		delete(rg.traces, traceID)
+		rg.tracesMx.Unlock()
+	}()
+
+	hops := []routing.TraceHop{{PK: rg.desc.DstPK(), Time: time.Now()}}
+
+	packet, err := routing.MakeTracePacket(rule.NextRouteID(), traceID, false, hops)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rg.writePacket(ctx, tp, packet, rule.KeyRouteID()); err != nil {
+		return nil, err
+	}
+
+	select {
+	case hops := <-resultCh:
+		return hops, nil
+	case <-rg.closed:
+		return nil, io.ErrClosedPipe
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func (rg *RouteGroup) networkProbeServiceFn(_ time.Duration) {
 	if err := rg.sendNetworkProbe(); err != nil {
 		rg.logger.Warnf("Failed to send network probe: %v", err)
@@ -444,19 +686,15 @@ func (rg *RouteGroup) servicePacketLoop(name string, interval time.Duration, f s
 	}
 }
 
+// keepAliveServiceFn sends keep-alive packets via the routes which were idle for the half of the `interval`.
+// Each route is kept alive on its own, so the idle routes don't expire while the primary one is busy.
 func (rg *RouteGroup) keepAliveServiceFn(interval time.Duration) {
-	lastSent := time.Unix(0, atomic.LoadInt64(&rg.lastSent))
-
-	if time.Since(lastSent) < interval {
-		return
-	}
-
-	if err := rg.sendKeepAlive(); err != nil {
+	if err := rg.sendKeepAlive(interval / 2); err != nil {
 		rg.logger.Warnf("Failed to send keepalive: %v", err)
 	}
 }
 
-func (rg *RouteGroup) sendKeepAlive() error {
+func (rg *RouteGroup) sendKeepAlive(idle time.Duration) error {
 	rg.mu.Lock()
 	defer rg.mu.Unlock()
 
@@ -465,7 +703,11 @@ func (rg *RouteGroup) sendKeepAlive() error {
 		return nil
 	}
 
-	for i := 0; i < len(rg.tps); i++ {
+	lastSent := time.Unix(0, atomic.LoadInt64(&rg.lastSent))
+
+	var err error
+
+	for i := 0; i < len(rg.tps) && i < len(rg.fwd); i++ {
 		tp := rg.tps[i]
 		rule := rg.fwd[i]
 
@@ -473,14 +715,24 @@ func (rg *RouteGroup) sendKeepAlive() error {
 			continue
 		}
 
+		act := rg.activity(i)
+		if act != nil && act.sinceSent() < idle || act == nil && time.Since(lastSent) < idle {
+			continue
+		}
+
 		packet := routing.MakeKeepAlivePacket(rule.NextRouteID())
 
-		if err := rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID()); err != nil {
-			return err
+		if wErr := rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID()); wErr != nil {
+			err = wErr
+			continue
+		}
+
+		if act != nil {
+			act.markSent()
 		}
 	}
 
-	return nil
+	return err
 }
 
 func (rg *RouteGroup) sendHandshake(encrypt bool) error {
@@ -500,16 +752,17 @@ func (rg *RouteGroup) sendHandshake(encrypt bool) error {
 		}
 
 		rule := rg.fwd[i]
-		packet := routing.MakeHandshakePacket(rule.NextRouteID(), encrypt)
+		packet := routing.MakeHandshakePacket(rule.NextRouteID(), encrypt, rg.handshakeFlags(),
+			uint32(rg.cfg.ReadChBufSize))
 
 		err := rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID())
 		if err == nil {
-			rg.logger.Infof("Sent handshake via transport %v", tp.Entry.ID)
+			rg.logger.Infof("Sent handshake via transport %v", tp.TransportID())
 			return nil
 		}
 
 		rg.logger.Infof("Failed to send handshake via transport %v: %v [%v/%v]",
-			tp.Entry.ID, err, i+1, len(rg.tps))
+			tp.TransportID(), err, i+1, len(rg.tps))
 	}
 
 	return ErrNoSuitableTransport
@@ -533,6 +786,7 @@ func (rg *RouteGroup) close(code routing.CloseCode) error {
 	if closeInitiator {
 		// will wait for close response from all the transports
 		rg.closeDone.Add(len(rg.tps))
+		atomic.StoreInt32(&rg.closeAwaiting, int32(len(rg.tps)))
 	}
 
 	rg.broadcastClosePackets(code)
@@ -565,6 +819,8 @@ func (rg *RouteGroup) close(code routing.CloseCode) error {
 }
 
 func (rg *RouteGroup) handlePacket(packet routing.Packet) error {
+	rg.markRecv(packet.RouteID())
+
 	switch packet.Type() {
 	case routing.ClosePacket:
 		rg.mu.Lock()
@@ -580,6 +836,12 @@ func (rg *RouteGroup) handlePacket(packet routing.Packet) error {
 		return rg.handleDataPacket(packet)
 	case routing.NetworkProbePacket:
 		return rg.handleNetworkProbePacket(packet)
+	case routing.TracePacket:
+		return rg.handleTracePacket(packet)
+	case routing.CreditPacket:
+		if flow := rg.flowControl(); flow != nil {
+			flow.grant(packet.Credit())
+		}
 	case routing.HandshakePacket:
 		rg.handshakeProcessedOnce.Do(func() {
 			// first packet is handshake packet, so we're communicating with the new visor
@@ -588,6 +850,20 @@ func (rg *RouteGroup) handlePacket(packet routing.Packet) error {
 				rg.encrypt = false
 			}
 
+			rg.mu.Lock()
+			rg.remoteFlags = packet.HandshakeFlags()
+			if rg.remoteFlags.Has(routing.HandshakeDatagram) {
+				rg.datagram = true
+			}
+			// datagrams are never retransmitted nor flow controlled
+			if !rg.datagram && rg.cfg.Reliable && rg.remoteFlags.Has(routing.HandshakeReliable) {
+				rg.rel = newReliableState()
+			}
+			if rg.rel != nil && rg.cfg.FlowControl && rg.remoteFlags.Has(routing.HandshakeFlowControl) {
+				rg.flow = newFlowState(packet.HandshakeWindow(), uint32(rg.cfg.ReadChBufSize))
+			}
+			rg.mu.Unlock()
+
 			close(rg.handshakeProcessed)
 		})
 	}
@@ -610,9 +886,88 @@ func (rg *RouteGroup) handleNetworkProbePacket(packet routing.Packet) error {
 	return nil
 }
 
+// handleTracePacket sends the TracePacket back via the route paired with the one it came from.
+// Once it comes back, the awaiting trace call gets the hops.
+func (rg *RouteGroup) handleTracePacket(packet routing.Packet) error {
+	traceID, returning, hops, err := packet.Trace()
+	if err != nil {
+		return err
+	}
+
+	hops = append(hops, routing.TraceHop{PK: rg.desc.DstPK(), Time: time.Now()})
+
+	if returning {
+		rg.tracesMx.Lock()
+		resultCh, ok := rg.traces[traceID]
+		rg.tracesMx.Unlock()
+
+		if !ok {
+			rg.logger.Debugf("Dropping trace packet %d which is no longer awaited", traceID)
+			return nil
+		}
+
+		select {
+		case resultCh <- hops:
+		default:
+		}
+
+		return nil
+	}
+
+	rg.mu.Lock()
+	tp, rule, ok := rg.primaryRoute()
+	idx := rg.reverseIdx(packet.RouteID())
+	if idx != -1 && idx < len(rg.fwd) && idx < len(rg.tps) && rg.tps[idx] != nil {
+		tp, rule, ok = rg.tps[idx], rg.fwd[idx], true
+	}
+	rg.mu.Unlock()
+
+	if !ok || tp == nil {
+		return ErrNoTransports
+	}
+
+	reply, err := routing.MakeTracePacket(rule.NextRouteID(), traceID, true, hops)
+	if err != nil {
+		return err
+	}
+
+	return rg.writePacket(context.Background(), tp, reply, rule.KeyRouteID())
+}
+
 func (rg *RouteGroup) handleDataPacket(packet routing.Packet) error {
 	rg.networkStats.AddBandwidthReceived(uint64(packet.Size()))
 
+	if rel := rg.reliability(); rel != nil {
+		return rg.handleReliableData(rel, packet.Payload())
+	}
+
+	if err := rg.pushRead(packet.Payload()); err != nil && !errors.Is(err, errDataDropped) {
+		return err
+	}
+
+	return nil
+}
+
+// pushRead passes `data` to the reader of the route group.
+// With flow control, remote never exceeds the buffer of the reader. So if it does,
+// data is dropped rather than blocking the transport shared with other route groups,
+// errDataDropped is returned then.
+func (rg *RouteGroup) pushRead(data []byte) error {
+	if rg.flowControl() != nil {
+		select {
+		case <-rg.closed:
+			return io.ErrClosedPipe
+		case <-rg.remoteClosed:
+			return nil
+		case rg.readCh <- data:
+		default:
+			rg.logger.Warn("Dropping data which exceeds credits granted to remote")
+			return errDataDropped
+		}
+
+		return nil
+	}
+
 	select {
 	case <-rg.closed:
 		return io.ErrClosedPipe
@@ -621,7 +976,7 @@ func (rg *RouteGroup) handleDataPacket(packet routing.Packet) error {
 		// but some packets may still reach the rg causing panic on writing
 		// to `readCh`, so we simple omit such packets
 		return nil
-	case rg.readCh <- packet.Payload():
+	case rg.readCh <- data:
 	}
 
 	return nil
@@ -634,13 +989,38 @@ func (rg *RouteGroup) handleClosePacket(code routing.CloseCode) error {
 		// this route group initiated close loop and got response
 		rg.logger.Debugf("Handling response close packet with code %d", code)
 
-		rg.closeDone.Done()
+		// with multiple routes, each of them brings its own response back
+		if atomic.AddInt32(&rg.closeAwaiting, -1) >= 0 {
+			rg.closeDone.Done()
+		}
+
 		return nil
 	}
 
+	rg.setCloseReason(code)
+
 	return rg.close(code)
 }
 
+// setCloseReason makes further reads and writes fail with the error of `code`.
+func (rg *RouteGroup) setCloseReason(code routing.CloseCode) {
+	if code == routing.CloseRequested {
+		return
+	}
+
+	rg.closeReason.Store(&routing.CloseError{Code: code})
+}
+
+// closeErrOr returns the error describing why the route group got closed,
+// or `err` if it was closed on request.
+func (rg *RouteGroup) closeErrOr(err error) error {
+	if closeErr, ok := rg.closeReason.Load().(*routing.CloseError); ok {
+		return closeErr
+	}
+
+	return err
+}
+
 func (rg *RouteGroup) broadcastClosePackets(code routing.CloseCode) {
 	for i := 0; i < len(rg.tps); i++ {
 		if rg.tps[i] == nil || rg.fwd[i] == nil {
@@ -674,6 +1054,12 @@ func (rg *RouteGroup) waitForCloseRouteGroup(waitTimeout time.Duration) error {
 	return nil
 }
 
+// awaitsCloseResponses checks whether the route group initiated close loop
+// and still waits for the close packets to come back via some of its routes.
+func (rg *RouteGroup) awaitsCloseResponses() bool {
+	return rg.isCloseInitiator() && atomic.LoadInt32(&rg.closeAwaiting) > 0
+}
+
 func (rg *RouteGroup) isCloseInitiator() bool {
 	return atomic.LoadInt32(&rg.closeInitiated) == 1
 }
@@ -684,6 +1070,14 @@ func (rg *RouteGroup) setRemoteClosed() {
 	})
 }
 
+// isDatagram checks whether the route group keeps message boundaries.
+func (rg *RouteGroup) isDatagram() bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.datagram
+}
+
 func (rg *RouteGroup) isRemoteClosed() bool {
 	return chanClosed(rg.remoteClosed)
 }
@@ -692,14 +1086,216 @@ func (rg *RouteGroup) isClosed() bool {
 	return chanClosed(rg.closed)
 }
 
-func (rg *RouteGroup) appendRules(forward, reverse routing.Rule, tp *transport.ManagedTransport) {
+func (rg *RouteGroup) setSetupNode(pk cipher.PubKey) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.setupPK = pk
+	rg.hasSetupPK = true
+}
+
+func (rg *RouteGroup) setupNode() (cipher.PubKey, bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.setupPK, rg.hasSetupPK
+}
+
+func (rg *RouteGroup) appendRules(rules routing.EdgeRules, tp transport.PacketWriter) {
 	rg.mu.Lock()
 	defer rg.mu.Unlock()
 
-	rg.fwd = append(rg.fwd, forward)
-	rg.rvs = append(rg.rvs, reverse)
+	rg.fwd = append(rg.fwd, rules.Forward)
+	rg.rvs = append(rg.rvs, rules.Reverse)
 
 	rg.tps = append(rg.tps, tp)
+	rg.act = append(rg.act, newRouteActivity())
+	rg.remoteRvs = append(rg.remoteRvs, rules.RemoteReverseID)
+}
+
+// setRoutePath records the hops of the route which reverse rule has the `reverseID` key route ID.
+func (rg *RouteGroup) setRoutePath(reverseID routing.RouteID, route routing.BidirectionalRoute) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if rg.paths == nil {
+		rg.paths = make(map[routing.RouteID]routePath)
+	}
+
+	rg.paths[reverseID] = routePath{forward: route.Forward, reverse: route.Reverse}
+}
+
+// replaceRoute replaces the route which reverse rule has the `reverseID` key route ID with the route of `rules`.
+// The replaced rules are returned.
+func (rg *RouteGroup) replaceRoute(reverseID routing.RouteID, rules routing.EdgeRules,
+	tp transport.PacketWriter) (oldForward, oldReverse routing.Rule, ok bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	idx := rg.reverseIdx(reverseID)
+	if idx < 0 || idx >= len(rg.fwd) || idx >= len(rg.tps) {
+		return nil, nil, false
+	}
+
+	oldForward, oldReverse = rg.fwd[idx], rg.rvs[idx]
+	delete(rg.paths, reverseID)
+
+	rg.fwd[idx] = rules.Forward
+	rg.rvs[idx] = rules.Reverse
+	rg.tps[idx] = tp
+
+	if idx < len(rg.act) {
+		rg.act[idx] = newRouteActivity()
+	}
+
+	if idx < len(rg.remoteRvs) {
+		rg.remoteRvs[idx] = rules.RemoteReverseID
+	}
+
+	return oldForward, oldReverse, true
+}
+
+// removeRoute removes the route which reverse rule has the `reverseID` key route ID.
+// The forward rule of the removed route is returned.
+func (rg *RouteGroup) removeRoute(reverseID routing.RouteID) (forward routing.Rule, ok bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	idx := rg.reverseIdx(reverseID)
+	if idx < 0 || idx >= len(rg.fwd) || idx >= len(rg.tps) {
+		return nil, false
+	}
+
+	forward = rg.fwd[idx]
+	delete(rg.paths, reverseID)
+
+	rg.fwd = append(rg.fwd[:idx], rg.fwd[idx+1:]...)
+	rg.rvs = append(rg.rvs[:idx], rg.rvs[idx+1:]...)
+	rg.tps = append(rg.tps[:idx], rg.tps[idx+1:]...)
+
+	if idx < len(rg.act) {
+		rg.act = append(rg.act[:idx], rg.act[idx+1:]...)
+	}
+
+	if idx < len(rg.remoteRvs) {
+		rg.remoteRvs = append(rg.remoteRvs[:idx], rg.remoteRvs[idx+1:]...)
+	}
+
+	switch {
+	case rg.primary > idx:
+		rg.primary--
+	case rg.primary >= len(rg.fwd):
+		rg.primary = 0
+	}
+
+	return forward, true
+}
+
+// transportsGone checks whether transports of all the routes are gone.
+func (rg *RouteGroup) transportsGone(lookupTp func(uuid.UUID) transport.PacketWriter) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if len(rg.fwd) == 0 {
+		return false
+	}
+
+	for _, rule := range rg.fwd {
+		if lookupTp(rule.NextTransportID()) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// brokenRoutes returns indexes of the routes which transports are gone or
+// which didn't bring any packets from remote during the `staleTimeout`.
+// Transports which were re-created under the same ID are updated along the way.
+func (rg *RouteGroup) brokenRoutes(lookupTp func(uuid.UUID) transport.PacketWriter,
+	staleTimeout time.Duration) []int {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	var broken []int
+
+	for i := 0; i < len(rg.fwd) && i < len(rg.tps); i++ {
+		tp := lookupTp(rg.fwd[i].NextTransportID())
+		if tp == nil {
+			broken = append(broken, i)
+			continue
+		}
+
+		if tp != rg.tps[i] {
+			rg.logger.Infof("Transport %s got re-created, updating route", tp.TransportID())
+			rg.tps[i] = tp
+		}
+
+		if act := rg.activity(i); act != nil && act.sinceRecv() > staleTimeout {
+			broken = append(broken, i)
+		}
+	}
+
+	return broken
+}
+
+// handshakeFlags returns the route group features announced within the handshake.
+// NOTE: should be called under the `rg.mu` lock.
+func (rg *RouteGroup) handshakeFlags() routing.HandshakeFlags {
+	flags := handshakeFlags
+	if rg.cfg.Reliable {
+		flags |= routing.HandshakeReliable
+	}
+
+	if rg.cfg.FlowControl {
+		flags |= routing.HandshakeFlowControl
+	}
+
+	if rg.datagram {
+		flags |= routing.HandshakeDatagram
+	}
+
+	return flags
+}
+
+// remoteSupports checks whether remote announced all of the `flags` in the handshake.
+func (rg *RouteGroup) remoteSupports(flags routing.HandshakeFlags) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.remoteFlags.Has(flags)
+}
+
+// markRecv records that a packet came in via the route with the `reverseID` reverse rule.
+func (rg *RouteGroup) markRecv(reverseID routing.RouteID) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if act := rg.activity(rg.reverseIdx(reverseID)); act != nil {
+		act.markRecv()
+	}
+}
+
+// reverseIdx returns index of the route which reverse rule has the `reverseID` key route ID, or -1.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) reverseIdx(reverseID routing.RouteID) int {
+	for i, rule := range rg.rvs {
+		if rule != nil && rule.KeyRouteID() == reverseID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// activity returns activity of the route of index `idx`, or nil if it's not recorded.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) activity(idx int) *routeActivity {
+	if idx < 0 || idx >= len(rg.act) {
+		return nil
+	}
+
+	return rg.act[idx]
 }
 
 func chanClosed(ch chan struct{}) bool {
@@ -0,0 +1,197 @@
+package router
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+// Once flow control is negotiated within the handshake, each edge announces its credit window,
+// which is the number of DataPackets it is able to buffer for its reader. The remote may only send
+// as many DataPackets as it was granted credits for. Credits are granted cumulatively with CreditPackets
+// as the reader consumes data, so lost CreditPackets are compensated by the following ones.
+// Credit of a DataPacket is only given back once the reader consumes it, so flow control is only used
+// along with reliable delivery, which retransmits the lost DataPackets and the ones the reader dropped.
+const (
+	creditInterval = time.Second
+)
+
+// flowState keeps track of credits of the route group.
+type flowState struct {
+	mx sync.Mutex
+
+	// sending side.
+	sent     uint32
+	limit    uint32
+	creditCh chan struct{} // signaled once remote grants more credits
+
+	// receiving side.
+	window     uint32
+	consumed   uint32
+	advertised uint32
+	grantCh    chan struct{} // signaled once credits should be granted
+}
+
+func newFlowState(remoteWindow, window uint32) *flowState {
+	return &flowState{
+		limit:      remoteWindow,
+		creditCh:   make(chan struct{}, 1),
+		window:     window,
+		advertised: window,
+		grantCh:    make(chan struct{}, 1),
+	}
+}
+
+// take takes a credit to send a DataPacket, it returns false if no credits are left.
+func (s *flowState) take() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if !seqBefore(s.sent, s.limit) {
+		return false
+	}
+
+	s.sent++
+
+	return true
+}
+
+// grant updates the credit limit granted by remote.
+func (s *flowState) grant(limit uint32) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	// CreditPackets may come out of order via different routes.
+	if !seqBefore(s.limit, limit) {
+		return
+	}
+
+	s.limit = limit
+
+	select {
+	case s.creditCh <- struct{}{}:
+	default:
+	}
+}
+
+// consume marks a DataPacket as consumed by the reader. Credits are granted
+// once half of the window is consumed, so that remote doesn't stall.
+func (s *flowState) consume() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.consumed++
+
+	if s.consumed+s.window-s.advertised < s.window/2 {
+		return
+	}
+
+	select {
+	case s.grantCh <- struct{}{}:
+	default:
+	}
+}
+
+// credit returns the credit limit to grant remote with.
+func (s *flowState) credit() uint32 {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.advertised = s.consumed + s.window
+
+	return s.advertised
+}
+
+// flowControl returns flow control state, or nil if flow control wasn't negotiated.
+func (rg *RouteGroup) flowControl() *flowState {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.flow
+}
+
+// takeCredit waits until remote grants a credit to send a DataPacket.
+func (rg *RouteGroup) takeCredit() error {
+	flow := rg.flowControl()
+	if flow == nil {
+		return nil
+	}
+
+	for {
+		if flow.take() {
+			return nil
+		}
+
+		select {
+		case <-flow.creditCh:
+		case <-rg.writeDeadline.Wait():
+			return timeoutError{}
+		case <-rg.closed:
+			return io.ErrClosedPipe
+		case <-rg.remoteClosed:
+			return io.ErrClosedPipe
+		}
+	}
+}
+
+// startCreditLoop starts granting credits to remote if flow control was negotiated.
+func (rg *RouteGroup) startCreditLoop() {
+	flow := rg.flowControl()
+	if flow == nil {
+		return
+	}
+
+	go rg.creditLoop(flow)
+}
+
+// creditLoop grants credits once the reader consumes half of the window. Credits are also
+// granted periodically, so that remote recovers from lost CreditPackets.
+func (rg *RouteGroup) creditLoop(flow *flowState) {
+	ticker := time.NewTicker(creditInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rg.remoteClosed:
+			return
+		case <-flow.grantCh:
+		case <-ticker.C:
+		}
+
+		if err := rg.sendCredit(flow.credit()); err != nil {
+			rg.logger.WithError(err).Debug("Failed to send credit packet")
+		}
+	}
+}
+
+func (rg *RouteGroup) sendCredit(limit uint32) error {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if len(rg.tps) == 0 || len(rg.fwd) == 0 {
+		return ErrNoTransports
+	}
+
+	var err error
+
+	// credits are granted via each of the routes, so that they survive failure of some of them
+	for i := 0; i < len(rg.tps) && i < len(rg.fwd); i++ {
+		tp := rg.tps[i]
+		rule := rg.fwd[i]
+
+		if tp == nil {
+			continue
+		}
+
+		packet := routing.MakeCreditPacket(rule.NextRouteID(), limit)
+
+		if wErr := rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID()); wErr != nil {
+			err = wErr
+		}
+	}
+
+	return err
+}
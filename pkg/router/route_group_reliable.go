@@ -0,0 +1,395 @@
+package router
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+// Once reliable delivery is negotiated within the handshake, payload of each DataPacket is a frame:
+//     | kind (byte) | seq (uint32) | data (~) |
+// Data frames carry sequenced data, ack frames carry the sequence number of the next awaited data frame.
+// Frames are carried by DataPackets, so intermediaries forward them as usual.
+const (
+	frameData byte = iota
+	frameAck
+)
+
+const (
+	frameHeaderSize = 1 + 4
+	maxSegmentSize  = math.MaxUint16 - frameHeaderSize
+
+	reliableWindow        = 256
+	reliableCheckInterval = 50 * time.Millisecond
+	minRTO                = 200 * time.Millisecond
+	maxRTO                = 5 * time.Second
+	maxRetransmits        = 20
+	maxRTOBackoff         = 4
+)
+
+var (
+	// ErrBadFrame is returned when data of the reliable route group can't be parsed.
+	ErrBadFrame = errors.New("bad data frame")
+)
+
+func encodeFrame(kind byte, seq uint32, data []byte) []byte {
+	frame := make([]byte, frameHeaderSize+len(data))
+	frame[0] = kind
+	binary.BigEndian.PutUint32(frame[1:], seq)
+	copy(frame[frameHeaderSize:], data)
+
+	return frame
+}
+
+func decodeFrame(frame []byte) (kind byte, seq uint32, data []byte, err error) {
+	if len(frame) < frameHeaderSize {
+		return 0, 0, nil, ErrBadFrame
+	}
+
+	return frame[0], binary.BigEndian.Uint32(frame[1:]), frame[frameHeaderSize:], nil
+}
+
+// seqBefore checks whether sequence number `a` goes before `b`, taking wrapping into account.
+func seqBefore(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// segment is a data frame awaiting acknowledgement.
+type segment struct {
+	seq     uint32
+	frame   []byte
+	sentAt  time.Time
+	retries int
+}
+
+// reliableState keeps track of sequencing, acknowledgement and retransmission of route group data.
+type reliableState struct {
+	mx sync.Mutex
+
+	// sending side.
+	nextSeq uint32
+	unacked []*segment // ordered by seq
+	srtt    time.Duration
+	space   chan struct{} // signaled once window gets space
+
+	// receiving side.
+	expected  uint32
+	pending   map[uint32][]byte // data which isn't delivered to the reader yet
+	ackCh     chan struct{}     // signaled once ack should be sent
+	deliverMx sync.Mutex        // serializes delivery of data received via different routes
+}
+
+func newReliableState() *reliableState {
+	return &reliableState{
+		space:   make(chan struct{}, 1),
+		pending: make(map[uint32][]byte),
+		ackCh:   make(chan struct{}, 1),
+	}
+}
+
+// push sequences `data`, it returns false if the window is full.
+func (s *reliableState) push(data []byte) (*segment, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if len(s.unacked) >= reliableWindow {
+		return nil, false
+	}
+
+	seg := &segment{
+		seq:    s.nextSeq,
+		frame:  encodeFrame(frameData, s.nextSeq, data),
+		sentAt: time.Now(),
+	}
+
+	s.nextSeq++
+	s.unacked = append(s.unacked, seg)
+
+	return seg, true
+}
+
+// ack removes segments acknowledged by remote which awaits `next` sequence number.
+func (s *reliableState) ack(next uint32) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	acked := 0
+	for _, seg := range s.unacked {
+		if !seqBefore(seg.seq, next) {
+			break
+		}
+
+		// retransmitted segments are ambiguous to measure RTT with.
+		if seg.retries == 0 {
+			s.sampleRTT(time.Since(seg.sentAt))
+		}
+
+		acked++
+	}
+
+	if acked == 0 {
+		return
+	}
+
+	s.unacked = s.unacked[acked:]
+
+	select {
+	case s.space <- struct{}{}:
+	default:
+	}
+}
+
+// NOTE: not thread-safe.
+func (s *reliableState) sampleRTT(rtt time.Duration) {
+	if s.srtt == 0 {
+		s.srtt = rtt
+		return
+	}
+
+	s.srtt = (7*s.srtt + rtt) / 8
+}
+
+func (s *reliableState) rto() time.Duration {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rto := 2 * s.srtt
+	if rto < minRTO {
+		rto = minRTO
+	}
+
+	if rto > maxRTO {
+		rto = maxRTO
+	}
+
+	return rto
+}
+
+// expired returns segments which weren't acknowledged within `rto` and marks them as resent.
+// It returns false if any of the segments was retransmitted too many times.
+func (s *reliableState) expired(rto time.Duration) ([]*segment, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var resend []*segment
+
+	now := time.Now()
+	for _, seg := range s.unacked {
+		// back off exponentially, so that segments outlive route repairs.
+		backoff := seg.retries
+		if backoff > maxRTOBackoff {
+			backoff = maxRTOBackoff
+		}
+
+		if now.Sub(seg.sentAt) < rto<<uint(backoff) {
+			continue
+		}
+
+		if seg.retries >= maxRetransmits {
+			return nil, false
+		}
+
+		seg.retries++
+		seg.sentAt = now
+		resend = append(resend, seg)
+	}
+
+	return resend, true
+}
+
+// receive stores `data` of sequence number `seq` and returns the data which may be delivered in order.
+// Data is only acknowledged once it's marked as delivered, so remote retransmits the data which
+// the reader failed to take.
+func (s *reliableState) receive(seq uint32, data []byte) [][]byte {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if seqBefore(seq, s.expected) || seq-s.expected >= reliableWindow {
+		return nil
+	}
+
+	s.pending[seq] = data
+
+	var ready [][]byte
+	for next := s.expected; ; next++ {
+		data, ok := s.pending[next]
+		if !ok {
+			break
+		}
+
+		ready = append(ready, data)
+	}
+
+	return ready
+}
+
+// delivered marks the next awaited data as delivered to the reader.
+func (s *reliableState) delivered() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.pending, s.expected)
+	s.expected++
+}
+
+// requestAck makes the ack loop send an ack, acks requested while one is being sent are coalesced.
+func (s *reliableState) requestAck() {
+	select {
+	case s.ackCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *reliableState) ackSeq() uint32 {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.expected
+}
+
+// reliability returns reliable delivery state, or nil if reliable delivery wasn't negotiated.
+func (rg *RouteGroup) reliability() *reliableState {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.rel
+}
+
+// writeReliable sequences `p` and writes it in segments. Segments which can't be written right away
+// are kept to be retransmitted, possibly via another route.
+func (rg *RouteGroup) writeReliable(rel *reliableState, p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxSegmentSize {
+			chunk = chunk[:maxSegmentSize]
+		}
+
+		if err := rg.takeCredit(); err != nil {
+			return written, err
+		}
+
+		seg, err := rg.pushSegment(rel, chunk)
+		if err != nil {
+			return written, err
+		}
+
+		if _, err := rg.writeData(seg.frame, rg.writeDeadline.Wait()); err != nil {
+			rg.logger.WithError(err).Debugf("Failed to write segment %d, it will be retransmitted", seg.seq)
+		}
+
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+// pushSegment waits until the window has space for `data`.
+func (rg *RouteGroup) pushSegment(rel *reliableState, data []byte) (*segment, error) {
+	for {
+		if seg, ok := rel.push(data); ok {
+			return seg, nil
+		}
+
+		select {
+		case <-rel.space:
+		case <-rg.writeDeadline.Wait():
+			return nil, timeoutError{}
+		case <-rg.closed:
+			return nil, io.ErrClosedPipe
+		case <-rg.remoteClosed:
+			return nil, io.ErrClosedPipe
+		}
+	}
+}
+
+func (rg *RouteGroup) handleReliableData(rel *reliableState, payload []byte) error {
+	kind, seq, data, err := decodeFrame(payload)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case frameAck:
+		rel.ack(seq)
+		return nil
+	case frameData:
+		rel.deliverMx.Lock()
+		defer rel.deliverMx.Unlock()
+
+		// remote awaits an ack in any case, it might have missed the previous one.
+		defer rel.requestAck()
+
+		for _, data := range rel.receive(seq, data) {
+			if err := rg.pushRead(data); err != nil {
+				if errors.Is(err, errDataDropped) {
+					// data stays pending and isn't acknowledged, so it's delivered once it's retransmitted
+					return nil
+				}
+
+				return err
+			}
+
+			rel.delivered()
+		}
+
+		return nil
+	default:
+		return ErrBadFrame
+	}
+}
+
+// startReliableLoops starts sending acks and retransmitting segments if reliable delivery was negotiated.
+func (rg *RouteGroup) startReliableLoops() {
+	rel := rg.reliability()
+	if rel == nil {
+		return
+	}
+
+	go rg.ackLoop(rel)
+	go rg.servicePacketLoop("retransmission", reliableCheckInterval, func(time.Duration) {
+		rg.retransmit(rel)
+	})
+}
+
+// ackLoop sends acks, acks requested while one is being sent are coalesced.
+func (rg *RouteGroup) ackLoop(rel *reliableState) {
+	for {
+		select {
+		case <-rg.remoteClosed:
+			return
+		case <-rel.ackCh:
+			if _, err := rg.writeData(encodeFrame(frameAck, rel.ackSeq(), nil), nil); err != nil {
+				rg.logger.WithError(err).Debug("Failed to send ack")
+			}
+		}
+	}
+}
+
+func (rg *RouteGroup) retransmit(rel *reliableState) {
+	resend, ok := rel.expired(rel.rto())
+	if !ok {
+		rg.logger.Errorf("Data wasn't acknowledged after %d retransmissions, closing...", maxRetransmits)
+
+		go func() {
+			if err := rg.closeWithCode(routing.CloseTransportFailed); err != nil {
+				rg.logger.WithError(err).Error("Failed to close route group")
+			}
+		}()
+
+		return
+	}
+
+	for _, seg := range resend {
+		if _, err := rg.writeData(seg.frame, nil); err != nil {
+			rg.logger.WithError(err).Debugf("Failed to retransmit segment %d", seg.seq)
+		}
+	}
+}
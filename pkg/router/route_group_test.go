@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -182,6 +184,456 @@ func testWrite(t *testing.T, rg1, rg2 *RouteGroup, m1, m2 *transport.Manager) {
 	rg1.mu.Unlock()
 }
 
+func TestRouteGroup_writeRoutes(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+
+	_, err := rg.writeRoutes()
+	require.Equal(t, ErrNoTransports, err)
+
+	tp := &transport.ManagedTransport{}
+	rule := routing.ForwardRule(ruleKeepAlive, 1, 2, uuid.New(), rg.desc.SrcPK(), rg.desc.DstPK(), 0, 0)
+
+	rg.tps = []transport.PacketWriter{tp, tp, nil}
+	rg.fwd = []routing.Rule{rule}
+
+	_, err = rg.writeRoutes()
+	require.Equal(t, ErrRuleTransportMismatch, err)
+
+	rg.fwd = []routing.Rule{rule, rule, rule}
+
+	routeIdxs := func() []int {
+		routes, err := rg.writeRoutes()
+		require.NoError(t, err)
+
+		idxs := make([]int, 0, len(routes))
+		for _, route := range routes {
+			idxs = append(idxs, route.idx)
+		}
+
+		return idxs
+	}
+
+	// failover: primary route goes first, routes with nil transports are omitted
+	require.Equal(t, []int{0, 1}, routeIdxs())
+	rg.setPrimary(1)
+	require.Equal(t, []int{1, 0}, routeIdxs())
+
+	// stripe: every call starts with the next route
+	rg.cfg.WritePolicy = WriteStripe
+	require.Equal(t, []int{0, 1}, routeIdxs())
+	require.Equal(t, []int{1, 0}, routeIdxs())
+	require.Equal(t, []int{0, 1}, routeIdxs())
+}
+
+func TestRouteGroup_routes(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+	pk1, pk2 := rg.desc.SrcPK(), rg.desc.DstPK()
+
+	tp1 := &transport.ManagedTransport{Entry: transport.Entry{ID: uuid.New()}}
+	tp2 := &transport.ManagedTransport{Entry: transport.Entry{ID: uuid.New()}}
+	tps := map[uuid.UUID]*transport.ManagedTransport{tp1.Entry.ID: tp1, tp2.Entry.ID: tp2}
+	lookupTp := func(id uuid.UUID) transport.PacketWriter {
+		if tp, ok := tps[id]; ok {
+			return tp
+		}
+
+		return nil
+	}
+
+	rg.appendRules(routing.EdgeRules{
+		Forward:         routing.ForwardRule(ruleKeepAlive, 1, 11, tp1.Entry.ID, pk1, pk2, 0, 0),
+		Reverse:         routing.ConsumeRule(ruleKeepAlive, 2, pk1, pk2, 0, 0),
+		RemoteReverseID: 21,
+	}, tp1)
+	rg.appendRules(routing.EdgeRules{
+		Forward:         routing.ForwardRule(ruleKeepAlive, 3, 13, tp2.Entry.ID, pk1, pk2, 0, 0),
+		Reverse:         routing.ConsumeRule(ruleKeepAlive, 4, pk1, pk2, 0, 0),
+		RemoteReverseID: 23,
+	}, tp2)
+
+	require.Empty(t, rg.brokenRoutes(lookupTp, time.Minute))
+
+	// transport is gone
+	delete(tps, tp2.Entry.ID)
+	require.Equal(t, []int{1}, rg.brokenRoutes(lookupTp, time.Minute))
+
+	// transport is re-created
+	tp2New := &transport.ManagedTransport{Entry: tp2.Entry}
+	tps[tp2.Entry.ID] = tp2New
+	require.Empty(t, rg.brokenRoutes(lookupTp, time.Minute))
+	require.Equal(t, tp2New, rg.tps[1])
+
+	// nothing comes in via the second route
+	time.Sleep(10 * time.Millisecond)
+	rg.markRecv(2)
+	require.Equal(t, []int{1}, rg.brokenRoutes(lookupTp, 5*time.Millisecond))
+
+	newFwd := routing.ForwardRule(ruleKeepAlive, 5, 15, tp1.Entry.ID, pk1, pk2, 0, 0)
+	newRvs := routing.ConsumeRule(ruleKeepAlive, 6, pk1, pk2, 0, 0)
+	newRules := routing.EdgeRules{Forward: newFwd, Reverse: newRvs, RemoteReverseID: 25}
+
+	// routes are replaced by their reverse rules
+	oldFwd, oldRvs, ok := rg.replaceRoute(4, newRules, tp1)
+	require.True(t, ok)
+	require.Equal(t, routing.RouteID(3), oldFwd.KeyRouteID())
+	require.Equal(t, routing.RouteID(4), oldRvs.KeyRouteID())
+	require.Equal(t, []routing.RouteID{21, 25}, rg.remoteRvs)
+	require.Empty(t, rg.brokenRoutes(lookupTp, 5*time.Millisecond))
+
+	_, _, ok = rg.replaceRoute(4, newRules, tp1)
+	require.False(t, ok)
+
+	rg.setPrimary(1)
+
+	fwd, ok := rg.removeRoute(2)
+	require.True(t, ok)
+	require.Equal(t, routing.RouteID(1), fwd.KeyRouteID())
+	require.Equal(t, 1, rg.routesCount())
+	require.Equal(t, 0, rg.primary)
+	require.Equal(t, newRvs, rg.rvs[0])
+	require.Equal(t, []routing.RouteID{25}, rg.remoteRvs)
+
+	_, ok = rg.removeRoute(2)
+	require.False(t, ok)
+}
+
+func TestRouteGroup_handleTracePacket(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+	remotePK, _ := cipher.GenerateKeyPair()
+
+	resultCh := make(chan []routing.TraceHop, 1)
+	rg.traces[7] = resultCh
+
+	sent := []routing.TraceHop{{PK: rg.desc.DstPK(), Time: time.Now()}, {PK: remotePK, Time: time.Now()}}
+
+	packet, err := routing.MakeTracePacket(1, 7, true, sent)
+	require.NoError(t, err)
+	require.NoError(t, rg.handlePacket(packet))
+
+	hops := <-resultCh
+	require.Len(t, hops, 3)
+	require.Equal(t, remotePK, hops[1].PK)
+	require.Equal(t, rg.desc.DstPK(), hops[2].PK)
+
+	// traces which are no longer awaited are dropped
+	packet, err = routing.MakeTracePacket(1, 8, true, sent)
+	require.NoError(t, err)
+	require.NoError(t, rg.handlePacket(packet))
+
+	// there's no route to send the trace back via
+	packet, err = routing.MakeTracePacket(1, 9, false, sent)
+	require.NoError(t, err)
+	require.Equal(t, ErrNoTransports, rg.handlePacket(packet))
+}
+
+func TestReliableState(t *testing.T) {
+	t.Run("receive", func(t *testing.T) {
+		s := newReliableState()
+
+		require.Empty(t, s.receive(1, []byte("b")))
+		require.Empty(t, s.receive(2, []byte("c")))
+
+		ready := s.receive(0, []byte("a"))
+		require.Equal(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, ready)
+		require.Equal(t, uint32(0), s.ackSeq(), "data is acknowledged only once it's delivered")
+
+		s.delivered()
+		require.Equal(t, [][]byte{[]byte("b"), []byte("c")}, s.receive(2, []byte("c")))
+
+		s.delivered()
+		s.delivered()
+		require.Equal(t, uint32(3), s.ackSeq())
+
+		// duplicates and data out of the window are dropped
+		require.Empty(t, s.receive(1, []byte("b")))
+		require.Empty(t, s.receive(3+reliableWindow, []byte("x")))
+		require.Equal(t, uint32(3), s.ackSeq())
+	})
+
+	t.Run("ack", func(t *testing.T) {
+		s := newReliableState()
+
+		for i := 0; i < reliableWindow; i++ {
+			_, ok := s.push([]byte{byte(i)})
+			require.True(t, ok)
+		}
+
+		_, ok := s.push([]byte("full"))
+		require.False(t, ok)
+
+		s.ack(2)
+		require.Len(t, s.unacked, reliableWindow-2)
+		require.Equal(t, uint32(2), s.unacked[0].seq)
+
+		seg, ok := s.push([]byte("space"))
+		require.True(t, ok)
+		require.Equal(t, uint32(reliableWindow), seg.seq)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		s := newReliableState()
+
+		seg, ok := s.push([]byte("a"))
+		require.True(t, ok)
+
+		resend, ok := s.expired(time.Hour)
+		require.True(t, ok)
+		require.Empty(t, resend)
+
+		resend, ok = s.expired(0)
+		require.True(t, ok)
+		require.Equal(t, []*segment{seg}, resend)
+
+		seg.retries = maxRetransmits
+		_, ok = s.expired(0)
+		require.False(t, ok)
+	})
+
+	t.Run("wrapping", func(t *testing.T) {
+		require.True(t, seqBefore(math.MaxUint32, 0))
+		require.False(t, seqBefore(0, math.MaxUint32))
+	})
+}
+
+func TestRouteGroup_handleReliableData(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+	rg.rel = newReliableState()
+
+	for _, seq := range []uint32{1, 0, 0, 2} {
+		packet, err := routing.MakeDataPacket(1, encodeFrame(frameData, seq, []byte(strconv.Itoa(int(seq)))))
+		require.NoError(t, err)
+		require.NoError(t, rg.handlePacket(packet))
+	}
+
+	for _, want := range []string{"0", "1", "2"} {
+		require.Equal(t, want, string(<-rg.readCh))
+	}
+
+	_, ok := rg.rel.push([]byte("a"))
+	require.True(t, ok)
+
+	packet, err := routing.MakeDataPacket(1, encodeFrame(frameAck, 1, nil))
+	require.NoError(t, err)
+	require.NoError(t, rg.handlePacket(packet))
+	require.Empty(t, rg.rel.unacked)
+
+	packet, err = routing.MakeDataPacket(1, []byte{frameData})
+	require.NoError(t, err)
+	require.Equal(t, ErrBadFrame, rg.handlePacket(packet))
+}
+
+func TestRouteGroup_handleReliableData_dropped(t *testing.T) {
+	cfg := DefaultRouteGroupConfig()
+	cfg.ReadChBufSize = 1
+
+	rg := createRouteGroup(cfg)
+	rg.rel = newReliableState()
+	rg.flow = newFlowState(0, 1)
+
+	send := func(seq uint32) {
+		packet, err := routing.MakeDataPacket(1, encodeFrame(frameData, seq, []byte(strconv.Itoa(int(seq)))))
+		require.NoError(t, err)
+		require.NoError(t, rg.handlePacket(packet))
+	}
+
+	// the second data is dropped as the read buffer is full, so it's not acknowledged
+	send(0)
+	send(1)
+	require.Equal(t, uint32(1), rg.rel.ackSeq())
+
+	require.Equal(t, "0", string(<-rg.readCh))
+
+	// retransmitted data is delivered
+	send(1)
+	require.Equal(t, uint32(2), rg.rel.ackSeq())
+	require.Equal(t, "1", string(<-rg.readCh))
+}
+
+func TestRouteGroup_closeCode(t *testing.T) {
+	t.Run("closed by remote", func(t *testing.T) {
+		rg := createRouteGroup(DefaultRouteGroupConfig())
+
+		rg.mu.Lock()
+		require.NoError(t, rg.handleClosePacket(routing.CloseRuleExpired))
+		rg.mu.Unlock()
+
+		_, err := rg.Read(make([]byte, 1))
+		require.Equal(t, &routing.CloseError{Code: routing.CloseRuleExpired}, err)
+
+		_, err = rg.Write([]byte("a"))
+		require.Equal(t, &routing.CloseError{Code: routing.CloseRuleExpired}, err)
+	})
+
+	t.Run("closed on request", func(t *testing.T) {
+		rg := createRouteGroup(DefaultRouteGroupConfig())
+
+		rg.mu.Lock()
+		require.NoError(t, rg.handleClosePacket(routing.CloseRequested))
+		rg.mu.Unlock()
+
+		_, err := rg.Read(make([]byte, 1))
+		require.Equal(t, io.EOF, err)
+	})
+}
+
+func TestRouteGroup_readDatagram(t *testing.T) {
+	cfg := DefaultRouteGroupConfig()
+	cfg.Datagram = true
+
+	rg := createRouteGroup(cfg)
+
+	rg.readCh <- []byte("first")
+	rg.readCh <- []byte("second")
+
+	buf := make([]byte, 3)
+
+	// message boundaries are kept, the rest of the message is discarded
+	n, err := rg.read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "fir", string(buf[:n]))
+
+	n, err = rg.read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "sec", string(buf[:n]))
+}
+
+func TestFlowState(t *testing.T) {
+	s := newFlowState(2, 4)
+
+	require.True(t, s.take())
+	require.True(t, s.take())
+	require.False(t, s.take())
+
+	// stale credits are ignored
+	s.grant(1)
+	require.False(t, s.take())
+
+	s.grant(3)
+	require.True(t, s.take())
+	require.False(t, s.take())
+
+	s.consume()
+	require.Len(t, s.grantCh, 0)
+
+	s.consume()
+	require.Len(t, s.grantCh, 1)
+	require.Equal(t, uint32(6), s.credit())
+}
+
+func TestRouteGroup_takeCredit(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+	rg.flow = newFlowState(0, 4)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rg.takeCredit()
+	}()
+
+	select {
+	case <-errCh:
+		t.Fatal("credit taken without being granted")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, rg.handlePacket(routing.MakeCreditPacket(1, 1)))
+	require.NoError(t, <-errCh)
+
+	require.NoError(t, rg.SetWriteDeadline(time.Now()))
+	require.Equal(t, timeoutError{}, rg.takeCredit())
+}
+
+func TestRouteGroup_flowControl_lostData(t *testing.T) {
+	const (
+		window = 4
+		total  = 5 * window
+	)
+
+	cfg := DefaultRouteGroupConfig()
+	cfg.ReadChBufSize = window
+
+	// receiving edge.
+	rg := createRouteGroup(cfg)
+	rg.rel = newReliableState()
+	rg.flow = newFlowState(window, window)
+
+	// sending edge.
+	rel := newReliableState()
+	flow := newFlowState(window, window)
+
+	// every third DataPacket gets lost.
+	transmitted := 0
+	transmit := func(seg *segment) {
+		if transmitted++; transmitted%3 == 0 {
+			return
+		}
+
+		packet, err := routing.MakeDataPacket(1, seg.frame)
+		require.NoError(t, err)
+		require.NoError(t, rg.handlePacket(packet))
+	}
+
+	buf := make([]byte, 8)
+	got := make([]string, 0, total)
+
+	for written := 0; len(got) < total; {
+		require.Less(t, transmitted, 10*total, "writer doesn't progress")
+
+		for written < total && flow.take() {
+			seg, ok := rel.push([]byte(strconv.Itoa(written)))
+			require.True(t, ok)
+			transmit(seg)
+			written++
+		}
+
+		for len(rg.readCh) > 0 {
+			n, err := rg.Read(buf)
+			require.NoError(t, err)
+			got = append(got, string(buf[:n]))
+		}
+
+		rel.ack(rg.rel.ackSeq())
+		flow.grant(rg.flow.credit())
+
+		resend, ok := rel.expired(0)
+		require.True(t, ok)
+
+		for _, seg := range resend {
+			transmit(seg)
+		}
+	}
+
+	for i, s := range got {
+		require.Equal(t, strconv.Itoa(i), s)
+	}
+}
+
+func TestRouteGroup_flowControl_unreliable(t *testing.T) {
+	cfg := DefaultRouteGroupConfig()
+	cfg.Reliable = false
+	cfg.FlowControl = true
+
+	rg := createRouteGroup(cfg)
+
+	flags := routing.HandshakeReliable | routing.HandshakeFlowControl
+	require.NoError(t, rg.handlePacket(routing.MakeHandshakePacket(1, false, flags, 4)))
+
+	// lost data would never give its credit back without retransmission.
+	require.Nil(t, rg.flowControl())
+}
+
+func TestRouteGroup_handshakeDatagram(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+
+	flags := routing.HandshakeReliable | routing.HandshakeFlowControl | routing.HandshakeDatagram
+	require.NoError(t, rg.handlePacket(routing.MakeHandshakePacket(1, false, flags, 4)))
+
+	// datagrams are never retransmitted nor flow controlled
+	require.True(t, rg.isDatagram())
+	require.Nil(t, rg.reliability())
+	require.Nil(t, rg.flowControl())
+	require.True(t, rg.handshakeFlags().Has(routing.HandshakeDatagram))
+}
+
 func TestRouteGroup_ReadWrite(t *testing.T) {
 	const iterations = 3
 
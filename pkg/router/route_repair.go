@@ -0,0 +1,299 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+const (
+	// routeRepairInterval is the interval of checking route groups for broken routes.
+	routeRepairInterval = 3 * time.Second
+	// routeStaleTimeout is the time after which the route which doesn't bring any packets
+	// from remote is considered broken. Routes are kept alive at least every `DefaultRouteKeepAlive/2`,
+	// and the timeout leaves some time to repair the route before its rules expire.
+	routeStaleTimeout = DefaultRouteKeepAlive * 5 / 6
+	// routeRepairTimeout limits the time spent on setting up a single replacement route.
+	routeRepairTimeout = 20 * time.Second
+	// repairPathCandidates is the number of disjoint paths to choose the replacement route from.
+	repairPathCandidates = 8
+	// routeRepairConcurrency is the maximum number of route groups repaired at once.
+	routeRepairConcurrency = 8
+)
+
+func (r *router) routeRepairLoop() {
+	ticker := time.NewTicker(routeRepairInterval)
+	defer ticker.Stop()
+
+	repairs := newRouteRepairs(routeRepairConcurrency)
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.repairRoutes(repairs)
+		}
+	}
+}
+
+// routeRepairs keeps track of the route groups which are being repaired and limits the number
+// of route groups repaired at once.
+type routeRepairs struct {
+	sem    chan struct{}
+	mx     sync.Mutex
+	active map[*RouteGroup]struct{}
+}
+
+func newRouteRepairs(concurrency int) *routeRepairs {
+	return &routeRepairs{
+		sem:    make(chan struct{}, concurrency),
+		active: make(map[*RouteGroup]struct{}),
+	}
+}
+
+// start marks `rg` as being repaired, it returns false if the route group is being repaired already
+// or if there are too many route groups being repaired.
+func (rr *routeRepairs) start(rg *RouteGroup) bool {
+	rr.mx.Lock()
+	defer rr.mx.Unlock()
+
+	if _, ok := rr.active[rg]; ok {
+		return false
+	}
+
+	select {
+	case rr.sem <- struct{}{}:
+	default:
+		return false
+	}
+
+	rr.active[rg] = struct{}{}
+
+	return true
+}
+
+func (rr *routeRepairs) done(rg *RouteGroup) {
+	rr.mx.Lock()
+	defer rr.mx.Unlock()
+
+	delete(rr.active, rg)
+	<-rr.sem
+}
+
+// repairRoutes replaces broken routes of the route groups. Route is broken if its transport
+// is gone or if it stopped bringing packets from remote. Only the edge which dialed the route group
+// repairs its routes, so the replacement routes aren't set up twice. Remote puts the new route
+// in place of the broken one within the same route group, apps don't notice anything. Route groups
+// are repaired concurrently, the ones which are still repaired since the previous call are skipped.
+// Route groups which are left without transports get closed with CloseTransportFailed.
+func (r *router) repairRoutes(repairs *routeRepairs) {
+	r.mx.Lock()
+	nrgs := make([]*NoiseRouteGroup, 0, len(r.rgsNs))
+	for _, nrg := range r.rgsNs {
+		if nrg != nil {
+			nrgs = append(nrgs, nrg)
+		}
+	}
+	r.mx.Unlock()
+
+	for _, nrg := range nrgs {
+		rg := nrg.rg
+
+		if !rg.IsAlive() {
+			continue
+		}
+
+		// remote should be able to attach new route to the existing route group
+		_, dialed := rg.setupNode()
+		if dialed && rg.remoteSupports(routing.HandshakeMultiRoute) {
+			if broken := rg.brokenRoutes(r.tm.Writer, routeStaleTimeout); len(broken) > 0 && repairs.start(rg) {
+				go func() {
+					defer repairs.done(rg)
+
+					for _, idx := range broken {
+						if err := r.repairRoute(rg, idx); err != nil {
+							r.logger.WithError(err).Warnf("Failed to repair route of route group %s", &rg.desc)
+						}
+					}
+				}()
+			}
+		}
+
+		if rg.transportsGone(r.tm.Writer) {
+			r.logger.Infof("Transports of route group %s are gone, closing...", &rg.desc)
+
+			r.removeNoiseRouteGroup(rg.desc)
+
+			if err := nrg.CloseWithCode(routing.CloseTransportFailed); err != nil {
+				r.logger.WithError(err).Warnf("Failed to close route group %s", &rg.desc)
+			}
+		}
+	}
+}
+
+// repairRoute sets up a new route between the route group edges and puts it in place of the route of index `idx`.
+// Remote is told which of its routes the new one replaces.
+func (r *router) repairRoute(rg *RouteGroup, idx int) error {
+	rg.mu.Lock()
+	if idx >= len(rg.fwd) || idx >= len(rg.rvs) {
+		rg.mu.Unlock()
+		return fmt.Errorf("route %d is already gone", idx)
+	}
+
+	brokenTpID := rg.fwd[idx].NextTransportID()
+	reverseID := rg.rvs[idx].KeyRouteID()
+	brokenPath := rg.paths[reverseID]
+
+	var remoteReverseID routing.RouteID
+	if idx < len(rg.remoteRvs) {
+		remoteReverseID = rg.remoteRvs[idx]
+	}
+	rg.mu.Unlock()
+
+	desc := rg.desc.Invert()
+
+	// the broken route may still be in discovery, the new one must not go through any part of it
+	avoid := newPathAvoidance(desc, brokenTpID, brokenPath)
+
+	r.logger.Infof("Repairing route via transport %s of route group %s", brokenTpID, &rg.desc)
+
+	opts := &DialOptions{
+		MinForwardRts: 1,
+		MaxForwardRts: repairPathCandidates,
+		MinConsumeRts: 1,
+		MaxConsumeRts: repairPathCandidates,
+		Preferences:   rg.cfg.Preferences,
+	}
+
+	fwdPaths, rvsPaths, err := r.fetchBestRoutes(desc.SrcPK(), desc.DstPK(), opts)
+	if err != nil {
+		return fmt.Errorf("route finder: %w", err)
+	}
+
+	fwdPath, ok := avoid.firstDisjoint(fwdPaths)
+	if !ok {
+		return fmt.Errorf("no forward path disjoint with the broken route")
+	}
+
+	rvsPath, ok := avoid.firstDisjoint(rvsPaths)
+	if !ok {
+		return fmt.Errorf("no reverse path disjoint with the broken route")
+	}
+
+	req := routing.BidirectionalRoute{
+		Desc:      desc,
+		KeepAlive: DefaultRouteKeepAlive,
+		Forward:   fwdPath,
+		Reverse:   rvsPath,
+		Attach:    true,
+		Replaces:  remoteReverseID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), routeRepairTimeout)
+	defer cancel()
+
+	rules, _, err := r.dialRouteGroup(ctx, req)
+	if err != nil {
+		return fmt.Errorf("route setup: %w", err)
+	}
+
+	if err := r.SaveRoutingRules(rules.Forward, rules.Reverse); err != nil {
+		return err
+	}
+
+	tp := r.tm.Writer(rules.Forward.NextTransportID())
+
+	oldFwd, oldRvs, ok := rg.replaceRoute(reverseID, rules, tp)
+	if !ok {
+		// route group changed in the meantime, the new route is still of use
+		rg.appendRules(rules, tp)
+		rg.setRoutePath(rules.Reverse.KeyRouteID(), req)
+
+		return nil
+	}
+
+	rg.setRoutePath(rules.Reverse.KeyRouteID(), req)
+
+	r.rt.DelRules([]routing.RouteID{oldFwd.KeyRouteID(), oldRvs.KeyRouteID()})
+
+	r.logger.Infof("Repaired route of route group %s, new route goes via transport %s",
+		&rg.desc, rules.Forward.NextTransportID())
+
+	return nil
+}
+
+// routePath is the hops of a route in both directions.
+type routePath struct {
+	forward []routing.Hop
+	reverse []routing.Hop
+}
+
+// pathAvoidance is the set of transports and intermediate visors a path must not go through.
+type pathAvoidance struct {
+	desc routing.RouteDescriptor
+	tps  map[uuid.UUID]struct{}
+	pks  map[cipher.PubKey]struct{}
+}
+
+// newPathAvoidance returns the avoidance of the transports and intermediate visors of `path`
+// in both directions and of the transport of `tpID`. Edges of `desc` are never avoided.
+func newPathAvoidance(desc routing.RouteDescriptor, tpID uuid.UUID, path routePath) *pathAvoidance {
+	a := &pathAvoidance{
+		desc: desc,
+		tps:  map[uuid.UUID]struct{}{tpID: {}},
+		pks:  make(map[cipher.PubKey]struct{}),
+	}
+
+	for _, hops := range [][]routing.Hop{path.forward, path.reverse} {
+		for _, hop := range hops {
+			a.tps[hop.TpID] = struct{}{}
+
+			for _, pk := range []cipher.PubKey{hop.From, hop.To} {
+				if !a.isEdge(pk) {
+					a.pks[pk] = struct{}{}
+				}
+			}
+		}
+	}
+
+	return a
+}
+
+func (a *pathAvoidance) isEdge(pk cipher.PubKey) bool {
+	return pk == a.desc.SrcPK() || pk == a.desc.DstPK()
+}
+
+// disjoint checks whether `path` goes through none of the avoided transports and visors.
+func (a *pathAvoidance) disjoint(path []routing.Hop) bool {
+	for _, hop := range path {
+		if _, ok := a.tps[hop.TpID]; ok {
+			return false
+		}
+
+		for _, pk := range []cipher.PubKey{hop.From, hop.To} {
+			if _, ok := a.pks[pk]; ok && !a.isEdge(pk) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// firstDisjoint returns the first of `paths` which is disjoint with the avoided ones.
+func (a *pathAvoidance) firstDisjoint(paths [][]routing.Hop) ([]routing.Hop, bool) {
+	for _, path := range paths {
+		if a.disjoint(path) {
+			return path, true
+		}
+	}
+
+	return nil, false
+}
@@ -9,9 +9,11 @@ import (
 	"io"
 	"net"
 	"net/rpc"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/skycoin/dmsg/noise"
@@ -41,6 +43,9 @@ const (
 	maxHops       = 50
 	retryDuration = 10 * time.Second
 	retryInterval = 500 * time.Millisecond
+
+	// handshakeFlags are the route group features supported by this router.
+	handshakeFlags = routing.HandshakeMultiRoute
 )
 
 var (
@@ -49,6 +54,12 @@ var (
 
 	// ErrRemoteEmptyPK occurs when the specified remote public key is empty.
 	ErrRemoteEmptyPK = errors.New("empty remote public key")
+
+	// ErrHopLimitReached is returned when a packet is dropped as it can't be forwarded any further.
+	ErrHopLimitReached = errors.New("packet hop limit reached")
+
+	// ErrDatagramNotSupported is returned when remote doesn't keep message boundaries of the dialed route group.
+	ErrDatagramNotSupported = errors.New("remote doesn't support datagram route groups")
 )
 
 // Config configures Router.
@@ -61,6 +72,13 @@ type Config struct {
 	RouteGroupDialer setupclient.RouteGroupDialer
 	SetupNodes       []cipher.PubKey
 	RulesGCInterval  time.Duration
+	RoutingTable     routing.Table
+	// RouteSetupTrusted are visors allowed to set routes up via the router directly, same as setup nodes.
+	RouteSetupTrusted []cipher.PubKey
+	// DirectSetup, if set, makes the router set its routes up by itself instead of using setup nodes.
+	// Intermediaries and the remote have to trust the visor, routes are only built via
+	// intermediaries of 'RouteSetupTrusted'.
+	DirectSetup setupclient.DirectDialer
 }
 
 // SetDefaults sets default values for certain empty values.
@@ -76,14 +94,30 @@ func (c *Config) SetDefaults() {
 	if c.RulesGCInterval <= 0 {
 		c.RulesGCInterval = DefaultRulesGCInterval
 	}
+
+	if c.RoutingTable == nil {
+		c.RoutingTable = routing.NewTable()
+	}
 }
 
 // DialOptions describes dial options.
+// 'Min*Rts'/'Max*Rts' limit the number of disjoint forward/consume routes the route group is made of.
+// Each route pairs a forward and a consume path, so the route group is made of as many routes as the direction
+// with fewer paths has.
+// 'WritePolicy' specifies how the dialed route group writes via its forward routes.
+// 'Reliable' requests sequencing, acknowledgement and retransmission of data, it's only used if remote supports it.
+// 'Datagram' makes the route group keep message boundaries, such route groups are never reliable nor flow controlled.
+// Dialing fails with ErrDatagramNotSupported if remote doesn't keep the boundaries too.
+// 'Preferences' narrow down the routes the route group is made of, they are kept for route repair as well.
 type DialOptions struct {
 	MinForwardRts int
 	MaxForwardRts int
 	MinConsumeRts int
 	MaxConsumeRts int
+	WritePolicy   WritePolicy
+	Reliable      bool
+	Datagram      bool
+	Preferences   rfclient.RoutePreferences
 }
 
 // DefaultDialOptions returns default dial options.
@@ -94,9 +128,24 @@ func DefaultDialOptions() *DialOptions {
 		MaxForwardRts: 1,
 		MinConsumeRts: 1,
 		MaxConsumeRts: 1,
+		WritePolicy:   WriteFailover,
 	}
 }
 
+// minRoutes returns the minimum number of bidirectional routes the dialed route group should consist of.
+func (o *DialOptions) minRoutes() int {
+	min := o.MinForwardRts
+	if o.MinConsumeRts > min {
+		min = o.MinConsumeRts
+	}
+
+	if min < 1 {
+		min = 1
+	}
+
+	return min
+}
+
 // Router is responsible for creating and keeping track of routes.
 // Internally, it uses the routing table, route finder client and setup client.
 type Router interface {
@@ -107,7 +156,8 @@ type Router interface {
 	// A nil 'opts' input results in a value of '1' for all DialOptions fields.
 	// A single call to DialRoutes should perform the following:
 	// - Find routes via RouteFinder (in one call).
-	// - Setup routes via SetupNode (in one call).
+	// - Pick up to 'MaxForwardRts'/'MaxConsumeRts' disjoint routes out of the found ones.
+	// - Setup routes via SetupNode (in one call per route).
 	// - Save to routing.Table and internal RouteGroup map.
 	// - Return RouteGroup if successful.
 	DialRoutes(ctx context.Context, rPK cipher.PubKey, lPort, rPort routing.Port, opts *DialOptions) (net.Conn, error)
@@ -120,14 +170,28 @@ type Router interface {
 	AcceptRoutes(context.Context) (net.Conn, error)
 	SaveRoutingRules(rules ...routing.Rule) error
 	ReserveKeys(n int) ([]routing.RouteID, error)
+	ReleaseKeys(ids []routing.RouteID)
 	IntroduceRules(rules routing.EdgeRules) error
+	// CheckRules checks whether `rules` may be saved: transports they forward packets to
+	// must not be of exceeded data caps.
+	CheckRules(rules ...routing.Rule) error
 	Serve(context.Context) error
 	SetupIsTrusted(cipher.PubKey) bool
+	IsSetupNode(cipher.PubKey) bool
+
+	// TraceRoute traces the route group which consume rule is of key 'rid'.
+	// It returns visors the trace went through along the forward and then the reverse route.
+	TraceRoute(ctx context.Context, rid routing.RouteID) ([]routing.TraceHop, error)
 
 	// routing table related methods
+	// RouteGroupSetupNode returns public key of the setup node which set up the route group of `desc`.
+	// It's only known for the route groups dialed by the local visor.
+	RouteGroupSetupNode(desc routing.RouteDescriptor) (cipher.PubKey, bool)
+
 	RoutesCount() int
 	Rules() []routing.Rule
 	Rule(routing.RouteID) (routing.Rule, error)
+	RuleStats(routing.RouteID) routing.RuleStats
 	SaveRule(routing.Rule) error
 	DelRules([]routing.RouteID)
 }
@@ -141,12 +205,12 @@ type router struct {
 	logger        *logging.Logger
 	n             *snet.Network
 	sl            *snet.Listener
-	trustedVisors map[cipher.PubKey]struct{}
+	trustedVisors map[cipher.PubKey]struct{} // setup nodes along with the visors trusted to set routes up
+	setupNodes    map[cipher.PubKey]struct{}
 	tm            *transport.Manager
 	rt            routing.Table
 	rgsNs         map[routing.RouteDescriptor]*NoiseRouteGroup // Noise-wrapped route groups to push incoming reads from transports.
 	rgsRaw        map[routing.RouteDescriptor]*RouteGroup      // Not-yet-noise-wrapped route groups. when one of these gets wrapped, it gets removed from here
-	rpcSrv        *rpc.Server
 	accept        chan routing.EdgeRules
 	done          chan struct{}
 	wg            sync.WaitGroup
@@ -163,8 +227,14 @@ func New(n *snet.Network, config *Config) (Router, error) {
 	}
 
 	trustedVisors := make(map[cipher.PubKey]struct{})
+	setupNodes := make(map[cipher.PubKey]struct{})
 	for _, node := range config.SetupNodes {
 		trustedVisors[node] = struct{}{}
+		setupNodes[node] = struct{}{}
+	}
+
+	for _, pk := range config.RouteSetupTrusted {
+		trustedVisors[pk] = struct{}{}
 	}
 
 	r := &router{
@@ -172,21 +242,18 @@ func New(n *snet.Network, config *Config) (Router, error) {
 		logger:        config.Logger,
 		n:             n,
 		tm:            config.TransportManager,
-		rt:            routing.NewTable(),
+		rt:            config.RoutingTable,
 		sl:            sl,
 		rgsNs:         make(map[routing.RouteDescriptor]*NoiseRouteGroup),
 		rgsRaw:        make(map[routing.RouteDescriptor]*RouteGroup),
-		rpcSrv:        rpc.NewServer(),
 		accept:        make(chan routing.EdgeRules, acceptSize),
 		done:          make(chan struct{}),
 		trustedVisors: trustedVisors,
+		setupNodes:    setupNodes,
 	}
 
 	go r.rulesGCLoop()
-
-	if err := r.rpcSrv.Register(NewRPCGateway(r)); err != nil {
-		return nil, fmt.Errorf("failed to register RPC server")
-	}
+	go r.routeRepairLoop()
 
 	return r, nil
 }
@@ -196,7 +263,8 @@ func New(n *snet.Network, config *Config) (Router, error) {
 // A nil 'opts' input results in a value of '1' for all DialOptions fields.
 // A single call to DialRoutes should perform the following:
 // - Find routes via RouteFinder (in one call).
-// - Setup routes via SetupNode (in one call).
+// - Pick up to 'MaxForwardRts'/'MaxConsumeRts' disjoint routes out of the found ones.
+// - Setup routes via SetupNode (in one call per route).
 // - Save to routing.Table and internal RouteGroup map.
 // - Return RouteGroup if successful.
 func (r *router) DialRoutes(
@@ -212,25 +280,27 @@ func (r *router) DialRoutes(
 		return nil, fmt.Errorf("failed to dial routes: %w", err)
 	}
 
+	if opts == nil {
+		opts = DefaultDialOptions()
+	}
+
 	lPK := r.conf.PubKey
 	forwardDesc := routing.NewRouteDescriptor(lPK, rPK, lPort, rPort)
 
-	forwardPath, reversePath, err := r.fetchBestRoutes(lPK, rPK, opts)
+	forwardPaths, reversePaths, err := r.fetchBestRoutes(lPK, rPK, opts)
 	if err != nil {
 		return nil, fmt.Errorf("route finder: %w", err)
 	}
 
-	req := routing.BidirectionalRoute{
-		Desc:      forwardDesc,
-		KeepAlive: DefaultRouteKeepAlive,
-		Forward:   forwardPath,
-		Reverse:   reversePath,
+	reqs := bidirectionalRoutes(forwardDesc, forwardPaths, reversePaths)
+	if minRts := opts.minRoutes(); len(reqs) < minRts {
+		return nil, fmt.Errorf("found %d disjoint bidirectional routes, at least %d required", len(reqs), minRts)
 	}
 
-	rules, err := r.conf.RouteGroupDialer.Dial(ctx, r.logger, r.n, r.conf.SetupNodes, req)
+	rules, setupPK, err := r.dialRouteGroup(ctx, reqs[0])
 	if err != nil {
 		r.logger.WithError(err).Error("Error dialing route group")
-		return nil, err
+		return nil, policyRejection(err)
 	}
 
 	if err := r.SaveRoutingRules(rules.Forward, rules.Reverse); err != nil {
@@ -245,11 +315,39 @@ func (r *router) DialRoutes(
 		Initiator: true,
 	}
 
-	nrg, err := r.saveRouteGroupRules(rules, nsConf)
+	rgConf := DefaultRouteGroupConfig()
+	rgConf.WritePolicy = opts.WritePolicy
+	rgConf.Reliable = opts.Reliable && !opts.Datagram
+	rgConf.FlowControl = !opts.Datagram
+	rgConf.Datagram = opts.Datagram
+	rgConf.Preferences = opts.Preferences
+
+	nrg, err := r.saveRouteGroupRules(rules, nsConf, rgConf)
 	if err != nil {
 		return nil, fmt.Errorf("saveRouteGroupRules: %w", err)
 	}
 
+	nrg.rg.setSetupNode(setupPK)
+	nrg.rg.setRoutePath(rules.Reverse.KeyRouteID(), reqs[0])
+
+	// the rest of the routes are set up once the route group is established,
+	// so the remote attaches them to the already accepted route group
+	if len(reqs) > 1 {
+		if nrg.rg.remoteSupports(routing.HandshakeMultiRoute) {
+			r.dialExtraRoutes(ctx, nrg.rg, reqs[1:])
+		} else {
+			r.logger.Warnf("Remote %s doesn't support multiple routes per route group, using a single one", rPK)
+		}
+	}
+
+	if minRts := opts.minRoutes(); nrg.rg.routesCount() < minRts {
+		if err := nrg.Close(); err != nil {
+			r.logger.WithError(err).Warn("Failed to close route group")
+		}
+
+		return nil, fmt.Errorf("established %d routes, at least %d required", nrg.rg.routesCount(), minRts)
+	}
+
 	nrg.rg.startOffServiceLoops()
 
 	r.logger.Infof("Created new routes to %s on port %d", rPK, lPort)
@@ -257,6 +355,28 @@ func (r *router) DialRoutes(
 	return nrg, nil
 }
 
+// dialExtraRoutes sets up additional routes of the already established route group, remote attaches
+// them to the route group as they are marked. Routes which fail to be set up are skipped.
+func (r *router) dialExtraRoutes(ctx context.Context, rg *RouteGroup, reqs []routing.BidirectionalRoute) {
+	for _, req := range reqs {
+		req.Attach = true
+
+		rules, _, err := r.dialRouteGroup(ctx, req)
+		if err != nil {
+			r.logger.WithError(err).Warnf("Failed to dial extra route for route group %s", &rg.desc)
+			continue
+		}
+
+		if err := r.SaveRoutingRules(rules.Forward, rules.Reverse); err != nil {
+			r.logger.WithError(err).Warnf("Failed to save rules of extra route for route group %s", &rg.desc)
+			continue
+		}
+
+		rg.appendRules(rules, r.tm.Writer(rules.Forward.NextTransportID()))
+		rg.setRoutePath(rules.Reverse.KeyRouteID(), req)
+	}
+}
+
 // AcceptsRoutes should block until we receive an AddRules packet from SetupNode
 // that contains ConsumeRule(s) or ForwardRule(s).
 // Then the following should happen:
@@ -296,7 +416,11 @@ func (r *router) AcceptRoutes(ctx context.Context) (net.Conn, error) {
 		Initiator: false,
 	}
 
-	nrg, err := r.saveRouteGroupRules(rules, nsConf)
+	// reliable delivery is only used if the initiator requests it.
+	rgConf := DefaultRouteGroupConfig()
+	rgConf.Reliable = true
+
+	nrg, err := r.saveRouteGroupRules(rules, nsConf, rgConf)
 	if err != nil {
 		return nil, fmt.Errorf("saveRouteGroupRules: %w", err)
 	}
@@ -368,11 +492,27 @@ func (r *router) serveSetup() {
 
 		r.logger.Infof("handling setup request: setupPK(%s)", conn.RemotePK())
 
-		go r.rpcSrv.ServeConn(conn)
+		go r.serveRPC(conn)
+	}
+}
+
+// serveRPC serves the router RPC on `conn` within a session of its own, see RPCGateway.
+func (r *router) serveRPC(conn io.ReadWriteCloser) {
+	srv := rpc.NewServer()
+	if err := srv.Register(NewRPCGateway(r)); err != nil {
+		r.logger.WithError(err).Error("Failed to register RPC gateway.")
+
+		if err := conn.Close(); err != nil {
+			r.logger.WithError(err).Warn("Failed to close RPC connection.")
+		}
+
+		return
 	}
+
+	srv.ServeConn(conn)
 }
 
-func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Config) (*NoiseRouteGroup, error) {
+func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Config, rgConf *RouteGroupConfig) (*NoiseRouteGroup, error) {
 	r.logger.Infof("Saving route group rules with desc: %s", &rules.Desc)
 
 	// When route group is wrapped with noise, it's put into `nrgs`. but before that,
@@ -393,8 +533,8 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 	nrg, ok := r.rgsNs[rules.Desc]
 
 	r.logger.Infof("Creating new route group rule with desc: %s", &rules.Desc)
-	rg := NewRouteGroup(DefaultRouteGroupConfig(), r.rt, rules.Desc)
-	rg.appendRules(rules.Forward, rules.Reverse, r.tm.Transport(rules.Forward.NextTransportID()))
+	rg := NewRouteGroup(rgConf, r.rt, rules.Desc)
+	rg.appendRules(rules, r.tm.Writer(rules.Forward.NextTransportID()))
 	// we put raw rg so it can be accessible to the router when handshake packets come in
 	r.rgsRaw[rules.Desc] = rg
 	r.mx.Unlock()
@@ -426,6 +566,16 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 		})
 	}
 
+	// remote which doesn't echo the datagram flag would mix up message boundaries
+	if nsConf.Initiator && rg.isDatagram() && !rg.remoteSupports(routing.HandshakeDatagram) {
+		r.logger.Errorf("Remote of route group (%s) doesn't support datagrams, closing...", &rules.Desc)
+		if err := rg.Close(); err != nil {
+			r.logger.WithError(err).Errorf("Failed to close route group (%s): %v", &rules.Desc, err)
+		}
+
+		return nil, fmt.Errorf("route group (%s): %w", &rules.Desc, ErrDatagramNotSupported)
+	}
+
 	if !nsConf.Initiator {
 		if err := rg.sendHandshake(true); err != nil {
 			r.logger.WithError(err).Errorf("Failed to send handshake from route group (%s): %v, closing...",
@@ -450,8 +600,16 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 	}
 
 	if rg.encrypt {
-		// wrapping rg with noise
-		wrappedRG, err := noisewrapper.WrapConn(nsConf, rg)
+		// wrapping rg with noise, datagrams are encrypted one by one to keep their boundaries
+		var (
+			wrappedRG net.Conn
+			err       error
+		)
+		if rg.isDatagram() {
+			wrappedRG, err = wrapDatagramConn(nsConf, rg)
+		} else {
+			wrappedRG, err = noisewrapper.WrapConn(nsConf, rg)
+		}
 		if err != nil {
 			r.logger.WithError(err).Errorf("Failed to wrap route group (%s): %v, closing...", &rules.Desc, err)
 			if err := rg.Close(); err != nil {
@@ -483,7 +641,7 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 
 func (r *router) handleTransportPacket(ctx context.Context, packet routing.Packet) error {
 	switch packet.Type() {
-	case routing.DataPacket, routing.HandshakePacket:
+	case routing.DataPacket, routing.HandshakePacket, routing.CreditPacket:
 		return r.handleDataHandshakePacket(ctx, packet)
 	case routing.ClosePacket:
 		return r.handleClosePacket(ctx, packet)
@@ -491,6 +649,8 @@ func (r *router) handleTransportPacket(ctx context.Context, packet routing.Packe
 		return r.handleKeepAlivePacket(ctx, packet)
 	case routing.NetworkProbePacket:
 		return r.handleNetworkProbePacket(ctx, packet)
+	case routing.TracePacket:
+		return r.handleTracePacket(ctx, packet)
 	default:
 		return ErrUnknownPacketType
 	}
@@ -585,17 +745,33 @@ func (r *router) handleClosePacket(ctx context.Context, packet routing.Packet) e
 		return errors.New("route descriptor does not exist")
 	}
 
-	defer r.removeNoiseRouteGroup(desc)
-
 	if nrg == nil {
+		r.removeNoiseRouteGroup(desc)
 		return errors.New("noiseRouteGroup is nil")
 	}
 
+	closeCode := routing.CloseCode(packet.Payload()[0])
+
+	// route group with several routes keeps working without the one expired on an intermediary
+	if closeCode == routing.CloseRuleExpired && nrg.rg.routesCount() > 1 {
+		if fwd, ok := nrg.rg.removeRoute(routeID); ok {
+			r.rt.DelRules([]routing.RouteID{fwd.KeyRouteID()})
+			r.logger.Debugf("Removed route expired on intermediary from route group with descriptor %s", &desc)
+
+			return nil
+		}
+	}
+
+	defer func() {
+		// with multiple routes, close responses keep coming via the rest of them
+		if !nrg.rg.awaitsCloseResponses() {
+			r.removeNoiseRouteGroup(desc)
+		}
+	}()
+
 	r.logger.Debugf("Got new remote close packet with size %d and route ID %d. Using rule: %s",
 		len(packet.Payload()), packet.RouteID(), rule)
 
-	closeCode := routing.CloseCode(packet.Payload()[0])
-
 	if nrg.isClosed() {
 		return io.ErrClosedPipe
 	}
@@ -608,6 +784,29 @@ func (r *router) handleClosePacket(ctx context.Context, packet routing.Packet) e
 	return nil
 }
 
+func (r *router) handleTracePacket(ctx context.Context, packet routing.Packet) error {
+	rule, err := r.GetRule(packet.RouteID())
+	if err != nil {
+		return err
+	}
+
+	if rt := rule.Type(); rt == routing.RuleForward || rt == routing.RuleIntermediary {
+		r.logger.Debugf("Handling packet of type %s with route ID %d and next ID %d", packet.Type(),
+			packet.RouteID(), rule.NextRouteID())
+		return r.forwardPacket(ctx, packet, rule)
+	}
+
+	desc := rule.RouteDescriptor()
+
+	nrg, ok := r.noiseRouteGroup(desc)
+	if !ok || nrg == nil {
+		r.logger.Infof("Descriptor not found for rule with type %s, descriptor: %s", rule.Type(), &desc)
+		return errors.New("route descriptor does not exist")
+	}
+
+	return nrg.handlePacket(packet)
+}
+
 func (r *router) handleNetworkProbePacket(ctx context.Context, packet routing.Packet) error {
 	rule, err := r.GetRule(packet.RouteID())
 	if err != nil {
@@ -686,6 +885,11 @@ func (r *router) handleKeepAlivePacket(ctx context.Context, packet routing.Packe
 
 	r.logger.Debugf("Route ID %v found, updated activity", routeID)
 
+	// route group keeps track of the activity of each of its routes
+	if nrg, ok := r.noiseRouteGroup(rule.RouteDescriptor()); ok && nrg != nil {
+		return nrg.handlePacket(packet)
+	}
+
 	return nil
 }
 
@@ -719,6 +923,27 @@ func (r *router) UpdateRuleActivity(routeID routing.RouteID) error {
 	return nil
 }
 
+// TraceRoute implements Router.
+func (r *router) TraceRoute(ctx context.Context, rid routing.RouteID) ([]routing.TraceHop, error) {
+	rule, err := r.rt.Rule(rid)
+	if err != nil {
+		return nil, err
+	}
+
+	if t := rule.Type(); t != routing.RuleReverse {
+		return nil, fmt.Errorf("rule of type %s is not a consume rule of a route group", t)
+	}
+
+	desc := rule.RouteDescriptor()
+
+	nrg, ok := r.noiseRouteGroup(desc)
+	if !ok || nrg == nil {
+		return nil, fmt.Errorf("no route group of descriptor %s", &desc)
+	}
+
+	return nrg.rg.trace(ctx)
+}
+
 // Close safely stops Router.
 func (r *router) Close() error {
 	if r == nil {
@@ -744,8 +969,29 @@ func (r *router) Close() error {
 	return r.tm.Close()
 }
 
-func (r *router) forwardPacket(ctx context.Context, packet routing.Packet, rule routing.Rule) error {
-	tp := r.tm.Transport(rule.NextTransportID())
+// forwardPacket forwards `packet` according to `rule`. Forwarding statistics of the rule are updated
+// with the forwarded packet or with the error.
+func (r *router) forwardPacket(ctx context.Context, packet routing.Packet, rule routing.Rule) (err error) {
+	var size int
+	defer func() {
+		r.rt.RecordForward(rule.KeyRouteID(), size, err)
+	}()
+
+	// packets bouncing between visors due to bad rules are dropped eventually,
+	// packet of hop limit N is forwarded N times at most.
+	hopLimit := packet.HopLimit()
+	if hopLimit == 0 {
+		r.logger.
+			WithField("rule", rule.KeyRouteID()).
+			WithField("tp_id", rule.NextTransportID()).
+			WithField("packet_type", packet.Type()).
+			Warn("Dropping packet which reached its hop limit, routing rules may contain a loop.")
+
+		return ErrHopLimitReached
+	}
+
+	// rules may reference bonds, packets are balanced over their transports then
+	tp := r.tm.Writer(rule.NextTransportID())
 	if tp == nil {
 		return errors.New("unknown transport")
 	}
@@ -766,23 +1012,41 @@ func (r *router) forwardPacket(ctx context.Context, packet routing.Packet, rule
 		if b == 0 {
 			supportEncryptionVal = false
 		}
-		p = routing.MakeHandshakePacket(rule.NextRouteID(), supportEncryptionVal)
+		p = routing.MakeHandshakePacket(rule.NextRouteID(), supportEncryptionVal, packet.HandshakeFlags(),
+			packet.HandshakeWindow())
 	case routing.NetworkProbePacket:
 		timestamp := int64(binary.BigEndian.Uint64(packet[routing.PacketPayloadOffset:]))
 		throughput := int64(binary.BigEndian.Uint64(packet[routing.PacketPayloadOffset+8:]))
 		p = routing.MakeNetworkProbePacket(rule.NextRouteID(), timestamp, throughput)
 	case routing.KeepAlivePacket:
 		p = routing.MakeKeepAlivePacket(rule.NextRouteID())
+	case routing.CreditPacket:
+		p = routing.MakeCreditPacket(rule.NextRouteID(), packet.Credit())
 	case routing.ClosePacket:
 		p = routing.MakeClosePacket(rule.NextRouteID(), routing.CloseCode(packet.Payload()[0]))
+	case routing.TracePacket:
+		traceID, returning, hops, err := packet.Trace()
+		if err != nil {
+			return err
+		}
+
+		hops = append(hops, routing.TraceHop{PK: r.conf.PubKey, Time: time.Now()})
+
+		if p, err = routing.MakeTracePacket(rule.NextRouteID(), traceID, returning, hops); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("packet of type %s can't be forwarded", packet.Type())
 	}
 
+	p.SetHopLimit(hopLimit - 1)
+
 	if err := tp.WritePacket(ctx, p); err != nil {
 		return err
 	}
 
+	size = int(p.Size())
+
 	// successfully forwarded packet, may update the rule activity now
 	if err := r.UpdateRuleActivity(rule.KeyRouteID()); err != nil {
 		r.logger.Errorf("Failed to update activity for rule with route ID %d: %v", rule.KeyRouteID(), err)
@@ -809,10 +1073,11 @@ func (r *router) RemoveRouteDescriptor(desc routing.RouteDescriptor) {
 	}
 }
 
-func (r *router) fetchBestRoutes(src, dst cipher.PubKey, opts *DialOptions) (fwd, rev []routing.Hop, err error) {
-	// TODO: use opts
+// fetchBestRoutes fetches forward and reverse paths between 'src' and 'dst'.
+// The number of returned paths is limited by 'opts', paths are mutually disjoint.
+func (r *router) fetchBestRoutes(src, dst cipher.PubKey, opts *DialOptions) (fwd, rev [][]routing.Hop, err error) {
 	if opts == nil {
-		opts = DefaultDialOptions() // nolint
+		opts = DefaultDialOptions()
 	}
 
 	r.logger.Infof("Requesting new routes from %s to %s", src, dst)
@@ -827,7 +1092,7 @@ fetchRoutesAgain:
 	ctx := context.Background()
 
 	paths, err := r.conf.RouteFinder.FindRoutes(ctx, []routing.PathEdges{forward, backward},
-		&rfclient.RouteOptions{MinHops: minHops, MaxHops: maxHops})
+		&rfclient.RouteOptions{MinHops: minHops, MaxHops: maxHops, RoutePreferences: opts.Preferences})
 
 	if err == rfclient.ErrTransportNotFound {
 		return nil, nil, err
@@ -845,15 +1110,180 @@ fetchRoutesAgain:
 
 	r.logger.Infof("Found routes Forward: %s. Reverse %s", paths[forward], paths[backward])
 
-	return paths[forward][0], paths[backward][0], nil
+	// route finder may not support all of the preferences, nor know the local probe stats
+	// transports of exceeded data caps can't be used for new routes
+	fwdPaths := r.uncappedPaths(r.directSetupPaths(opts.Preferences.FilterPaths(paths[forward])))
+	opts.Preferences.SortPaths(fwdPaths, r.transportWeight)
+	fwd = disjointPaths(fwdPaths, opts.MaxForwardRts)
+	if len(fwd) == 0 || len(fwd) < opts.MinForwardRts {
+		return nil, nil, fmt.Errorf("found %d disjoint forward routes, at least %d required",
+			len(fwd), opts.MinForwardRts)
+	}
+
+	revPaths := r.uncappedPaths(r.directSetupPaths(opts.Preferences.FilterPaths(paths[backward])))
+	opts.Preferences.SortPaths(revPaths, r.transportWeight)
+	rev = disjointPaths(revPaths, opts.MaxConsumeRts)
+	if len(rev) == 0 || len(rev) < opts.MinConsumeRts {
+		return nil, nil, fmt.Errorf("found %d disjoint reverse routes, at least %d required",
+			len(rev), opts.MinConsumeRts)
+	}
+
+	return fwd, rev, nil
+}
+
+// uncappedPaths returns the paths of `paths` which don't go through local transports of exceeded data caps.
+func (r *router) uncappedPaths(paths [][]routing.Hop) [][]routing.Hop {
+	res := make([][]routing.Hop, 0, len(paths))
+
+paths:
+	for _, path := range paths {
+		for _, hop := range path {
+			if r.transportCapped(hop.TpID) {
+				continue paths
+			}
+		}
+
+		res = append(res, path)
+	}
+
+	return res
+}
+
+// transportCapped checks whether a data cap of transport of `tpID` is exceeded.
+func (r *router) transportCapped(tpID uuid.UUID) bool {
+	tp := r.tm.Transport(tpID)
+	return tp != nil && tp.DataCapExceeded()
+}
+
+// transportWeight returns the weight of the local transport of `tpID` measured by the probes.
+func (r *router) transportWeight(tpID uuid.UUID) (time.Duration, bool) {
+	tp := r.tm.Transport(tpID)
+	if tp == nil {
+		return 0, false
+	}
+
+	stats := tp.ProbeStats()
+	if stats.Samples == 0 {
+		return 0, false
+	}
+
+	return stats.Weight(), true
+}
+
+// disjointPaths picks up to 'max' paths out of 'paths' (preserving the order) so that
+// no two picked paths share a transport or an intermediary visor.
+func disjointPaths(paths [][]routing.Hop, max int) [][]routing.Hop {
+	if max < 1 {
+		max = 1
+	}
+
+	usedTps := make(map[uuid.UUID]struct{})
+	usedPKs := make(map[cipher.PubKey]struct{})
+
+	res := make([][]routing.Hop, 0, max)
+
+	for _, path := range paths {
+		if len(res) == max {
+			break
+		}
+
+		if len(path) == 0 || !pathIsDisjoint(path, usedTps, usedPKs) {
+			continue
+		}
+
+		for i, hop := range path {
+			usedTps[hop.TpID] = struct{}{}
+			if i > 0 {
+				usedPKs[hop.From] = struct{}{}
+			}
+		}
+
+		res = append(res, path)
+	}
+
+	return res
+}
+
+func pathIsDisjoint(path []routing.Hop, usedTps map[uuid.UUID]struct{}, usedPKs map[cipher.PubKey]struct{}) bool {
+	for i, hop := range path {
+		if _, ok := usedTps[hop.TpID]; ok {
+			return false
+		}
+
+		if _, ok := usedPKs[hop.From]; ok && i > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// policyRejections are the errors of visors and setup nodes refusing routes by their policy.
+var policyRejections = []error{
+	setupclient.ErrRequestRejected,
+	transport.ErrDataCapExceeded,
+	transport.ErrTransportRejected,
+}
+
+// policyRejection wraps `err` with '*routing.CloseError' of ClosePolicyRejected code if `err`
+// is a policy rejection, so apps get it typed. Rejections of remote visors and setup nodes come
+// over RPC as text, so they're recognized by their messages.
+func policyRejection(err error) error {
+	for _, rejection := range policyRejections {
+		if errors.Is(err, rejection) || strings.Contains(err.Error(), rejection.Error()) {
+			return fmt.Errorf("%w: %v", &routing.CloseError{Code: routing.ClosePolicyRejected}, err)
+		}
+	}
+
+	return err
 }
 
-// SetupIsTrusted checks if setup node is trusted.
+// bidirectionalRoutes pairs forward and reverse paths into bidirectional routes. Paths are not reused,
+// so the routes stay disjoint in both directions, the number of routes is limited by the direction
+// with fewer paths.
+func bidirectionalRoutes(desc routing.RouteDescriptor, fwd, rev [][]routing.Hop) []routing.BidirectionalRoute {
+	n := len(fwd)
+	if len(rev) < n {
+		n = len(rev)
+	}
+
+	routes := make([]routing.BidirectionalRoute, 0, n)
+	for i := 0; i < n; i++ {
+		routes = append(routes, routing.BidirectionalRoute{
+			Desc:      desc,
+			KeepAlive: DefaultRouteKeepAlive,
+			Forward:   fwd[i],
+			Reverse:   rev[i],
+		})
+	}
+
+	return routes
+}
+
+// SetupIsTrusted checks if setup node or visor is trusted to set routes up via the router.
 func (r *router) SetupIsTrusted(sPK cipher.PubKey) bool {
 	_, ok := r.trustedVisors[sPK]
 	return ok
 }
 
+// IsSetupNode checks if `pk` is one of the setup nodes of the router.
+func (r *router) IsSetupNode(pk cipher.PubKey) bool {
+	_, ok := r.setupNodes[pk]
+	return ok
+}
+
+// CheckRules implements Router.
+func (r *router) CheckRules(rules ...routing.Rule) error {
+	for _, rule := range rules {
+		t := rule.Type()
+		if (t == routing.RuleForward || t == routing.RuleIntermediary) && r.transportCapped(rule.NextTransportID()) {
+			return fmt.Errorf("rule %d: %w", rule.KeyRouteID(), transport.ErrDataCapExceeded)
+		}
+	}
+
+	return nil
+}
+
 // Saves `rules` to the routing table.
 func (r *router) SaveRoutingRules(rules ...routing.Rule) error {
 	for _, rule := range rules {
@@ -877,6 +1307,21 @@ func (r *router) ReserveKeys(n int) ([]routing.RouteID, error) {
 	return ids, err
 }
 
+// ReleaseKeys releases route IDs which were reserved but aren't used.
+func (r *router) ReleaseKeys(ids []routing.RouteID) {
+	r.rt.ReleaseKeys(ids)
+}
+
+// RouteGroupSetupNode implements Router.
+func (r *router) RouteGroupSetupNode(desc routing.RouteDescriptor) (cipher.PubKey, bool) {
+	nrg, ok := r.noiseRouteGroup(desc)
+	if !ok || nrg == nil {
+		return cipher.PubKey{}, false
+	}
+
+	return nrg.rg.setupNode()
+}
+
 func (r *router) popNoiseRouteGroup(desc routing.RouteDescriptor) (*NoiseRouteGroup, bool) {
 	r.mx.Lock()
 	defer r.mx.Unlock()
@@ -909,6 +1354,45 @@ func (r *router) initializingRouteGroup(desc routing.RouteDescriptor) (*RouteGro
 	return rg, ok
 }
 
+// liveRouteGroup returns an alive route group (either initializing or noise-wrapped) with the `desc`.
+// NOTE: not thread-safe.
+func (r *router) liveRouteGroup(desc routing.RouteDescriptor) *RouteGroup {
+	if rg, ok := r.rgsRaw[desc]; ok && rg != nil && rg.IsAlive() {
+		return rg
+	}
+
+	if nrg, ok := r.rgsNs[desc]; ok && nrg != nil && nrg.IsAlive() {
+		return nrg.rg
+	}
+
+	return nil
+}
+
+// attachRules saves `rules` and attaches them to `rg`. The route which is repaired by remote gets
+// replaced, if it's already gone, or if the rules don't replace any, they are appended as one more route.
+func (r *router) attachRules(rg *RouteGroup, rules routing.EdgeRules) error {
+	if err := r.SaveRoutingRules(rules.Forward, rules.Reverse); err != nil {
+		return err
+	}
+
+	tp := r.tm.Writer(rules.Forward.NextTransportID())
+
+	if rules.Replaces != 0 {
+		if oldFwd, oldRvs, ok := rg.replaceRoute(rules.Replaces, rules, tp); ok {
+			r.logger.Infof("Replacing route %d of route group with desc: %s", rules.Replaces, &rules.Desc)
+			r.rt.DelRules([]routing.RouteID{oldFwd.KeyRouteID(), oldRvs.KeyRouteID()})
+
+			return nil
+		}
+	}
+
+	r.logger.Infof("Attaching extra route to route group with desc: %s", &rules.Desc)
+
+	rg.appendRules(rules, tp)
+
+	return nil
+}
+
 func (r *router) removeNoiseRouteGroup(desc routing.RouteDescriptor) {
 	r.mx.Lock()
 	defer r.mx.Unlock()
@@ -924,6 +1408,17 @@ func (r *router) IntroduceRules(rules routing.EdgeRules) error {
 		r.mx.Lock()
 		defer r.mx.Unlock()
 
+		// rules of an extra route of the already existing route group are attached to it straight away,
+		// the rest are accepted as a new route group which replaces the existing one, if any
+		if rules.Attach {
+			rg := r.liveRouteGroup(rules.Desc)
+			if rg == nil {
+				return fmt.Errorf("no route group with desc %s to attach route to", &rules.Desc)
+			}
+
+			return r.attachRules(rg, rules)
+		}
+
 		select {
 		case r.accept <- rules:
 			return nil
@@ -948,6 +1443,11 @@ func (r *router) Rule(id routing.RouteID) (routing.Rule, error) {
 	return r.rt.Rule(id)
 }
 
+// RuleStats returns forwarding statistics of the rule with `id` key.
+func (r *router) RuleStats(id routing.RouteID) routing.RuleStats {
+	return r.rt.RuleStats(id)
+}
+
 // SaveRule stores the `rule` within the routing table.
 func (r *router) SaveRule(rule routing.Rule) error {
 	return r.rt.SaveRule(rule)
@@ -969,7 +1469,7 @@ func (r *router) DelRules(ids []routing.RouteID) {
 	r.rt.DelRules(ids)
 
 	for _, rule := range rules {
-		r.removeRouteGroupOfRule(rule)
+		r.removeRouteGroupOfRule(rule, routing.CloseRequested)
 	}
 }
 
@@ -995,11 +1495,32 @@ func (r *router) rulesGC() {
 		Debug("Removed rules.")
 
 	for _, rule := range removedRules {
-		r.removeRouteGroupOfRule(rule)
+		if rule.Type() == routing.RuleIntermediary {
+			r.sendRuleExpired(rule)
+			continue
+		}
+
+		r.removeRouteGroupOfRule(rule, routing.CloseKeepAliveTimeout)
+	}
+}
+
+// sendRuleExpired lets the visors along the expired intermediary `rule` know that the route is gone.
+// Close packet makes its way to the route group edge, which closes the route group or drops the route.
+func (r *router) sendRuleExpired(rule routing.Rule) {
+	tp := r.tm.Writer(rule.NextTransportID())
+	if tp == nil {
+		return
+	}
+
+	packet := routing.MakeClosePacket(rule.NextRouteID(), routing.CloseRuleExpired)
+	if err := tp.WritePacket(context.Background(), packet); err != nil {
+		r.logger.WithError(err).Debugf("Failed to send close packet for expired rule with route ID %d",
+			rule.KeyRouteID())
 	}
 }
 
-func (r *router) removeRouteGroupOfRule(rule routing.Rule) {
+// removeRouteGroupOfRule closes the route group of the removed consume `rule` with the close `code`.
+func (r *router) removeRouteGroupOfRule(rule routing.Rule, code routing.CloseCode) {
 	log := r.logger.
 		WithField("func", "router.removeRouteGroupOfRule").
 		WithField("rule_type", rule.Type().String()).
@@ -1018,6 +1539,18 @@ func (r *router) removeRouteGroupOfRule(rule routing.Rule) {
 	}
 
 	rDesc := rule.RouteDescriptor()
+
+	// route group with several routes keeps working without the one of the removed rule
+	if nrg, ok := r.noiseRouteGroup(rDesc); ok && nrg != nil && nrg.rg.routesCount() > 1 {
+		if fwd, ok := nrg.rg.removeRoute(rule.KeyRouteID()); ok {
+			r.rt.DelRules([]routing.RouteID{fwd.KeyRouteID()})
+			log.WithField("rt_desc", rDesc.String()).
+				Debug("Removed route associated with rule from noise route group.")
+
+			return
+		}
+	}
+
 	log.WithField("rt_desc", rDesc.String()).
 		Debug("Closing noise route group associated with rule...")
 
@@ -1030,9 +1563,9 @@ func (r *router) removeRouteGroupOfRule(rule routing.Rule) {
 		log.Debug("Noise route group already closed. Nothing to be done.")
 		return
 	}
-	if err := nrg.Close(); err != nil {
+	if err := nrg.CloseWithCode(code); err != nil {
 		log.WithError(err).Error("Failed to close noise route group.")
 		return
 	}
-	log.Debug("Noise route group closed.")
+	log.WithField("close_code", code.String()).Debug("Noise route group closed.")
 }
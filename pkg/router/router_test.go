@@ -2,9 +2,11 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/rpc"
 	"os"
 	"sync"
 	"testing"
@@ -21,6 +23,7 @@ import (
 
 	"github.com/skycoin/skywire/internal/testhelpers"
 	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
+	"github.com/skycoin/skywire/pkg/router/routerclient"
 	"github.com/skycoin/skywire/pkg/routing"
 	"github.com/skycoin/skywire/pkg/setup/setupclient"
 	"github.com/skycoin/skywire/pkg/snet"
@@ -105,7 +108,7 @@ func Test_router_NoiseRouteGroups(t *testing.T) {
 
 	setupCl0 := &setupclient.MockRouteGroupDialer{}
 	setupCl0.On("Dial", mock.Anything, r0Logger, nEnv.Nets[0], mock.Anything, route).
-		Return(initEdge, testhelpers.NoErr)
+		Return(initEdge, cipher.PubKey{}, testhelpers.NoErr)
 
 	r0Conf := &Config{
 		Logger:           r0Logger,
@@ -415,7 +418,7 @@ func testClosePacketRemote(t *testing.T, r0, r1 *router, pk1, pk2 cipher.PubKey,
 	}
 
 	rg1 := NewRouteGroup(DefaultRouteGroupConfig(), r1.rt, rules.Desc)
-	rg1.appendRules(rules.Forward, rules.Reverse, r1.tm.Transport(rules.Forward.NextTransportID()))
+	rg1.appendRules(rules, r1.tm.Writer(rules.Forward.NextTransportID()))
 
 	nrg1 := &NoiseRouteGroup{rg: rg1}
 	r1.rgsNs[rg1.desc] = nrg1
@@ -476,7 +479,7 @@ func testClosePacketInitiator(t *testing.T, r0, r1 *router, pk1, pk2 cipher.PubK
 	}
 
 	rg1 := NewRouteGroup(DefaultRouteGroupConfig(), r1.rt, rules.Desc)
-	rg1.appendRules(rules.Forward, rules.Reverse, r1.tm.Transport(rules.Forward.NextTransportID()))
+	rg1.appendRules(rules, r1.tm.Writer(rules.Forward.NextTransportID()))
 
 	nrg1 := &NoiseRouteGroup{rg: rg1}
 	r1.rgsNs[rg1.desc] = nrg1
@@ -523,7 +526,7 @@ func testForwardRule(t *testing.T, r0, r1 *router, tp1 *transport.ManagedTranspo
 
 	rules := routing.EdgeRules{Desc: fwdRule.RouteDescriptor(), Forward: fwdRule, Reverse: nil}
 	rg0 := NewRouteGroup(DefaultRouteGroupConfig(), r0.rt, rules.Desc)
-	rg0.appendRules(rules.Forward, rules.Reverse, r0.tm.Transport(rules.Forward.NextTransportID()))
+	rg0.appendRules(rules, r0.tm.Writer(rules.Forward.NextTransportID()))
 
 	nrg0 := &NoiseRouteGroup{rg: rg0}
 	r0.rgsNs[rg0.desc] = nrg0
@@ -602,7 +605,7 @@ func testConsumeRule(t *testing.T, r0, r1 *router, tp1 *transport.ManagedTranspo
 	}
 
 	rg1 := NewRouteGroup(DefaultRouteGroupConfig(), r1.rt, rules.Desc)
-	rg1.appendRules(rules.Forward, rules.Reverse, r1.tm.Transport(rules.Forward.NextTransportID()))
+	rg1.appendRules(rules, r1.tm.Writer(rules.Forward.NextTransportID()))
 
 	nrg1 := &NoiseRouteGroup{rg: rg1}
 	r1.rgsNs[rg1.desc] = nrg1
@@ -730,7 +733,7 @@ func testGetRule(t *testing.T, r *router, rt routing.Table) {
 }
 
 func TestRouter_SetupIsTrusted(t *testing.T) {
-	keys := snettest.GenKeyPairs(2)
+	keys := snettest.GenKeyPairs(3)
 
 	nEnv := snettest.NewEnv(t, keys, []string{dmsg.Type})
 	defer nEnv.Teardown()
@@ -740,12 +743,302 @@ func TestRouter_SetupIsTrusted(t *testing.T) {
 
 	routerConfig := rEnv.GenRouterConfig(0)
 	routerConfig.SetupNodes = append(routerConfig.SetupNodes, keys[0].PK)
+	routerConfig.RouteSetupTrusted = append(routerConfig.RouteSetupTrusted, keys[2].PK)
 
 	r0, err := New(nEnv.Nets[0], routerConfig)
 	require.NoError(t, err)
 
 	assert.True(t, r0.SetupIsTrusted(keys[0].PK))
 	assert.False(t, r0.SetupIsTrusted(keys[1].PK))
+	assert.True(t, r0.SetupIsTrusted(keys[2].PK))
+
+	// visors trusted to set routes up aren't setup nodes
+	assert.True(t, r0.IsSetupNode(keys[0].PK))
+	assert.False(t, r0.IsSetupNode(keys[2].PK))
+}
+
+func TestRouter_forwardPacket_hopLimit(t *testing.T) {
+	keys := snettest.GenKeyPairs(2)
+
+	nEnv := snettest.NewEnv(t, keys, []string{dmsg.Type})
+	defer nEnv.Teardown()
+
+	m0, m1, tp0, _, err := transport.CreateTransportPair(transport.NewDiscoveryMock(), keys, nEnv, dmsg.Type)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, m0.Close())
+		require.NoError(t, m1.Close())
+	}()
+
+	r := &router{logger: logging.MustGetLogger("router"), tm: m0, rt: routing.NewTable()}
+
+	// rule forwards the packets back to itself, as if rules contained a loop
+	rule := routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 1, 1, tp0.Entry.ID)
+	require.NoError(t, r.rt.SaveRule(rule))
+
+	const hopLimit = 5
+
+	packet, err := routing.MakeDataPacket(1, []byte("foo"))
+	require.NoError(t, err)
+	packet.SetHopLimit(hopLimit)
+
+	hops := 0
+	for ; hops <= hopLimit; hops++ {
+		if err := r.forwardPacket(context.TODO(), packet, rule); err != nil {
+			require.Equal(t, ErrHopLimitReached, err)
+			break
+		}
+
+		packet, err = m1.ReadPacket()
+		require.NoError(t, err)
+		require.Equal(t, uint8(hopLimit-hops-1), packet.HopLimit())
+	}
+
+	require.Equal(t, hopLimit, hops)
+
+	stats := r.RuleStats(rule.KeyRouteID())
+	require.Equal(t, uint64(hopLimit), stats.Packets)
+	require.Equal(t, uint64(hopLimit*len("foo")), stats.Bytes, "only payload is counted, as in transport logs")
+	require.Equal(t, ErrHopLimitReached.Error(), stats.LastError)
+}
+
+func TestRouter_IntroduceRules(t *testing.T) {
+	tm, err := transport.NewManager(nil, nil, &transport.ManagerConfig{})
+	require.NoError(t, err)
+
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+	pk1, pk2 := rg.desc.SrcPK(), rg.desc.DstPK()
+	tpID := uuid.New()
+
+	r := &router{
+		logger: logging.MustGetLogger("router"),
+		tm:     tm,
+		rt:     rg.rt,
+		rgsNs:  map[routing.RouteDescriptor]*NoiseRouteGroup{rg.desc: {rg: rg, Conn: rg}},
+		rgsRaw: make(map[routing.RouteDescriptor]*RouteGroup),
+		accept: make(chan routing.EdgeRules, 1),
+		done:   make(chan struct{}),
+	}
+
+	makeRules := func(fwdID, rvsID routing.RouteID) routing.EdgeRules {
+		return routing.EdgeRules{
+			Desc:            rg.desc,
+			Forward:         routing.ForwardRule(ruleKeepAlive, fwdID, fwdID+10, tpID, pk1, pk2, 0, 0),
+			Reverse:         routing.ConsumeRule(ruleKeepAlive, rvsID, pk1, pk2, 0, 0),
+			RemoteReverseID: rvsID + 20,
+		}
+	}
+
+	initial := makeRules(1, 2)
+	require.NoError(t, r.SaveRoutingRules(initial.Forward, initial.Reverse))
+	rg.appendRules(initial, nil)
+
+	// rules which aren't marked make a new route group, even if the descriptor is in use
+	require.NoError(t, r.IntroduceRules(makeRules(3, 4)))
+	require.Equal(t, routing.RouteID(3), (<-r.accept).Forward.KeyRouteID())
+	require.Equal(t, 1, rg.routesCount())
+
+	// extra route is appended
+	extra := makeRules(5, 6)
+	extra.Attach = true
+	require.NoError(t, r.IntroduceRules(extra))
+	require.Equal(t, 2, rg.routesCount())
+
+	// repaired route replaces the route it's marked to replace
+	repaired := makeRules(7, 8)
+	repaired.Attach = true
+	repaired.Replaces = 2
+	require.NoError(t, r.IntroduceRules(repaired))
+	require.Equal(t, 2, rg.routesCount())
+	require.Equal(t, []routing.RouteID{28, 26}, rg.remoteRvs)
+
+	_, err = r.rt.Rule(1)
+	require.Error(t, err)
+	_, err = r.rt.Rule(2)
+	require.Error(t, err)
+
+	// route can't be attached to a route group which doesn't exist
+	unknown := makeRules(9, 10)
+	unknown.Attach = true
+	unknown.Desc = rg.desc.Invert()
+	require.Error(t, r.IntroduceRules(unknown))
+}
+
+func TestPolicyRejection(t *testing.T) {
+	// rejections of remote visors and setup nodes come over RPC as text
+	for _, rejection := range []error{
+		fmt.Errorf("rule 1: %w", transport.ErrDataCapExceeded),
+		rpc.ServerError(fmt.Sprintf("%v: rate_limit", setupclient.ErrRequestRejected)),
+		rpc.ServerError(transport.ErrTransportRejected.Error()),
+	} {
+		var closeErr *routing.CloseError
+		require.True(t, errors.As(policyRejection(rejection), &closeErr), rejection)
+		require.Equal(t, routing.ClosePolicyRejected, closeErr.Code)
+	}
+
+	err := rpc.ServerError("route finder: no route")
+	require.Equal(t, err, policyRejection(err))
+}
+
+func TestRouter_fetchBestRoutes_scoring(t *testing.T) {
+	keys := snettest.GenKeyPairs(3)
+	pkA, pkB, pkC := keys[0].PK, keys[1].PK, keys[2].PK
+
+	hop := func(from, to cipher.PubKey) routing.Hop {
+		return routing.Hop{TpID: transport.MakeTransportID(from, to, dmsg.Type), From: from, To: to}
+	}
+
+	forward := routing.PathEdges{pkA, pkC}
+	backward := routing.PathEdges{pkC, pkA}
+
+	// route finder returns the longer paths first
+	rfPaths := map[routing.PathEdges][][]routing.Hop{
+		forward:  {{hop(pkA, pkB), hop(pkB, pkC)}, {hop(pkA, pkC)}},
+		backward: {{hop(pkC, pkB), hop(pkB, pkA)}, {hop(pkC, pkA)}},
+	}
+
+	rfCl := &rfclient.MockClient{}
+	rfCl.On("FindRoutes", mock.Anything, mock.Anything, mock.Anything).Return(rfPaths, testhelpers.NoErr)
+
+	tm, err := transport.NewManager(nil, nil, &transport.ManagerConfig{})
+	require.NoError(t, err)
+
+	r := &router{logger: logging.MustGetLogger("router"), tm: tm, conf: &Config{RouteFinder: rfCl}}
+
+	opts := DefaultDialOptions()
+	opts.Preferences.Scoring = rfclient.ScoreHops
+
+	fwd, rev, err := r.fetchBestRoutes(pkA, pkC, opts)
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{{hop(pkA, pkC)}}, fwd)
+	require.Equal(t, [][]routing.Hop{{hop(pkC, pkA)}}, rev)
+}
+
+func TestRouter_directSetupPaths(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+	pkD, _ := cipher.GenerateKeyPair()
+
+	direct := []routing.Hop{{TpID: uuid.New(), From: pkA, To: pkD}}
+	viaB := []routing.Hop{{TpID: uuid.New(), From: pkA, To: pkB}, {TpID: uuid.New(), From: pkB, To: pkD}}
+	viaC := []routing.Hop{{TpID: uuid.New(), From: pkA, To: pkC}, {TpID: uuid.New(), From: pkC, To: pkD}}
+	paths := [][]routing.Hop{direct, viaB, viaC}
+
+	r := &router{
+		conf:          &Config{},
+		trustedVisors: map[cipher.PubKey]struct{}{pkB: {}},
+	}
+
+	require.Equal(t, paths, r.directSetupPaths(paths))
+
+	r.conf.DirectSetup = &setupclient.MockDirectDialer{}
+	require.Equal(t, [][]routing.Hop{direct, viaB}, r.directSetupPaths(paths))
+}
+
+func TestDisjointPaths(t *testing.T) {
+	keys := snettest.GenKeyPairs(4)
+	src, dst, inter1, inter2 := keys[0].PK, keys[1].PK, keys[2].PK, keys[3].PK
+
+	direct := []routing.Hop{{From: src, To: dst, TpID: uuid.New()}}
+	via1 := []routing.Hop{{From: src, To: inter1, TpID: uuid.New()}, {From: inter1, To: dst, TpID: uuid.New()}}
+	via1Again := []routing.Hop{{From: src, To: inter1, TpID: uuid.New()}, {From: inter1, To: dst, TpID: uuid.New()}}
+	sharedTp := []routing.Hop{direct[0]}
+	via2 := []routing.Hop{{From: src, To: inter2, TpID: uuid.New()}, {From: inter2, To: dst, TpID: uuid.New()}}
+
+	paths := [][]routing.Hop{direct, via1, via1Again, sharedTp, via2}
+
+	require.Equal(t, [][]routing.Hop{direct}, disjointPaths(paths, 1))
+	require.Equal(t, [][]routing.Hop{direct, via1, via2}, disjointPaths(paths, 5))
+	require.Equal(t, [][]routing.Hop{direct}, disjointPaths(paths, 0))
+	require.Empty(t, disjointPaths(nil, 3))
+}
+
+func TestBidirectionalRoutes(t *testing.T) {
+	keys := snettest.GenKeyPairs(2)
+	desc := routing.NewRouteDescriptor(keys[0].PK, keys[1].PK, 1, 2)
+
+	fwd := [][]routing.Hop{
+		{{From: keys[0].PK, To: keys[1].PK, TpID: uuid.New()}},
+		{{From: keys[0].PK, To: keys[1].PK, TpID: uuid.New()}},
+	}
+	rev := [][]routing.Hop{
+		{{From: keys[1].PK, To: keys[0].PK, TpID: uuid.New()}},
+	}
+
+	// paths are not reused, so the routes don't share intermediaries in either direction
+	routes := bidirectionalRoutes(desc, fwd, rev)
+	require.Len(t, routes, 1)
+
+	rev = append(rev, []routing.Hop{{From: keys[1].PK, To: keys[0].PK, TpID: uuid.New()}})
+
+	routes = bidirectionalRoutes(desc, fwd, rev)
+	require.Len(t, routes, 2)
+
+	for i, route := range routes {
+		require.Equal(t, desc, route.Desc)
+		require.Equal(t, fwd[i], route.Forward)
+		require.Equal(t, rev[i], route.Reverse)
+		require.NoError(t, route.Check())
+	}
+}
+
+func TestPathAvoidance(t *testing.T) {
+	keys := snettest.GenKeyPairs(4)
+	src, dst, via1, via2 := keys[0].PK, keys[1].PK, keys[2].PK, keys[3].PK
+	desc := routing.NewRouteDescriptor(src, dst, 1, 2)
+
+	hop := func(from, to cipher.PubKey) routing.Hop {
+		return routing.Hop{From: from, To: to, TpID: uuid.New()}
+	}
+
+	broken := routePath{
+		forward: []routing.Hop{hop(src, via1), hop(via1, dst)},
+		reverse: []routing.Hop{hop(dst, via1), hop(via1, src)},
+	}
+	brokenTpID := broken.forward[0].TpID
+
+	avoid := newPathAvoidance(desc, brokenTpID, broken)
+
+	// the same path, paths sharing the intermediate visor or a transport aren't of use
+	sameVisor := []routing.Hop{hop(src, via1), hop(via1, dst)}
+	sameTp := []routing.Hop{broken.forward[0], hop(via1, dst)}
+	reverseTp := []routing.Hop{hop(src, via2), {From: via2, To: dst, TpID: broken.reverse[0].TpID}}
+	disjoint := []routing.Hop{hop(src, via2), hop(via2, dst)}
+	direct := []routing.Hop{hop(src, dst)}
+
+	_, ok := avoid.firstDisjoint([][]routing.Hop{broken.forward, sameVisor, sameTp, reverseTp})
+	require.False(t, ok)
+
+	path, ok := avoid.firstDisjoint([][]routing.Hop{sameVisor, disjoint, direct})
+	require.True(t, ok)
+	require.Equal(t, disjoint, path)
+
+	path, ok = avoid.firstDisjoint([][]routing.Hop{direct})
+	require.True(t, ok)
+	require.Equal(t, direct, path)
+
+	// without the recorded path only the broken transport is avoided
+	avoid = newPathAvoidance(desc, brokenTpID, routePath{})
+
+	path, ok = avoid.firstDisjoint([][]routing.Hop{sameTp, sameVisor})
+	require.True(t, ok)
+	require.Equal(t, sameVisor, path)
+}
+
+func TestRouteRepairs(t *testing.T) {
+	repairs := newRouteRepairs(2)
+	rg1, rg2, rg3 := &RouteGroup{}, &RouteGroup{}, &RouteGroup{}
+
+	require.True(t, repairs.start(rg1))
+	require.False(t, repairs.start(rg1), "route group is already being repaired")
+	require.True(t, repairs.start(rg2))
+	require.False(t, repairs.start(rg3), "concurrency limit is reached")
+
+	repairs.done(rg1)
+	require.True(t, repairs.start(rg3))
+	require.False(t, repairs.start(rg1), "concurrency limit is reached")
 }
 
 func clearRouteGroups(routers ...*router) {
@@ -828,3 +1121,33 @@ func (e *TestEnv) GenRouterConfig(i int) *Config {
 func (e *TestEnv) Teardown() {
 	e.teardown()
 }
+
+func TestRouter_serveRPC(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	r := &router{logger: logging.MustGetLogger("router"), rt: routing.NewTable()}
+
+	dial := func() *routerclient.Client {
+		conn, srvConn := net.Pipe()
+		go connServerFunc(r.serveRPC).ServeConn(srvConn)
+
+		rc := routerclient.NewClientFromRaw(conn, pk)
+		t.Cleanup(func() { assert.NoError(t, rc.Close()) })
+
+		return rc
+	}
+
+	ctx := context.Background()
+	rc1, rc2 := dial(), dial()
+
+	ids, err := rc1.ReserveIDs(ctx, 2)
+	require.NoError(t, err)
+
+	// each connection is a session of its own, IDs reserved within one can't be released by another
+	_, err = rc2.ReleaseIDs(ctx, ids)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrRouteIDNotOwned.Error())
+
+	ok, err := rc1.ReleaseIDs(ctx, ids)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
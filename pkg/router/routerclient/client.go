@@ -72,6 +72,20 @@ func (c *Client) ReserveIDs(ctx context.Context, n uint8) (rtIDs []routing.Route
 	return rtIDs, err
 }
 
+// DeleteRules deletes rules of route IDs from router.
+func (c *Client) DeleteRules(ctx context.Context, rtIDs []routing.RouteID) (ok bool, err error) {
+	const method = "DeleteRules"
+	err = c.call(ctx, method, rtIDs, &ok)
+	return ok, err
+}
+
+// ReleaseIDs releases reserved route IDs which are not used.
+func (c *Client) ReleaseIDs(ctx context.Context, rtIDs []routing.RouteID) (ok bool, err error) {
+	const method = "ReleaseIDs"
+	err = c.call(ctx, method, rtIDs, &ok)
+	return ok, err
+}
+
 func (c *Client) call(ctx context.Context, method string, args interface{}, reply interface{}) error {
 	call := c.rpc.Go(RPCName+"."+method, args, reply, nil)
 	select {
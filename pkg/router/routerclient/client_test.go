@@ -6,6 +6,7 @@ import (
 	"net/rpc"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/nettest"
@@ -39,11 +40,12 @@ func TestClient_AddEdgeRules(t *testing.T) {
 
 	rules := routing.EdgeRules{
 		Desc:    desc,
-		Forward: routing.Rule{0, 0, 0},
-		Reverse: routing.Rule{1, 1, 1},
+		Forward: routing.ForwardRule(0, 1, 2, uuid.UUID{}, srcPK, dstPK, srcPort, dstPort),
+		Reverse: routing.ConsumeRule(0, 3, srcPK, dstPK, srcPort, dstPort),
 	}
 
 	r := &router.MockRouter{}
+	r.On("CheckRules", rules.Forward).Return(testhelpers.NoErr)
 	r.On("IntroduceRules", rules).Return(testhelpers.NoErr)
 	r.On("SaveRoutingRules", rules.Forward, rules.Reverse).Return(testhelpers.NoErr)
 
@@ -56,12 +58,13 @@ func TestClient_AddEdgeRules(t *testing.T) {
 }
 
 func TestClient_AddIntermediaryRules(t *testing.T) {
-	rule1 := routing.Rule{0, 0, 0}
-	rule2 := routing.Rule{1, 1, 1}
+	rule1 := routing.IntermediaryForwardRule(0, 1, 2, uuid.UUID{})
+	rule2 := routing.IntermediaryForwardRule(0, 3, 4, uuid.UUID{})
 	rulesIfc := []interface{}{rule1, rule2}
 	rules := []routing.Rule{rule1, rule2}
 
 	r := &router.MockRouter{}
+	r.On("CheckRules", rulesIfc...).Return(testhelpers.NoErr)
 	r.On("SaveRoutingRules", rulesIfc...).Return(testhelpers.NoErr)
 
 	_, cl, cleanup := prepRPCServerAndClient(t, r)
@@ -87,6 +90,49 @@ func TestClient_ReserveIDs(t *testing.T) {
 	require.Equal(t, ids, gotIDs)
 }
 
+func TestClient_DeleteRules(t *testing.T) {
+	ids := []routing.RouteID{1, 2}
+
+	r := &router.MockRouter{}
+	r.On("ReserveKeys", len(ids)).Return(ids, testhelpers.NoErr)
+	r.On("DelRules", ids).Return()
+
+	_, cl, cleanup := prepRPCServerAndClient(t, r)
+	defer cleanup()
+
+	// rules of route IDs which aren't reserved within the session are not deleted
+	ok, err := cl.DeleteRules(context.Background(), ids)
+	require.Error(t, err)
+	require.False(t, ok)
+
+	_, err = cl.ReserveIDs(context.Background(), uint8(len(ids)))
+	require.NoError(t, err)
+
+	ok, err = cl.DeleteRules(context.Background(), ids)
+	require.NoError(t, err)
+	require.True(t, ok)
+	r.AssertExpectations(t)
+}
+
+func TestClient_ReleaseIDs(t *testing.T) {
+	ids := []routing.RouteID{3, 4}
+
+	r := &router.MockRouter{}
+	r.On("ReserveKeys", len(ids)).Return(ids, testhelpers.NoErr)
+	r.On("ReleaseKeys", ids).Return()
+
+	_, cl, cleanup := prepRPCServerAndClient(t, r)
+	defer cleanup()
+
+	_, err := cl.ReserveIDs(context.Background(), uint8(len(ids)))
+	require.NoError(t, err)
+
+	ok, err := cl.ReleaseIDs(context.Background(), ids)
+	require.NoError(t, err)
+	require.True(t, ok)
+	r.AssertExpectations(t)
+}
+
 // nolint:unparam
 func prepRPCServerAndClient(t *testing.T, r router.Router) (s *rpc.Server, cl *Client, cleanup func()) {
 	l, err := nettest.NewLocalListener("tcp")
@@ -1,15 +1,28 @@
 package router
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+
 	"github.com/skycoin/skycoin/src/util/logging"
 
 	"github.com/skycoin/skywire/pkg/routing"
 )
 
+// ErrRouteIDNotOwned is returned when rules or route IDs which are neither reserved nor saved
+// within the RPC session are requested to be deleted or released.
+var ErrRouteIDNotOwned = errors.New("route ID is not reserved or saved within the session")
+
 // RPCGateway is a RPC interface for router.
+// Gateway is a single RPC session, e.g. a single route group setup. Rules and route IDs may only be
+// deleted or released by the session which reserved or saved them.
 type RPCGateway struct {
 	logger *logging.Logger
 	router Router
+
+	mx    sync.Mutex
+	owned map[routing.RouteID]struct{} // route IDs reserved or saved within the session
 }
 
 // NewRPCGateway creates a new RPCGateway.
@@ -17,12 +30,20 @@ func NewRPCGateway(router Router) *RPCGateway {
 	return &RPCGateway{
 		logger: logging.MustGetLogger("router-gateway"),
 		router: router,
+		owned:  make(map[routing.RouteID]struct{}),
 	}
 }
 
 // AddEdgeRules adds edge rules.
+// Rules forwarding packets to transports of exceeded data caps are refused,
+// so the setup node rolls the route group back.
 func (r *RPCGateway) AddEdgeRules(rules routing.EdgeRules, ok *bool) error {
-	if err := r.router.IntroduceRules(rules); err != nil {
+	err := r.router.CheckRules(rules.Forward)
+	if err == nil {
+		err = r.router.IntroduceRules(rules)
+	}
+
+	if err != nil {
 		*ok = false
 
 		r.logger.WithError(err).Warnf("Request completed with error.")
@@ -30,14 +51,22 @@ func (r *RPCGateway) AddEdgeRules(rules routing.EdgeRules, ok *bool) error {
 		return routing.Failure{Code: routing.FailureAddRules, Msg: err.Error()}
 	}
 
+	r.own(rules.Forward.KeyRouteID(), rules.Reverse.KeyRouteID())
+
 	*ok = true
 
 	return nil
 }
 
 // AddIntermediaryRules adds intermediary rules.
+// Rules forwarding packets to transports of exceeded data caps are refused.
 func (r *RPCGateway) AddIntermediaryRules(rules []routing.Rule, ok *bool) error {
-	if err := r.router.SaveRoutingRules(rules...); err != nil {
+	err := r.router.CheckRules(rules...)
+	if err == nil {
+		err = r.router.SaveRoutingRules(rules...)
+	}
+
+	if err != nil {
 		*ok = false
 
 		r.logger.WithError(err).Warnf("Request completed with error.")
@@ -45,6 +74,10 @@ func (r *RPCGateway) AddIntermediaryRules(rules []routing.Rule, ok *bool) error
 		return routing.Failure{Code: routing.FailureAddRules, Msg: err.Error()}
 	}
 
+	for _, rule := range rules {
+		r.own(rule.KeyRouteID())
+	}
+
 	*ok = true
 
 	return nil
@@ -58,7 +91,78 @@ func (r *RPCGateway) ReserveIDs(n uint8, routeIDs *[]routing.RouteID) error {
 		return routing.Failure{Code: routing.FailureReserveRtIDs, Msg: err.Error()}
 	}
 
+	r.own(ids...)
+
 	*routeIDs = ids
 
 	return nil
 }
+
+// DeleteRules deletes rules of route IDs, route groups of the deleted edge rules get closed.
+// Request is refused if any of the route IDs isn't reserved or saved within the session.
+func (r *RPCGateway) DeleteRules(routeIDs []routing.RouteID, ok *bool) error {
+	if err := r.checkOwned(routeIDs); err != nil {
+		*ok = false
+
+		r.logger.WithError(err).Warnf("Request completed with error.")
+
+		return routing.Failure{Code: routing.FailureDeleteRules, Msg: err.Error()}
+	}
+
+	r.router.DelRules(routeIDs)
+
+	*ok = true
+
+	return nil
+}
+
+// ReleaseIDs releases reserved route IDs which are not used.
+// Request is refused if any of the route IDs isn't reserved or saved within the session.
+func (r *RPCGateway) ReleaseIDs(routeIDs []routing.RouteID, ok *bool) error {
+	if err := r.checkOwned(routeIDs); err != nil {
+		*ok = false
+
+		r.logger.WithError(err).Warnf("Request completed with error.")
+
+		return routing.Failure{Code: routing.FailureReleaseRtIDs, Msg: err.Error()}
+	}
+
+	r.router.ReleaseKeys(routeIDs)
+	r.disown(routeIDs...)
+
+	*ok = true
+
+	return nil
+}
+
+func (r *RPCGateway) own(ids ...routing.RouteID) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, id := range ids {
+		r.owned[id] = struct{}{}
+	}
+}
+
+// disown forgets the released route IDs, they may be reserved by another session then.
+func (r *RPCGateway) disown(ids ...routing.RouteID) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, id := range ids {
+		delete(r.owned, id)
+	}
+}
+
+func (r *RPCGateway) checkOwned(ids []routing.RouteID) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, id := range ids {
+		if _, ok := r.owned[id]; !ok {
+			return fmt.Errorf("%w: %d", ErrRouteIDNotOwned, id)
+		}
+	}
+
+	return nil
+}
@@ -1,13 +1,16 @@
 package router
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skywire/internal/testhelpers"
 	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
 )
 
 func TestRPCGateway_AddEdgeRules(t *testing.T) {
@@ -20,12 +23,13 @@ func TestRPCGateway_AddEdgeRules(t *testing.T) {
 
 	rules := routing.EdgeRules{
 		Desc:    desc,
-		Forward: routing.Rule{0, 0, 0},
-		Reverse: routing.Rule{1, 1, 1},
+		Forward: routing.ForwardRule(0, 1, 2, uuid.UUID{}, srcPK, dstPK, srcPort, dstPort),
+		Reverse: routing.ConsumeRule(0, 3, srcPK, dstPK, srcPort, dstPort),
 	}
 
 	t.Run("ok", func(t *testing.T) {
 		r := &MockRouter{}
+		r.On("CheckRules", rules.Forward).Return(testhelpers.NoErr)
 		r.On("IntroduceRules", rules).Return(testhelpers.NoErr)
 		r.On("SaveRoutingRules", rules.Forward, rules.Reverse).Return(testhelpers.NoErr)
 
@@ -39,6 +43,7 @@ func TestRPCGateway_AddEdgeRules(t *testing.T) {
 
 	t.Run("fail introducing rules", func(t *testing.T) {
 		r := &MockRouter{}
+		r.On("CheckRules", rules.Forward).Return(testhelpers.NoErr)
 		r.On("IntroduceRules", rules).Return(testhelpers.Err)
 
 		gateway := NewRPCGateway(r)
@@ -57,6 +62,7 @@ func TestRPCGateway_AddEdgeRules(t *testing.T) {
 
 	t.Run("fail saving rules", func(t *testing.T) {
 		r := &MockRouter{}
+		r.On("CheckRules", rules.Forward).Return(testhelpers.NoErr)
 		r.On("IntroduceRules", rules).Return(testhelpers.Err)
 
 		gateway := NewRPCGateway(r)
@@ -71,16 +77,37 @@ func TestRPCGateway_AddEdgeRules(t *testing.T) {
 		require.Equal(t, wantErr, err)
 		require.False(t, ok)
 	})
+
+	t.Run("data cap exceeded", func(t *testing.T) {
+		capErr := fmt.Errorf("rule 0: %w", transport.ErrDataCapExceeded)
+
+		r := &MockRouter{}
+		r.On("CheckRules", rules.Forward).Return(capErr)
+
+		gateway := NewRPCGateway(r)
+
+		wantErr := routing.Failure{
+			Code: routing.FailureAddRules,
+			Msg:  capErr.Error(),
+		}
+
+		var ok bool
+		err := gateway.AddEdgeRules(rules, &ok)
+		require.Equal(t, wantErr, err)
+		require.False(t, ok)
+		r.AssertNotCalled(t, "IntroduceRules", rules)
+	})
 }
 
 func TestRPCGateway_AddIntermediaryRules(t *testing.T) {
-	rule1 := routing.Rule{0, 0, 0}
-	rule2 := routing.Rule{1, 1, 1}
+	rule1 := routing.IntermediaryForwardRule(0, 1, 2, uuid.UUID{})
+	rule2 := routing.IntermediaryForwardRule(0, 3, 4, uuid.UUID{})
 	rulesIfc := []interface{}{rule1, rule2}
 	rules := []routing.Rule{rule1, rule2}
 
 	t.Run("ok", func(t *testing.T) {
 		r := &MockRouter{}
+		r.On("CheckRules", rulesIfc...).Return(testhelpers.NoErr)
 		r.On("SaveRoutingRules", rulesIfc...).Return(testhelpers.NoErr)
 
 		gateway := NewRPCGateway(r)
@@ -93,6 +120,7 @@ func TestRPCGateway_AddIntermediaryRules(t *testing.T) {
 
 	t.Run("fail saving rules", func(t *testing.T) {
 		r := &MockRouter{}
+		r.On("CheckRules", rulesIfc...).Return(testhelpers.NoErr)
 		r.On("SaveRoutingRules", rulesIfc...).Return(testhelpers.Err)
 
 		gateway := NewRPCGateway(r)
@@ -107,6 +135,26 @@ func TestRPCGateway_AddIntermediaryRules(t *testing.T) {
 		require.Equal(t, wantErr, err)
 		require.False(t, ok)
 	})
+
+	t.Run("data cap exceeded", func(t *testing.T) {
+		capErr := fmt.Errorf("rule 0: %w", transport.ErrDataCapExceeded)
+
+		r := &MockRouter{}
+		r.On("CheckRules", rulesIfc...).Return(capErr)
+
+		gateway := NewRPCGateway(r)
+
+		wantErr := routing.Failure{
+			Code: routing.FailureAddRules,
+			Msg:  capErr.Error(),
+		}
+
+		var ok bool
+		err := gateway.AddIntermediaryRules(rules, &ok)
+		require.Equal(t, wantErr, err)
+		require.False(t, ok)
+		r.AssertNotCalled(t, "SaveRoutingRules", rulesIfc...)
+	})
 }
 
 func TestRPCGateway_ReserveIDs(t *testing.T) {
@@ -142,3 +190,87 @@ func TestRPCGateway_ReserveIDs(t *testing.T) {
 		require.Nil(t, gotIds)
 	})
 }
+
+func TestRPCGateway_DeleteRules(t *testing.T) {
+	rule := routing.IntermediaryForwardRule(0, 3, 4, uuid.UUID{})
+	ids := []routing.RouteID{1, 3}
+
+	r := &MockRouter{}
+	r.On("ReserveKeys", 2).Return([]routing.RouteID{1, 2}, testhelpers.NoErr)
+	r.On("CheckRules", rule).Return(testhelpers.NoErr)
+	r.On("SaveRoutingRules", rule).Return(testhelpers.NoErr)
+	r.On("DelRules", ids).Return()
+
+	gateway := NewRPCGateway(r)
+
+	var reserved []routing.RouteID
+	require.NoError(t, gateway.ReserveIDs(2, &reserved))
+
+	var ok bool
+	require.NoError(t, gateway.AddIntermediaryRules([]routing.Rule{rule}, &ok))
+
+	t.Run("reserved and saved", func(t *testing.T) {
+		var ok bool
+		require.NoError(t, gateway.DeleteRules(ids, &ok))
+		require.True(t, ok)
+		r.AssertCalled(t, "DelRules", ids)
+	})
+
+	t.Run("not owned", func(t *testing.T) {
+		wantErr := routing.Failure{
+			Code: routing.FailureDeleteRules,
+			Msg:  fmt.Sprintf("%v: 7", ErrRouteIDNotOwned),
+		}
+
+		ok := true
+		require.Equal(t, wantErr, gateway.DeleteRules([]routing.RouteID{1, 7}, &ok))
+		require.False(t, ok)
+		r.AssertNotCalled(t, "DelRules", []routing.RouteID{1, 7})
+	})
+
+	t.Run("owned by another session", func(t *testing.T) {
+		var ok bool
+		require.Error(t, NewRPCGateway(r).DeleteRules([]routing.RouteID{1}, &ok))
+		require.False(t, ok)
+		r.AssertNotCalled(t, "DelRules", []routing.RouteID{1})
+	})
+}
+
+func TestRPCGateway_ReleaseIDs(t *testing.T) {
+	ids := []routing.RouteID{3, 4}
+
+	r := &MockRouter{}
+	r.On("ReserveKeys", 2).Return(ids, testhelpers.NoErr)
+	r.On("ReleaseKeys", ids).Return()
+
+	gateway := NewRPCGateway(r)
+
+	t.Run("not owned", func(t *testing.T) {
+		wantErr := routing.Failure{
+			Code: routing.FailureReleaseRtIDs,
+			Msg:  fmt.Sprintf("%v: 3", ErrRouteIDNotOwned),
+		}
+
+		var ok bool
+		require.Equal(t, wantErr, gateway.ReleaseIDs(ids, &ok))
+		require.False(t, ok)
+		r.AssertNotCalled(t, "ReleaseKeys", ids)
+	})
+
+	t.Run("reserved", func(t *testing.T) {
+		var reserved []routing.RouteID
+		require.NoError(t, gateway.ReserveIDs(2, &reserved))
+
+		var ok bool
+		require.NoError(t, gateway.ReleaseIDs(ids, &ok))
+		require.True(t, ok)
+		r.AssertNumberOfCalls(t, "ReleaseKeys", 1)
+	})
+
+	t.Run("already released", func(t *testing.T) {
+		var ok bool
+		require.Error(t, gateway.ReleaseIDs(ids, &ok))
+		require.False(t, ok)
+		r.AssertNumberOfCalls(t, "ReleaseKeys", 1)
+	})
+}
@@ -24,6 +24,8 @@ const (
 	FailureCreateRoutes
 	FailureRoutesCreated
 	FailureReserveRtIDs
+	FailureDeleteRules
+	FailureReleaseRtIDs
 )
 
 func (fc FailureCode) String() string {
@@ -38,6 +40,10 @@ func (fc FailureCode) String() string {
 		return "FailureRoutesCreated"
 	case FailureReserveRtIDs:
 		return "FailureReserveRtIDs"
+	case FailureDeleteRules:
+		return "FailureDeleteRules"
+	case FailureReleaseRtIDs:
+		return "FailureReleaseRtIDs"
 	default:
 		return fmt.Sprintf("unknown(%d)", fc)
 	}
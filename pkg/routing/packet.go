@@ -5,24 +5,41 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
 )
 
 // Packet defines generic packet recognized by all skywire visors.
 // The unit of communication for routing/router is called packets.
 // Packet format:
+//     | version & type (byte) | route ID (uint32) | payload size (uint16) | hop limit (byte) | payload (~) |
+//     | 1[0:1]                | 4[1:5]            | 2[5:7]                | 1[7:8]           | [8:~]       |
+// The highest bit of the first byte marks the header version. Legacy (version 0) packets
+// have the bit unset and lack the hop limit byte:
 //     | type (byte) | route ID (uint32) | payload size (uint16) | payload (~) |
 //     | 1[0:1]      | 4[1:5]            | 2[5:7]                | [7:~]       |
+// Packets are always versioned in memory, legacy packets only exist on the wire (see Legacy and FromLegacy).
 type Packet []byte
 
 // Packet sizes and offsets.
 const (
 	// PacketHeaderSize represents the base size of a packet.
 	// All rules should have at-least this size.
-	PacketHeaderSize        = 7
+	PacketHeaderSize        = 8
 	PacketTypeOffset        = 0
 	PacketRouteIDOffset     = 1
 	PacketPayloadSizeOffset = 5
+	PacketHopLimitOffset    = 7
 	PacketPayloadOffset     = PacketHeaderSize
+
+	// LegacyPacketHeaderSize represents the header size of legacy packets which lack the hop limit.
+	LegacyPacketHeaderSize = 7
+
+	// DefaultHopLimit is the hop limit of newly created packets.
+	DefaultHopLimit = 64
+
+	packetVersionBit = 0x80
 )
 
 var (
@@ -45,21 +62,39 @@ func (t PacketType) String() string {
 		return "NetworkProbe"
 	case HandshakePacket:
 		return "Handshake"
+	case TracePacket:
+		return "TracePacket"
+	case CreditPacket:
+		return "CreditPacket"
+	case TransportProbePacket:
+		return "TransportProbe"
 	default:
 		return fmt.Sprintf("Unknown(%d)", t)
 	}
 }
 
 // Possible PacketType values:
-// - DataPacket      - Payload is just the underlying data.
-// - ClosePacket     - Payload is a type CloseCode byte.
-// - KeepAlivePacket - Payload is empty.
+// - DataPacket         - Payload is just the underlying data.
+// - ClosePacket        - Payload is a type CloseCode byte.
+// - KeepAlivePacket    - Payload is empty.
+// - HandshakePacket    - Payload is an encryption support byte optionally followed by a HandshakeFlags byte.
+//                        With HandshakeFlowControl set, flags are followed by the initial credit window (uint32).
+// - NetworkProbePacket - Payload is a timestamp (int64) followed by a throughput (int64).
+// - TracePacket        - Payload is a flags byte and a trace ID (uint32) followed by the hops
+//                        the packet went through, each hop is a public key and a timestamp (int64).
+// - CreditPacket       - Payload is a credit limit (uint32), the total number of DataPackets the sender
+//                        is allowed to send since the route group was established.
+// - TransportProbePacket - Payload is a flags byte followed by a probe sequence number (uint32).
+//                          It's exchanged by the edges of a transport and is never routed.
 const (
 	DataPacket PacketType = iota
 	ClosePacket
 	KeepAlivePacket
 	HandshakePacket
 	NetworkProbePacket
+	TracePacket
+	CreditPacket
+	TransportProbePacket
 )
 
 // CloseCode represents close code for ClosePacket.
@@ -69,16 +104,122 @@ func (cc CloseCode) String() string {
 	switch cc {
 	case CloseRequested:
 		return "Closing requested by visor"
+	case CloseKeepAliveTimeout:
+		return "Keep-alive timeout"
+	case CloseRuleExpired:
+		return "Intermediary rule expired"
+	case CloseTransportFailed:
+		return "Transport failed"
+	case ClosePolicyRejected:
+		return "Rejected by visor policy"
+	case CloseNoListener:
+		return "No listener on port"
 	default:
 		return fmt.Sprintf("Unknown(%d)", byte(cc))
 	}
 }
 
+// Transient checks whether the route group was closed due to network conditions,
+// so that redialing the remote may succeed.
+func (cc CloseCode) Transient() bool {
+	switch cc {
+	case CloseKeepAliveTimeout, CloseRuleExpired, CloseTransportFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close codes are sent within ClosePackets, new codes should only be added to the end.
 const (
 	// CloseRequested is used when a closing is requested by visor.
 	CloseRequested CloseCode = iota
+	// CloseKeepAliveTimeout is used when remote didn't send anything within the keep-alive timeout.
+	CloseKeepAliveTimeout
+	// CloseRuleExpired is used by intermediary visor once rule of the route expires.
+	CloseRuleExpired
+	// CloseTransportFailed is used when transports of the route group are gone.
+	CloseTransportFailed
+	// ClosePolicyRejected is used when route group is rejected by the policy of visor or setup node,
+	// e.g. once data cap of a transport is exceeded or request limits of setup node are reached.
+	ClosePolicyRejected
+	// CloseNoListener is used when nothing listens on the route group port of visor.
+	CloseNoListener
 )
 
+// CloseError is returned by route group once it's closed with the code other than CloseRequested.
+// Implements net.Error.
+type CloseError struct {
+	Code CloseCode
+}
+
+// Error implements error.
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("route group closed: %s", e.Code)
+}
+
+// Timeout implements net.Error.
+func (e *CloseError) Timeout() bool { return false }
+
+// Temporary implements net.Error. Closed route group is never recovered,
+// Transient tells whether redialing makes sense.
+func (e *CloseError) Temporary() bool { return false }
+
+// Transient checks whether redialing the remote may succeed.
+func (e *CloseError) Transient() bool { return e.Code.Transient() }
+
+// HandshakeFlags represents optional route group features announced within HandshakePacket.
+// Visors which are not aware of any flags send HandshakePacket without the flags byte.
+type HandshakeFlags byte
+
+const (
+	// HandshakeMultiRoute is set when route group attaches extra routes of the same route descriptor
+	// and keeps each of its routes alive separately. Routes of such route groups may be repaired.
+	HandshakeMultiRoute HandshakeFlags = 1 << iota
+
+	// HandshakeReliable is set when route group supports sequencing, acknowledgement and retransmission of data.
+	// Reliable delivery is only used if both edges of the route group set it.
+	HandshakeReliable
+
+	// HandshakeFlowControl is set when route group announces its credit window within the handshake
+	// and grants credits with CreditPackets as its reader consumes data.
+	HandshakeFlowControl
+
+	// HandshakeDatagram is set by the initiator of a route group which keeps message boundaries
+	// and is never retransmitted. The responder takes the route group as a datagram one as well.
+	HandshakeDatagram
+)
+
+// Has checks whether all of the `flags` are set.
+func (f HandshakeFlags) Has(flags HandshakeFlags) bool {
+	return f&flags == flags
+}
+
+// TraceHop is a visor a TracePacket went through.
+type TraceHop struct {
+	PK   cipher.PubKey `json:"pk"`
+	Time time.Time     `json:"time"`
+}
+
+const (
+	traceHeaderSize = 1 + 4
+	traceHopSize    = pkSize + 8
+
+	traceReturning = 1
+)
+
+// ErrBadTracePayload is returned when TracePacket payload can't be parsed.
+var ErrBadTracePayload = errors.New("bad trace packet payload")
+
+const (
+	probeSize = 1 + 4
+
+	probeReply = 1
+)
+
+// ErrBadProbePayload is returned when TransportProbePacket payload can't be parsed.
+var ErrBadProbePayload = errors.New("bad transport probe packet payload")
+
 // RouteID represents ID of a Route in a Packet.
 type RouteID uint32
 
@@ -89,10 +230,9 @@ func MakeDataPacket(id RouteID, payload []byte) (Packet, error) {
 		return Packet{}, ErrPayloadTooBig
 	}
 
-	packet := make([]byte, PacketHeaderSize+len(payload))
+	packet := make(Packet, PacketHeaderSize+len(payload))
 
-	packet[PacketTypeOffset] = byte(DataPacket)
-	binary.BigEndian.PutUint32(packet[PacketRouteIDOffset:], uint32(id))
+	packet.setHeader(DataPacket, id)
 	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(len(payload)))
 	copy(packet[PacketPayloadOffset:], payload)
 
@@ -101,10 +241,9 @@ func MakeDataPacket(id RouteID, payload []byte) (Packet, error) {
 
 // MakeClosePacket constructs a new ClosePacket.
 func MakeClosePacket(id RouteID, code CloseCode) Packet {
-	packet := make([]byte, PacketHeaderSize+1)
+	packet := make(Packet, PacketHeaderSize+1)
 
-	packet[PacketTypeOffset] = byte(ClosePacket)
-	binary.BigEndian.PutUint32(packet[PacketRouteIDOffset:], uint32(id))
+	packet.setHeader(ClosePacket, id)
 	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(1))
 	packet[PacketPayloadOffset] = byte(code)
 
@@ -113,10 +252,9 @@ func MakeClosePacket(id RouteID, code CloseCode) Packet {
 
 // MakeKeepAlivePacket constructs a new KeepAlivePacket.
 func MakeKeepAlivePacket(id RouteID) Packet {
-	packet := make([]byte, PacketHeaderSize)
+	packet := make(Packet, PacketHeaderSize)
 
-	packet[PacketTypeOffset] = byte(KeepAlivePacket)
-	binary.BigEndian.PutUint32(packet[PacketRouteIDOffset:], uint32(id))
+	packet.setHeader(KeepAlivePacket, id)
 	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(0))
 
 	return packet
@@ -124,10 +262,9 @@ func MakeKeepAlivePacket(id RouteID) Packet {
 
 // MakeNetworkProbePacket constructs a new NetworkProbePacket.
 func MakeNetworkProbePacket(id RouteID, timestamp, throughput int64) Packet {
-	packet := make([]byte, PacketHeaderSize+16)
+	packet := make(Packet, PacketHeaderSize+16)
 
-	packet[PacketTypeOffset] = byte(NetworkProbePacket)
-	binary.BigEndian.PutUint32(packet[PacketRouteIDOffset:], uint32(id))
+	packet.setHeader(NetworkProbePacket, id)
 	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(16))
 	binary.BigEndian.PutUint64(packet[PacketPayloadOffset:], uint64(timestamp))
 	binary.BigEndian.PutUint64(packet[PacketPayloadOffset+8:], uint64(throughput))
@@ -136,25 +273,179 @@ func MakeNetworkProbePacket(id RouteID, timestamp, throughput int64) Packet {
 }
 
 // MakeHandshakePacket constructs a new HandshakePacket.
-func MakeHandshakePacket(id RouteID, supportEncryption bool) Packet {
-	packet := make([]byte, PacketHeaderSize+1)
+// The flags byte is omitted if no `flags` are set, so the packet stays the same as the one of older visors.
+// The credit `window` is only sent if HandshakeFlowControl is set.
+func MakeHandshakePacket(id RouteID, supportEncryption bool, flags HandshakeFlags, window uint32) Packet {
+	size := 1
+	if flags != 0 {
+		size++
+	}
+
+	if flags.Has(HandshakeFlowControl) {
+		size += 4
+	}
+
+	packet := make(Packet, PacketHeaderSize+size)
 
 	supportEncryptionVal := 1
 	if !supportEncryption {
 		supportEncryptionVal = 0
 	}
 
-	packet[PacketTypeOffset] = byte(HandshakePacket)
-	binary.BigEndian.PutUint32(packet[PacketRouteIDOffset:], uint32(id))
-	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(1))
+	packet.setHeader(HandshakePacket, id)
+	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(size))
 	packet[PacketPayloadOffset] = byte(supportEncryptionVal)
 
+	if flags != 0 {
+		packet[PacketPayloadOffset+1] = byte(flags)
+	}
+
+	if flags.Has(HandshakeFlowControl) {
+		binary.BigEndian.PutUint32(packet[PacketPayloadOffset+2:], window)
+	}
+
+	return packet
+}
+
+// MakeCreditPacket constructs a new CreditPacket.
+func MakeCreditPacket(id RouteID, limit uint32) Packet {
+	packet := make(Packet, PacketHeaderSize+4)
+
+	packet.setHeader(CreditPacket, id)
+	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(4))
+	binary.BigEndian.PutUint32(packet[PacketPayloadOffset:], limit)
+
 	return packet
 }
 
+// MakeTransportProbePacket constructs a new TransportProbePacket.
+// 'reply' is set once the probe `seq` is sent back by the remote edge of the transport.
+func MakeTransportProbePacket(seq uint32, reply bool) Packet {
+	packet := make(Packet, PacketHeaderSize+probeSize)
+
+	packet.setHeader(TransportProbePacket, 0)
+	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(probeSize))
+
+	if reply {
+		packet[PacketPayloadOffset] = probeReply
+	}
+
+	binary.BigEndian.PutUint32(packet[PacketPayloadOffset+1:], seq)
+
+	return packet
+}
+
+// TransportProbe parses payload of a TransportProbePacket.
+func (p Packet) TransportProbe() (seq uint32, reply bool, err error) {
+	payload := p.Payload()
+	if len(payload) != probeSize {
+		return 0, false, ErrBadProbePayload
+	}
+
+	return binary.BigEndian.Uint32(payload[1:]), payload[0]&probeReply != 0, nil
+}
+
+func (p Packet) setHeader(t PacketType, id RouteID) {
+	p[PacketTypeOffset] = byte(t) | packetVersionBit
+	binary.BigEndian.PutUint32(p[PacketRouteIDOffset:], uint32(id))
+	p[PacketHopLimitOffset] = DefaultHopLimit
+}
+
+// MakeTracePacket constructs a new TracePacket.
+// 'returning' is set once the packet is sent back by the remote edge of the route group.
+func MakeTracePacket(id RouteID, traceID uint32, returning bool, hops []TraceHop) (Packet, error) {
+	size := traceHeaderSize + len(hops)*traceHopSize
+	if size > math.MaxUint16 {
+		return Packet{}, ErrPayloadTooBig
+	}
+
+	packet := make(Packet, PacketHeaderSize+size)
+
+	packet.setHeader(TracePacket, id)
+	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(size))
+
+	if returning {
+		packet[PacketPayloadOffset] = traceReturning
+	}
+
+	binary.BigEndian.PutUint32(packet[PacketPayloadOffset+1:], traceID)
+
+	for i, hop := range hops {
+		off := PacketPayloadOffset + traceHeaderSize + i*traceHopSize
+		copy(packet[off:], hop.PK[:])
+		binary.BigEndian.PutUint64(packet[off+pkSize:], uint64(hop.Time.UnixNano()))
+	}
+
+	return packet, nil
+}
+
+// Trace parses payload of a TracePacket.
+func (p Packet) Trace() (traceID uint32, returning bool, hops []TraceHop, err error) {
+	payload := p.Payload()
+	if len(payload) < traceHeaderSize || (len(payload)-traceHeaderSize)%traceHopSize != 0 {
+		return 0, false, nil, ErrBadTracePayload
+	}
+
+	returning = payload[0]&traceReturning != 0
+	traceID = binary.BigEndian.Uint32(payload[1:])
+
+	hops = make([]TraceHop, 0, (len(payload)-traceHeaderSize)/traceHopSize)
+	for off := traceHeaderSize; off < len(payload); off += traceHopSize {
+		var hop TraceHop
+
+		copy(hop.PK[:], payload[off:])
+		hop.Time = time.Unix(0, int64(binary.BigEndian.Uint64(payload[off+pkSize:])))
+		hops = append(hops, hop)
+	}
+
+	return traceID, returning, hops, nil
+}
+
 // Type returns Packet's type.
 func (p Packet) Type() PacketType {
-	return PacketType(p[PacketTypeOffset])
+	return PacketType(p[PacketTypeOffset] &^ packetVersionBit)
+}
+
+// IsLegacy checks whether the header is a legacy one. It only relies on the first byte,
+// so it may be called on partially read packets.
+func (p Packet) IsLegacy() bool {
+	return p[PacketTypeOffset]&packetVersionBit == 0
+}
+
+// HopLimit returns the number of hops the Packet may still be forwarded through.
+func (p Packet) HopLimit() uint8 {
+	return p[PacketHopLimitOffset]
+}
+
+// SetHopLimit sets the hop limit of the Packet.
+func (p Packet) SetHopLimit(limit uint8) {
+	p[PacketHopLimitOffset] = limit
+}
+
+// Legacy encodes the Packet with a legacy header, for visors which don't support hop limits.
+func (p Packet) Legacy() []byte {
+	legacy := make([]byte, len(p)-1)
+
+	copy(legacy, p[:LegacyPacketHeaderSize])
+	legacy[PacketTypeOffset] &^= packetVersionBit
+	copy(legacy[LegacyPacketHeaderSize:], p[PacketPayloadOffset:])
+
+	return legacy
+}
+
+// FromLegacy converts a packet with a legacy header into a Packet with DefaultHopLimit.
+// Legacy visors don't keep the hop limit, so it starts over once a packet passes a legacy hop,
+// loops which go through legacy visors are only detected if they have more than DefaultHopLimit
+// hops between the legacy ones.
+func FromLegacy(legacy []byte) Packet {
+	packet := make(Packet, len(legacy)+1)
+
+	copy(packet, legacy[:LegacyPacketHeaderSize])
+	packet[PacketTypeOffset] |= packetVersionBit
+	packet[PacketHopLimitOffset] = DefaultHopLimit
+	copy(packet[PacketPayloadOffset:], legacy[LegacyPacketHeaderSize:])
+
+	return packet
 }
 
 // Size returns Packet's payload size.
@@ -171,3 +462,32 @@ func (p Packet) RouteID() RouteID {
 func (p Packet) Payload() []byte {
 	return p[PacketPayloadOffset:]
 }
+
+// HandshakeFlags returns flags of a HandshakePacket. Zero flags are returned
+// if the packet comes from a visor which doesn't send them.
+func (p Packet) HandshakeFlags() HandshakeFlags {
+	if len(p) < PacketPayloadOffset+2 || p.Size() < 2 {
+		return 0
+	}
+
+	return HandshakeFlags(p[PacketPayloadOffset+1])
+}
+
+// HandshakeWindow returns the credit window announced within a HandshakePacket.
+// Zero is returned if the packet lacks HandshakeFlowControl flag.
+func (p Packet) HandshakeWindow() uint32 {
+	if !p.HandshakeFlags().Has(HandshakeFlowControl) || len(p) < PacketPayloadOffset+6 || p.Size() < 6 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(p[PacketPayloadOffset+2:])
+}
+
+// Credit returns the credit limit of a CreditPacket.
+func (p Packet) Credit() uint32 {
+	if len(p) < PacketPayloadOffset+4 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(p[PacketPayloadOffset:])
+}
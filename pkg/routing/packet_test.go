@@ -2,6 +2,9 @@ package routing
 
 import (
 	"testing"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -11,9 +14,11 @@ func TestMakeDataPacket(t *testing.T) {
 	packet, err := MakeDataPacket(2, []byte("foo"))
 	require.NoError(t, err)
 
-	expected := []byte{0x0, 0x0, 0x0, 0x0, 0x2, 0x0, 0x3, 0x66, 0x6f, 0x6f}
+	expected := []byte{0x80, 0x0, 0x0, 0x0, 0x2, 0x0, 0x3, 0x40, 0x66, 0x6f, 0x6f}
+	legacy := []byte{0x0, 0x0, 0x0, 0x0, 0x2, 0x0, 0x3, 0x66, 0x6f, 0x6f}
 
 	assert.Equal(t, expected, []byte(packet))
+	assert.Equal(t, legacy, packet.Legacy())
 	assert.Equal(t, uint16(3), packet.Size())
 	assert.Equal(t, RouteID(2), packet.RouteID())
 	assert.Equal(t, []byte("foo"), packet.Payload())
@@ -21,9 +26,11 @@ func TestMakeDataPacket(t *testing.T) {
 
 func TestMakeClosePacket(t *testing.T) {
 	packet := MakeClosePacket(3, CloseRequested)
-	expected := []byte{0x1, 0x0, 0x0, 0x0, 0x3, 0x0, 0x1, 0x0}
+	expected := []byte{0x81, 0x0, 0x0, 0x0, 0x3, 0x0, 0x1, 0x40, 0x0}
+	legacy := []byte{0x1, 0x0, 0x0, 0x0, 0x3, 0x0, 0x1, 0x0}
 
 	assert.Equal(t, expected, []byte(packet))
+	assert.Equal(t, legacy, packet.Legacy())
 	assert.Equal(t, uint16(1), packet.Size())
 	assert.Equal(t, RouteID(3), packet.RouteID())
 	assert.Equal(t, []byte{0x0}, packet.Payload())
@@ -31,10 +38,130 @@ func TestMakeClosePacket(t *testing.T) {
 
 func TestMakeKeepAlivePacket(t *testing.T) {
 	packet := MakeKeepAlivePacket(4)
-	expected := []byte{0x2, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0}
+	expected := []byte{0x82, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0, 0x40}
+	legacy := []byte{0x2, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0}
 
 	assert.Equal(t, expected, []byte(packet))
+	assert.Equal(t, legacy, packet.Legacy())
 	assert.Equal(t, uint16(0), packet.Size())
 	assert.Equal(t, RouteID(4), packet.RouteID())
 	assert.Equal(t, []byte{}, packet.Payload())
 }
+
+func TestMakeHandshakePacket(t *testing.T) {
+	packet := MakeHandshakePacket(5, true, 0, 0)
+	expected := []byte{0x3, 0x0, 0x0, 0x0, 0x5, 0x0, 0x1, 0x1}
+
+	assert.Equal(t, expected, packet.Legacy())
+	assert.Equal(t, HandshakeFlags(0), packet.HandshakeFlags())
+
+	packet = MakeHandshakePacket(5, false, HandshakeMultiRoute, 10)
+	expected = []byte{0x3, 0x0, 0x0, 0x0, 0x5, 0x0, 0x2, 0x0, 0x1}
+
+	assert.Equal(t, expected, packet.Legacy())
+	assert.Equal(t, uint16(2), packet.Size())
+	assert.True(t, packet.HandshakeFlags().Has(HandshakeMultiRoute))
+	assert.Equal(t, uint32(0), packet.HandshakeWindow())
+
+	packet = MakeHandshakePacket(5, true, HandshakeFlowControl, 10)
+	expected = []byte{0x3, 0x0, 0x0, 0x0, 0x5, 0x0, 0x6, 0x1, 0x4, 0x0, 0x0, 0x0, 0xa}
+
+	assert.Equal(t, expected, packet.Legacy())
+	assert.Equal(t, uint32(10), packet.HandshakeWindow())
+}
+
+func TestMakeCreditPacket(t *testing.T) {
+	packet := MakeCreditPacket(2, 1024)
+	expected := []byte{0x6, 0x0, 0x0, 0x0, 0x2, 0x0, 0x4, 0x0, 0x0, 0x4, 0x0}
+
+	assert.Equal(t, expected, packet.Legacy())
+	assert.Equal(t, CreditPacket, packet.Type())
+	assert.Equal(t, uint32(1024), packet.Credit())
+}
+
+func TestPacket_HopLimit(t *testing.T) {
+	packet, err := MakeDataPacket(2, []byte("foo"))
+	require.NoError(t, err)
+
+	assert.False(t, packet.IsLegacy())
+	assert.Equal(t, uint8(DefaultHopLimit), packet.HopLimit())
+
+	packet.SetHopLimit(3)
+	assert.Equal(t, uint8(3), packet.HopLimit())
+	assert.Equal(t, DataPacket, packet.Type())
+
+	legacy := packet.Legacy()
+	assert.True(t, Packet(legacy).IsLegacy())
+
+	converted := FromLegacy(legacy)
+	assert.False(t, converted.IsLegacy())
+	assert.Equal(t, DataPacket, converted.Type())
+	assert.Equal(t, RouteID(2), converted.RouteID())
+	assert.Equal(t, uint8(DefaultHopLimit), converted.HopLimit())
+	assert.Equal(t, []byte("foo"), converted.Payload())
+}
+
+func TestMakeTracePacket(t *testing.T) {
+	pk1, _ := cipher.GenerateKeyPair()
+	pk2, _ := cipher.GenerateKeyPair()
+
+	hops := []TraceHop{
+		{PK: pk1, Time: time.Unix(0, 1)},
+		{PK: pk2, Time: time.Unix(0, 2)},
+	}
+
+	packet, err := MakeTracePacket(6, 7, true, hops)
+	require.NoError(t, err)
+
+	assert.Equal(t, TracePacket, packet.Type())
+	assert.Equal(t, RouteID(6), packet.RouteID())
+
+	traceID, returning, gotHops, err := packet.Trace()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(7), traceID)
+	assert.True(t, returning)
+	assert.Equal(t, hops, gotHops)
+
+	packet, err = MakeTracePacket(6, 7, false, nil)
+	require.NoError(t, err)
+
+	_, returning, gotHops, err = packet.Trace()
+	require.NoError(t, err)
+	assert.False(t, returning)
+	assert.Empty(t, gotHops)
+
+	_, _, _, err = Packet(packet[:len(packet)-1]).Trace()
+	assert.Equal(t, ErrBadTracePayload, err)
+}
+
+func TestMakeTransportProbePacket(t *testing.T) {
+	packet := MakeTransportProbePacket(3, true)
+	expected := []byte{0x7, 0x0, 0x0, 0x0, 0x0, 0x0, 0x5, 0x1, 0x0, 0x0, 0x0, 0x3}
+
+	assert.Equal(t, expected, packet.Legacy())
+	assert.Equal(t, TransportProbePacket, packet.Type())
+
+	seq, reply, err := packet.TransportProbe()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), seq)
+	assert.True(t, reply)
+
+	_, reply, err = MakeTransportProbePacket(4, false).TransportProbe()
+	require.NoError(t, err)
+	assert.False(t, reply)
+
+	_, _, err = Packet(packet[:len(packet)-1]).TransportProbe()
+	assert.Equal(t, ErrBadProbePayload, err)
+}
+
+func TestCloseCode_Transient(t *testing.T) {
+	require.True(t, CloseKeepAliveTimeout.Transient())
+	require.True(t, CloseRuleExpired.Transient())
+	require.True(t, CloseTransportFailed.Transient())
+	require.False(t, CloseRequested.Transient())
+	require.False(t, ClosePolicyRejected.Transient())
+	require.False(t, CloseNoListener.Transient())
+
+	var err error = &CloseError{Code: CloseNoListener}
+	require.Equal(t, "route group closed: No listener on port", err.Error())
+}
@@ -42,6 +42,13 @@ type BidirectionalRoute struct {
 	KeepAlive time.Duration
 	Forward   []Hop
 	Reverse   []Hop
+
+	// Attach is set if the route is one more route of the existing route group of 'Desc'.
+	// Otherwise, the route makes a new route group which replaces the existing one, if any.
+	Attach bool
+	// Replaces is the key route ID of the responding edge's reverse rule of the route the attached route replaces.
+	// Zero if it doesn't replace any.
+	Replaces RouteID
 }
 
 // ForwardAndReverse generate forward and reverse routes for bidirectional route.
@@ -104,6 +111,16 @@ type EdgeRules struct {
 	Desc    RouteDescriptor
 	Forward Rule
 	Reverse Rule
+
+	// Attach is set if the rules are of one more route of the existing route group of 'Desc'.
+	// Otherwise, the rules make a new route group which replaces the existing one, if any.
+	Attach bool
+	// Replaces is the key route ID of the reverse rule of the route the attached route replaces.
+	// Zero if it doesn't replace any.
+	Replaces RouteID
+	// RemoteReverseID is the key route ID of the reverse rule of the route on the remote edge.
+	// Edges refer to the routes of the remote by it.
+	RemoteReverseID RouteID
 }
 
 // String implements fmt.Stringer
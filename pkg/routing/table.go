@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +17,8 @@ var (
 	ErrRuleTimedOut = errors.New("rule keep-alive timeout exceeded")
 	// ErrNoAvailableRoutes is returned when there're no more available routeIDs
 	ErrNoAvailableRoutes = errors.New("no available routeIDs")
+	// ErrTableClosed is returned when closing an already closed routing table
+	ErrTableClosed = errors.New("routing table is already closed")
 )
 
 // Table represents a routing table implementation.
@@ -22,6 +26,9 @@ type Table interface {
 	// ReserveKeys reserves n RouteIDs.
 	ReserveKeys(n int) ([]RouteID, error)
 
+	// ReleaseKeys releases reserved RouteIDs which are not used by any rule, so they may be reserved again.
+	ReleaseKeys([]RouteID)
+
 	// SaveRule sets RoutingRule for a given RouteID.
 	SaveRule(Rule) error
 
@@ -44,53 +51,162 @@ type Table interface {
 
 	// CollectGarbage checks all the stored rules, removes and returns ones that timed out.
 	CollectGarbage() []Rule
+
+	// RecordForward updates forwarding statistics of the rule with a given RouteID.
+	// Packet of 'size' payload bytes is counted if 'err' is nil, otherwise 'err' is recorded as the last error.
+	RecordForward(key RouteID, size int, err error)
+
+	// RuleStats returns forwarding statistics of the rule with a given RouteID.
+	RuleStats(RouteID) RuleStats
+}
+
+// RuleStats is forwarding statistics of a rule.
+type RuleStats struct {
+	Packets     uint64    `json:"packets"`
+	Bytes       uint64    `json:"bytes"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitempty"`
+}
+
+// RuleEntry is a rule along with its forwarding statistics.
+type RuleEntry struct {
+	Rule  Rule      `json:"rule"`
+	Stats RuleStats `json:"stats"`
 }
 
 type memTable struct {
 	sync.RWMutex
 
 	nextID   RouteID
+	free     map[RouteID]struct{} // released IDs below 'nextID'
 	rules    map[RouteID]Rule
 	activity map[RouteID]time.Time
+
+	// stats are updated per forwarded packet, so they're guarded separately from the rules:
+	// the map is only write-locked when rules are added or removed, counters are atomic.
+	statsMx sync.RWMutex
+	stats   map[RouteID]*ruleCounters
+}
+
+// ruleCounters are forwarding statistics of a rule which are updated concurrently.
+type ruleCounters struct {
+	packets uint64 // atomic, kept first to be 64-bit aligned
+	bytes   uint64 // atomic
+
+	errMx       sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func (rc *ruleCounters) record(size int, err error) {
+	if err != nil {
+		rc.errMx.Lock()
+		rc.lastError = err.Error()
+		rc.lastErrorAt = time.Now()
+		rc.errMx.Unlock()
+
+		return
+	}
+
+	atomic.AddUint64(&rc.packets, 1)
+	atomic.AddUint64(&rc.bytes, uint64(size))
+}
+
+func (rc *ruleCounters) stats() RuleStats {
+	rc.errMx.Lock()
+	defer rc.errMx.Unlock()
+
+	return RuleStats{
+		Packets:     atomic.LoadUint64(&rc.packets),
+		Bytes:       atomic.LoadUint64(&rc.bytes),
+		LastError:   rc.lastError,
+		LastErrorAt: rc.lastErrorAt,
+	}
 }
 
 // NewTable returns an in-memory routing table implementation with a specified configuration.
 func NewTable() Table {
-	mt := &memTable{
+	return newMemTable()
+}
+
+func newMemTable() *memTable {
+	return &memTable{
+		free:     make(map[RouteID]struct{}),
 		rules:    map[RouteID]Rule{},
 		activity: make(map[RouteID]time.Time),
+		stats:    make(map[RouteID]*ruleCounters),
 	}
-
-	return mt
 }
 
 func (mt *memTable) ReserveKeys(n int) ([]RouteID, error) {
-	first, last, err := mt.reserveKeysImpl(n)
-	if err != nil {
-		return nil, err
+	mt.Lock()
+	defer mt.Unlock()
+
+	return mt.reserveKeysImpl(n)
+}
+
+// reserveKeysImpl reserves the released IDs first, the rest are reserved after the last reserved ID.
+func (mt *memTable) reserveKeysImpl(n int) ([]RouteID, error) {
+	fresh := n - len(mt.free)
+	if fresh < 0 {
+		fresh = 0
+	}
+
+	if int64(mt.nextID)+int64(fresh) >= math.MaxUint32 {
+		return nil, ErrNoAvailableRoutes
 	}
 
 	routes := make([]RouteID, 0, n)
-	for id := first; id <= last; id++ {
+	for id := range mt.free {
+		if len(routes) == n-fresh {
+			break
+		}
+
 		routes = append(routes, id)
+		delete(mt.free, id)
 	}
 
+	for i := 0; i < fresh; i++ {
+		mt.nextID++
+		routes = append(routes, mt.nextID)
+	}
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i] < routes[j] })
+
 	return routes, nil
 }
 
-func (mt *memTable) reserveKeysImpl(n int) (first, last RouteID, err error) {
+func (mt *memTable) ReleaseKeys(keys []RouteID) {
 	mt.Lock()
 	defer mt.Unlock()
 
-	if int64(mt.nextID)+int64(n) >= math.MaxUint32 {
-		return 0, 0, ErrNoAvailableRoutes
+	mt.releaseKeysImpl(keys)
+}
+
+// releaseKeysImpl puts the released keys which aren't used by rules to the free list. The last reserved ID
+// is rolled back over the free keys, so the free list is kept short. It reports whether the last ID is changed.
+func (mt *memTable) releaseKeysImpl(keys []RouteID) bool {
+	for _, key := range keys {
+		if _, ok := mt.rules[key]; ok || key == 0 || key > mt.nextID {
+			continue
+		}
+
+		mt.free[key] = struct{}{}
 	}
 
-	first = mt.nextID + 1
-	mt.nextID += RouteID(n)
-	last = mt.nextID
+	changed := false
 
-	return first, last, nil
+	for mt.nextID > 0 {
+		if _, ok := mt.free[mt.nextID]; !ok {
+			break
+		}
+
+		delete(mt.free, mt.nextID)
+		mt.nextID--
+		changed = true
+	}
+
+	return changed
 }
 
 func (mt *memTable) SaveRule(rule Rule) error {
@@ -104,6 +220,12 @@ func (mt *memTable) SaveRule(rule Rule) error {
 	fmt.Printf("ROUTING TABLE CONTENTS: %v\n", mt.rules)
 	mt.activity[key] = now
 
+	mt.statsMx.Lock()
+	if _, ok := mt.stats[key]; !ok {
+		mt.stats[key] = &ruleCounters{}
+	}
+	mt.statsMx.Unlock()
+
 	return nil
 }
 
@@ -189,6 +311,33 @@ func (mt *memTable) DelRules(keys []RouteID) {
 func (mt *memTable) delRule(key RouteID) {
 	delete(mt.rules, key)
 	delete(mt.activity, key)
+
+	mt.statsMx.Lock()
+	delete(mt.stats, key)
+	mt.statsMx.Unlock()
+}
+
+func (mt *memTable) RecordForward(key RouteID, size int, err error) {
+	mt.statsMx.RLock()
+	counters, ok := mt.stats[key]
+	mt.statsMx.RUnlock()
+
+	// statistics are kept only for the saved rules
+	if ok {
+		counters.record(size, err)
+	}
+}
+
+func (mt *memTable) RuleStats(key RouteID) RuleStats {
+	mt.statsMx.RLock()
+	counters, ok := mt.stats[key]
+	mt.statsMx.RUnlock()
+
+	if !ok {
+		return RuleStats{}
+	}
+
+	return counters.stats()
 }
 
 func (mt *memTable) Count() int {
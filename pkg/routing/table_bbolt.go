@@ -0,0 +1,219 @@
+package routing
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	boltRulesBucket = []byte("rules")
+	boltMetaBucket  = []byte("meta")
+	boltNextIDKey   = []byte("next_id")
+)
+
+// boltTable is a routing table that keeps its rules and reserved route IDs in a bbolt database,
+// so that rules survive visor restarts.
+// Reads are served from an in-memory table which is populated from the database on startup.
+// The free list of released route IDs is kept in memory only, so they're not reused after a restart.
+type boltTable struct {
+	*memTable
+
+	log    *logging.Logger
+	db     *bbolt.DB
+	idMx   sync.Mutex // serializes reservation of route IDs with persisting them
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewBoltTable returns a bbolt implementation of a routing table stored at 'path'.
+// Rules stored by previous runs are loaded back. As activity of rules is not persisted,
+// loaded rules are given a fresh keep-alive window and expire as usual if it is not refreshed.
+func NewBoltTable(log *logging.Logger, path string) (Table, error) {
+	if log == nil {
+		log = logging.MustGetLogger("routing_table")
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open routing table db: %w", err)
+	}
+
+	bt := &boltTable{
+		memTable: newMemTable(),
+		log:      log,
+		db:       db,
+		closed:   make(chan struct{}),
+	}
+
+	if err := bt.load(); err != nil {
+		if cErr := db.Close(); cErr != nil {
+			log.WithError(cErr).Warn("Failed to close routing table db.")
+		}
+
+		return nil, err
+	}
+
+	return bt, nil
+}
+
+func (bt *boltTable) load() error {
+	now := time.Now()
+
+	return bt.db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(boltMetaBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+
+		rules, err := tx.CreateBucketIfNotExists(boltRulesBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+
+		if v := meta.Get(boltNextIDKey); len(v) == 4 {
+			bt.nextID = RouteID(binary.BigEndian.Uint32(v))
+		}
+
+		return rules.ForEach(func(k, v []byte) error {
+			if len(k) != 4 || len(v) < RuleHeaderSize {
+				bt.log.Warnf("Skipping malformed routing rule entry of key %x.", k)
+				return nil
+			}
+
+			rule := append(Rule{}, v...)
+			key := rule.KeyRouteID()
+
+			bt.rules[key] = rule
+			bt.activity[key] = now
+			bt.stats[key] = &ruleCounters{}
+
+			if key > bt.nextID {
+				bt.nextID = key
+			}
+
+			return nil
+		})
+	})
+}
+
+func (bt *boltTable) ReserveKeys(n int) ([]RouteID, error) {
+	bt.idMx.Lock()
+	defer bt.idMx.Unlock()
+
+	bt.memTable.Lock()
+	routes, err := bt.reserveKeysImpl(n)
+	nextID := bt.nextID
+	bt.memTable.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = bt.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltMetaBucket).Put(boltNextIDKey, routeIDKey(nextID))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to persist reserved route IDs: %w", err)
+	}
+
+	return routes, nil
+}
+
+func (bt *boltTable) ReleaseKeys(keys []RouteID) {
+	bt.idMx.Lock()
+	defer bt.idMx.Unlock()
+
+	bt.memTable.Lock()
+	changed := bt.releaseKeysImpl(keys)
+	nextID := bt.nextID
+	bt.memTable.Unlock()
+
+	if !changed {
+		return
+	}
+
+	err := bt.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltMetaBucket).Put(boltNextIDKey, routeIDKey(nextID))
+	})
+	if err != nil {
+		bt.log.WithError(err).Warn("Failed to persist released route IDs.")
+	}
+}
+
+func (bt *boltTable) SaveRule(rule Rule) error {
+	err := bt.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltRulesBucket).Put(routeIDKey(rule.KeyRouteID()), rule)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to persist rule: %w", err)
+	}
+
+	return bt.memTable.SaveRule(rule)
+}
+
+func (bt *boltTable) DelRules(keys []RouteID) {
+	bt.memTable.DelRules(keys)
+	bt.delPersisted(keys)
+}
+
+func (bt *boltTable) CollectGarbage() []Rule {
+	timedOutRules := bt.memTable.CollectGarbage()
+	if len(timedOutRules) == 0 {
+		return timedOutRules
+	}
+
+	keys := make([]RouteID, 0, len(timedOutRules))
+	for _, rule := range timedOutRules {
+		keys = append(keys, rule.KeyRouteID())
+	}
+
+	bt.delPersisted(keys)
+
+	return timedOutRules
+}
+
+// Close implements io.Closer.
+func (bt *boltTable) Close() error {
+	err := ErrTableClosed
+
+	bt.once.Do(func() {
+		close(bt.closed)
+		err = bt.db.Close()
+	})
+
+	return err
+}
+
+func (bt *boltTable) delPersisted(keys []RouteID) {
+	select {
+	case <-bt.closed:
+		return
+	default:
+	}
+
+	err := bt.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(boltRulesBucket)
+		for _, key := range keys {
+			if err := b.Delete(routeIDKey(key)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		bt.log.WithError(err).Warnf("Failed to delete persisted rules %v.", keys)
+	}
+}
+
+func routeIDKey(id RouteID) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(id))
+
+	return b
+}
@@ -1,8 +1,12 @@
 package routing
 
 import (
+	"errors"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -65,10 +69,125 @@ func RoutingTableSuite(t *testing.T, tbl Table) {
 
 	require.ElementsMatch(t, []RouteID{id[0], id2[0]}, ids)
 
+	tbl.RecordForward(id[0], 100, nil)
+	tbl.RecordForward(id[0], 50, nil)
+	tbl.RecordForward(id[0], 0, errors.New("unknown transport"))
+
+	stats := tbl.RuleStats(id[0])
+	assert.Equal(t, uint64(2), stats.Packets)
+	assert.Equal(t, uint64(150), stats.Bytes)
+	assert.Equal(t, "unknown transport", stats.LastError)
+	assert.False(t, stats.LastErrorAt.IsZero())
+	assert.Equal(t, RuleStats{}, tbl.RuleStats(id2[0]))
+
 	tbl.DelRules([]RouteID{id[0], id2[0]})
 	assert.Equal(t, 0, tbl.Count())
+	assert.Equal(t, RuleStats{}, tbl.RuleStats(id[0]))
+
+	// statistics are not kept for unknown rules
+	tbl.RecordForward(id[0], 100, nil)
+	assert.Equal(t, RuleStats{}, tbl.RuleStats(id[0]))
+
+	// released IDs are reused if nothing was reserved after them
+	unused, err := tbl.ReserveKeys(2)
+	require.NoError(t, err)
+	tbl.ReleaseKeys(unused)
+
+	reused, err := tbl.ReserveKeys(2)
+	require.NoError(t, err)
+	assert.Equal(t, unused, reused)
+
+	// and so are released IDs which were followed by other reservations
+	later, err := tbl.ReserveKeys(1)
+	require.NoError(t, err)
+	tbl.ReleaseKeys(reused)
+
+	next, err := tbl.ReserveKeys(2)
+	require.NoError(t, err)
+	assert.Equal(t, reused, next)
+
+	next, err = tbl.ReserveKeys(1)
+	require.NoError(t, err)
+	assert.Equal(t, later[0]+1, next[0])
 }
 
 func TestRoutingTable(t *testing.T) {
 	RoutingTableSuite(t, NewTable())
 }
+
+func TestRoutingTable_RecordForwardConcurrently(t *testing.T) {
+	tbl := NewTable()
+
+	ids, err := tbl.ReserveKeys(1)
+	require.NoError(t, err)
+	require.NoError(t, tbl.SaveRule(IntermediaryForwardRule(15*time.Minute, ids[0], 2, uuid.New())))
+
+	const workers, packets = 8, 1000
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < packets; j++ {
+				tbl.RecordForward(ids[0], 10, nil)
+				tbl.RuleStats(ids[0])
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := tbl.RuleStats(ids[0])
+	assert.Equal(t, uint64(workers*packets), stats.Packets)
+	assert.Equal(t, uint64(workers*packets*10), stats.Bytes)
+
+	// counters of the rule are kept once it's updated
+	require.NoError(t, tbl.SaveRule(IntermediaryForwardRule(15*time.Minute, ids[0], 3, uuid.New())))
+	assert.Equal(t, stats, tbl.RuleStats(ids[0]))
+}
+
+func TestBoltTable(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "routing.db")
+
+	tbl, err := NewBoltTable(nil, dbPath)
+	require.NoError(t, err)
+	RoutingTableSuite(t, tbl)
+
+	ids, err := tbl.ReserveKeys(2)
+	require.NoError(t, err)
+
+	rule := IntermediaryForwardRule(15*time.Minute, ids[0], 2, uuid.New())
+	require.NoError(t, tbl.SaveRule(rule))
+
+	expired := IntermediaryForwardRule(50*time.Millisecond, ids[1], 3, uuid.New())
+	require.NoError(t, tbl.SaveRule(expired))
+	require.NoError(t, tbl.(io.Closer).Close())
+
+	// rules and reserved IDs survive a restart
+	tbl, err = NewBoltTable(nil, dbPath)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, tbl.(io.Closer).Close())
+	}()
+
+	assert.Equal(t, 2, tbl.Count())
+
+	r, err := tbl.Rule(ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, rule, r)
+
+	next, err := tbl.ReserveKeys(1)
+	require.NoError(t, err)
+	assert.Equal(t, ids[1]+1, next[0])
+
+	// keep-alive expiry is still honoured
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []Rule{expired}, tbl.CollectGarbage())
+	require.NoError(t, tbl.(io.Closer).Close())
+
+	tbl, err = NewBoltTable(nil, dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tbl.Count())
+}
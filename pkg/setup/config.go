@@ -23,4 +23,14 @@ type Config struct {
 	Dmsg               snet.DmsgConfig `json:"dmsg"`
 	TransportDiscovery string          `json:"transport_discovery"`
 	LogLevel           string          `json:"log_level"`
+	Limits             LimitsConfig    `json:"limits"`
+}
+
+// LimitsConfig defines limits of route setup requests, zero value means no limit.
+// Rates and per-PK limits are applied to each of the requesting visors separately.
+type LimitsConfig struct {
+	RequestsPerMinute  int `json:"requests_per_minute"`
+	Burst              int `json:"burst"`
+	MaxConcurrentPerPK int `json:"max_concurrent_per_pk"`
+	MaxConcurrent      int `json:"max_concurrent"`
 }
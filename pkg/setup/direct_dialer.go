@@ -0,0 +1,73 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/skycoin/dmsg"
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/setup/setupclient"
+	"github.com/skycoin/skywire/pkg/snet"
+)
+
+type directDialer struct{}
+
+// NewDirectDialer returns setupclient.DirectDialer which creates route groups the same way
+// the setup node does. Routers of the route are dialed by the initiating visor, so they
+// have to trust it.
+func NewDirectDialer() setupclient.DirectDialer {
+	return new(directDialer)
+}
+
+// DialDirect creates route group of `req` via routers of the route.
+func (d *directDialer) DialDirect(
+	ctx context.Context,
+	log *logging.Logger,
+	n *snet.Network,
+	local setupclient.ConnServer,
+	req routing.BidirectionalRoute,
+) (routing.EdgeRules, error) {
+	log.Debugf("Setting route group %s up directly", &req.Desc)
+
+	dialer := &localDialer{
+		n:       n,
+		localPK: n.LocalPK(),
+		local:   local,
+	}
+
+	rules, err := CreateRouteGroup(ctx, dialer, req)
+	if err != nil {
+		return routing.EdgeRules{}, fmt.Errorf("direct route setup: %w", err)
+	}
+
+	return rules, nil
+}
+
+// localDialer serves dials to the local visor via pipe to the local router RPC server,
+// the rest are dialed via dmsg.
+type localDialer struct {
+	n       *snet.Network
+	localPK cipher.PubKey
+	local   setupclient.ConnServer
+}
+
+// Dial implements snet.Dialer.
+func (d *localDialer) Dial(ctx context.Context, remote cipher.PubKey, port uint16) (net.Conn, error) {
+	if remote != d.localPK {
+		return d.n.Dial(ctx, dmsg.Type, remote, port)
+	}
+
+	conn, srvConn := net.Pipe()
+	go d.local.ServeConn(srvConn)
+
+	return conn, nil
+}
+
+// Type implements snet.Dialer.
+func (d *localDialer) Type() string {
+	return dmsg.Type
+}
@@ -0,0 +1,32 @@
+package setup
+
+import (
+	"context"
+	"net/rpc"
+	"testing"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire/pkg/router/routerclient"
+)
+
+func TestLocalDialer_Dial(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+
+	local := rpc.NewServer()
+	require.NoError(t, local.RegisterName(routerclient.RPCName, new(mockGatewayForDialer)))
+
+	dialer := &localDialer{localPK: pk, local: local}
+
+	ctx := context.Background()
+
+	// local router is served without going through the network
+	rc, err := routerclient.NewClient(ctx, dialer, pk)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, rc.Close()) }()
+
+	ids, err := rc.ReserveIDs(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+}
@@ -37,6 +37,9 @@ type IDReserver interface {
 	// TotalIDs returns the total number of route IDs we have reserved from the routers.
 	TotalIDs() int
 
+	// ReservedIDs returns all the route IDs reserved from the routers, popped ones included.
+	ReservedIDs() map[cipher.PubKey][]routing.RouteID
+
 	// Client returns a router client of given public key.
 	Client(pk cipher.PubKey) *routerclient.Client
 }
@@ -46,6 +49,7 @@ type idReserver struct {
 	rcM   routerclient.Map                    // map of router clients
 	rec   map[cipher.PubKey]uint8             // this records the number of expected rules per visor PK
 	ids   map[cipher.PubKey][]routing.RouteID // this records the obtained rules per visor PK
+	res   map[cipher.PubKey][]routing.RouteID // this records all the reserved rules per visor PK
 	mx    sync.Mutex
 }
 
@@ -83,6 +87,7 @@ func NewIDReserver(ctx context.Context, dialer snet.Dialer, paths [][]routing.Ho
 		rcM:   clients,
 		rec:   rec,
 		ids:   make(map[cipher.PubKey][]routing.RouteID, total),
+		res:   make(map[cipher.PubKey][]routing.RouteID, total),
 	}, nil
 }
 
@@ -103,6 +108,7 @@ func (idr *idReserver) ReserveIDs(ctx context.Context) error {
 			}
 			idr.mx.Lock()
 			idr.ids[pk] = rtIDs
+			idr.res[pk] = rtIDs
 			idr.mx.Unlock()
 			errCh <- nil
 		}(pk, n)
@@ -129,6 +135,18 @@ func (idr *idReserver) TotalIDs() int {
 	return idr.total
 }
 
+func (idr *idReserver) ReservedIDs() map[cipher.PubKey][]routing.RouteID {
+	idr.mx.Lock()
+	defer idr.mx.Unlock()
+
+	res := make(map[cipher.PubKey][]routing.RouteID, len(idr.res))
+	for pk, ids := range idr.res {
+		res[pk] = ids
+	}
+
+	return res
+}
+
 func (idr *idReserver) Client(pk cipher.PubKey) *routerclient.Client {
 	return idr.rcM[pk]
 }
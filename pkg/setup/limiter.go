@@ -0,0 +1,164 @@
+package setup
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+
+	"github.com/skycoin/skywire/pkg/setup/setupclient"
+)
+
+// ErrRequestRejected is returned when the request exceeds limits of the setup node.
+var ErrRequestRejected = setupclient.ErrRequestRejected
+
+// Reasons of request rejections.
+const (
+	RejectRateLimit         = "rate_limit"
+	RejectVisorConcurrency  = "visor_concurrency"
+	RejectGlobalConcurrency = "global_concurrency"
+)
+
+// RejectionError is returned when the request is rejected, it wraps ErrRequestRejected.
+type RejectionError struct {
+	Reason string
+}
+
+// Error implements error.
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRequestRejected, e.Reason)
+}
+
+// Unwrap returns ErrRequestRejected.
+func (e *RejectionError) Unwrap() error {
+	return ErrRequestRejected
+}
+
+// visorLimits keeps the state of limits of a single requesting visor.
+type visorLimits struct {
+	tokens  float64 // requests available in the rate limit bucket
+	updated time.Time
+	active  int
+}
+
+// Limiter limits route setup requests by rate and concurrency.
+// Nil Limiter doesn't limit anything.
+type Limiter struct {
+	conf LimitsConfig
+	now  func() time.Time
+
+	mx     sync.Mutex
+	visors map[cipher.PubKey]*visorLimits
+	active int
+	swept  time.Time
+}
+
+// NewLimiter constructs new Limiter.
+func NewLimiter(conf LimitsConfig) *Limiter {
+	return &Limiter{
+		conf:   conf,
+		now:    time.Now,
+		visors: make(map[cipher.PubKey]*visorLimits),
+	}
+}
+
+// Acquire checks whether a request of visor `pk` may be processed. If so, `release` should be called
+// once the request is processed. Otherwise, *RejectionError is returned.
+func (l *Limiter) Acquire(pk cipher.PubKey) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	v, ok := l.visors[pk]
+	if !ok {
+		v = &visorLimits{tokens: float64(l.burst()), updated: now}
+		l.visors[pk] = v
+	}
+
+	if l.conf.MaxConcurrent > 0 && l.active >= l.conf.MaxConcurrent {
+		return nil, &RejectionError{Reason: RejectGlobalConcurrency}
+	}
+
+	if l.conf.MaxConcurrentPerPK > 0 && v.active >= l.conf.MaxConcurrentPerPK {
+		return nil, &RejectionError{Reason: RejectVisorConcurrency}
+	}
+
+	if l.conf.RequestsPerMinute > 0 {
+		l.refill(v, now)
+
+		if v.tokens < 1 {
+			return nil, &RejectionError{Reason: RejectRateLimit}
+		}
+
+		v.tokens--
+	}
+
+	v.active++
+	l.active++
+
+	var once sync.Once
+
+	release = func() {
+		once.Do(func() {
+			l.mx.Lock()
+			defer l.mx.Unlock()
+
+			v.active--
+			l.active--
+		})
+	}
+
+	return release, nil
+}
+
+// burst is the max number of requests of a visor allowed at once by the rate limit.
+func (l *Limiter) burst() int {
+	if l.conf.Burst > 0 {
+		return l.conf.Burst
+	}
+
+	return 1
+}
+
+func (l *Limiter) refill(v *visorLimits, now time.Time) {
+	rate := float64(l.conf.RequestsPerMinute) / float64(time.Minute)
+
+	v.tokens += float64(now.Sub(v.updated)) * rate
+	if burst := float64(l.burst()); v.tokens > burst {
+		v.tokens = burst
+	}
+
+	v.updated = now
+}
+
+// sweep forgets visors which have no active requests and no rate limit to remember, once a minute.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+
+	l.swept = now
+
+	for pk, v := range l.visors {
+		if v.active > 0 {
+			continue
+		}
+
+		if l.conf.RequestsPerMinute > 0 {
+			l.refill(v, now)
+
+			if v.tokens < float64(l.burst()) {
+				continue
+			}
+		}
+
+		delete(l.visors, pk)
+	}
+}
@@ -0,0 +1,77 @@
+package setup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+
+	requireRejected := func(t *testing.T, l *Limiter, pk cipher.PubKey, reason string) {
+		_, err := l.Acquire(pk)
+		require.True(t, errors.Is(err, ErrRequestRejected))
+		require.Equal(t, &RejectionError{Reason: reason}, err)
+	}
+
+	t.Run("nil limiter", func(t *testing.T) {
+		var l *Limiter
+
+		release, err := l.Acquire(pkA)
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		now := time.Now()
+
+		l := NewLimiter(LimitsConfig{RequestsPerMinute: 2, Burst: 2})
+		l.now = func() time.Time { return now }
+
+		for i := 0; i < 2; i++ {
+			release, err := l.Acquire(pkA)
+			require.NoError(t, err)
+			release()
+		}
+
+		requireRejected(t, l, pkA, RejectRateLimit)
+
+		// Other visors aren't affected.
+		_, err := l.Acquire(pkB)
+		require.NoError(t, err)
+
+		now = now.Add(30 * time.Second)
+
+		_, err = l.Acquire(pkA)
+		require.NoError(t, err)
+		requireRejected(t, l, pkA, RejectRateLimit)
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		l := NewLimiter(LimitsConfig{MaxConcurrentPerPK: 1, MaxConcurrent: 2})
+
+		releaseA, err := l.Acquire(pkA)
+		require.NoError(t, err)
+		requireRejected(t, l, pkA, RejectVisorConcurrency)
+
+		releaseB, err := l.Acquire(pkB)
+		require.NoError(t, err)
+
+		pkC, _ := cipher.GenerateKeyPair()
+		requireRejected(t, l, pkC, RejectGlobalConcurrency)
+
+		releaseA()
+		releaseA()
+		releaseB()
+
+		_, err = l.Acquire(pkA)
+		require.NoError(t, err)
+		_, err = l.Acquire(pkC)
+		require.NoError(t, err)
+	})
+}
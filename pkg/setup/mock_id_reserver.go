@@ -82,6 +82,22 @@ func (_m *MockIDReserver) ReserveIDs(ctx context.Context) error {
 	return r0
 }
 
+// ReservedIDs provides a mock function with given fields:
+func (_m *MockIDReserver) ReservedIDs() map[cipher.PubKey][]routing.RouteID {
+	ret := _m.Called()
+
+	var r0 map[cipher.PubKey][]routing.RouteID
+	if rf, ok := ret.Get(0).(func() map[cipher.PubKey][]routing.RouteID); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[cipher.PubKey][]routing.RouteID)
+		}
+	}
+
+	return r0
+}
+
 // String provides a mock function with given fields:
 func (_m *MockIDReserver) String() string {
 	ret := _m.Called()
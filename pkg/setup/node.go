@@ -2,8 +2,10 @@ package setup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/rpc"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -21,9 +23,14 @@ import (
 
 var log = logging.MustGetLogger("setup_node")
 
+// rollbackTimeout limits the time spent on undoing a failed route group creation.
+const rollbackTimeout = 10 * time.Second
+
 // Node performs routes setup operations over messaging channel.
 type Node struct {
-	dmsgC *dmsg.Client
+	dmsgC   *dmsg.Client
+	limiter *Limiter
+	tracker *RequestTracker
 }
 
 // NewNode constructs a new SetupNode.
@@ -44,7 +51,9 @@ func NewNode(conf *Config) (*Node, error) {
 	log.Info("Connected!")
 
 	node := &Node{
-		dmsgC: dmsgC,
+		dmsgC:   dmsgC,
+		limiter: NewLimiter(conf.Limits),
+		tracker: NewRequestTracker(),
 	}
 	return node, nil
 }
@@ -57,6 +66,11 @@ func (sn *Node) Close() error {
 	return sn.dmsgC.Close()
 }
 
+// Requests returns the tracker of the in-flight requests, it serves them over HTTP.
+func (sn *Node) Requests() *RequestTracker {
+	return sn.tracker
+}
+
 // Serve starts transport listening loop.
 func (sn *Node) Serve(ctx context.Context, m setupmetrics.Metrics) error {
 	ctx, cancel := context.WithCancel(ctx)
@@ -85,6 +99,8 @@ func (sn *Node) Serve(ctx context.Context, m setupmetrics.Metrics) error {
 		}
 		gw := &RPCGateway{
 			Metrics: m,
+			Limiter: sn.limiter,
+			Tracker: sn.tracker,
 			Ctx:     ctx,
 			Conn:    conn,
 			ReqPK:   conn.RemoteAddr().(dmsg.Addr).PK,
@@ -106,7 +122,21 @@ func (sn *Node) Serve(ctx context.Context, m setupmetrics.Metrics) error {
 // * Intermediary rules are broadcasted to the intermediary routers.
 // * Edge rules are broadcasted to the responding router.
 // * Edge rules is returned (to the initiating router).
-func CreateRouteGroup(ctx context.Context, dialer snet.Dialer, biRt routing.BidirectionalRoute) (resp routing.EdgeRules, err error) {
+// On failure, installed rules are deleted and reserved route IDs are released, so no state is left behind.
+func CreateRouteGroup(ctx context.Context, dialer snet.Dialer, biRt routing.BidirectionalRoute) (routing.EdgeRules, error) {
+	return createRouteGroup(ctx, dialer, biRt, noopObserver)
+}
+
+// phaseObserver is called once route group creation enters `phase`,
+// the returned func is called with the result of the phase.
+type phaseObserver func(phase setupmetrics.Phase) func(error)
+
+func noopObserver(setupmetrics.Phase) func(error) {
+	return func(error) {}
+}
+
+func createRouteGroup(ctx context.Context, dialer snet.Dialer, biRt routing.BidirectionalRoute,
+	observe phaseObserver) (resp routing.EdgeRules, err error) {
 	start := time.Now()
 	log := logging.MustGetLogger(fmt.Sprintf("request:%s->%s", biRt.Desc.SrcPK(), biRt.Desc.DstPK()))
 	log.Info("Processing request.")
@@ -121,17 +151,64 @@ func CreateRouteGroup(ctx context.Context, dialer snet.Dialer, biRt routing.Bidi
 	}()
 
 	// Ensure bi routes input is valid.
-	if err = biRt.Check(); err != nil {
+	endPhase := observe(setupmetrics.PhaseValidate)
+	err = biRt.Check()
+	endPhase(err)
+
+	if err != nil {
 		return routing.EdgeRules{}, err
 	}
 
 	// Reserve route IDs from remote routers.
+	endPhase = observe(setupmetrics.PhaseReserveIDs)
 	rtIDR, err := ReserveRouteIDs(ctx, log, dialer, biRt)
+	endPhase(err)
+
 	if err != nil {
 		return routing.EdgeRules{}, err
 	}
 	defer func() { log.WithError(rtIDR.Close()).Debug("Closing route id reserver.") }()
 
+	// Keep track of the installed rules, so that nothing is left behind on failure.
+	installed := make(RulesMap)
+	defer func() {
+		if err != nil {
+			RollbackRouteGroup(log, rtIDR, installed)
+		}
+	}()
+
+	endPhase = observe(setupmetrics.PhaseIntermediaryRules)
+	initEdge, respEdge, err := broadcastIntermediaryRules(ctx, log, rtIDR, biRt, installed)
+	endPhase(err)
+
+	if err != nil {
+		return routing.EdgeRules{}, err
+	}
+
+	// Broadcast rules to responding router.
+	log.Debug("Broadcasting responding rules...")
+	dstPK := biRt.Desc.DstPK()
+	installed[dstPK] = append(installed[dstPK], respEdge.Forward, respEdge.Reverse)
+
+	endPhase = observe(setupmetrics.PhaseEdgeRules)
+	ok, err := rtIDR.Client(dstPK).AddEdgeRules(ctx, respEdge)
+	if err == nil && !ok {
+		err = errors.New("rules were not added")
+	}
+	endPhase(err)
+
+	if err != nil {
+		return routing.EdgeRules{}, fmt.Errorf("failed to broadcast rules to destination router: %v", err)
+	}
+
+	// Return rules to initiating router.
+	return initEdge, nil
+}
+
+// broadcastIntermediaryRules generates rules of `biRt` and broadcasts intermediary rules to intermediary routers.
+// Intermediary rules are recorded to `installed`. Returns edge rules of the initiating and responding routers.
+func broadcastIntermediaryRules(ctx context.Context, log logrus.FieldLogger, rtIDR IDReserver,
+	biRt routing.BidirectionalRoute, installed RulesMap) (initEdge, respEdge routing.EdgeRules, err error) {
 	// Generate forward and reverse routes.
 	fwdRt, revRt := biRt.ForwardAndReverse()
 	srcPK := biRt.Desc.SrcPK()
@@ -141,28 +218,37 @@ func CreateRouteGroup(ctx context.Context, dialer snet.Dialer, biRt routing.Bidi
 	// Rules are grouped by rule type [FWD, REV, INTER].
 	fwdRules, revRules, interRules, err := GenerateRules(rtIDR, []routing.Route{fwdRt, revRt})
 	if err != nil {
-		return routing.EdgeRules{}, err
+		return routing.EdgeRules{}, routing.EdgeRules{}, err
+	}
+	initEdge = routing.EdgeRules{
+		Desc:            revRt.Desc,
+		Forward:         fwdRules[srcPK][0],
+		Reverse:         revRules[srcPK][0],
+		Attach:          biRt.Attach,
+		RemoteReverseID: revRules[dstPK][0].KeyRouteID(),
+	}
+	respEdge = routing.EdgeRules{
+		Desc:            fwdRt.Desc,
+		Forward:         fwdRules[dstPK][0],
+		Reverse:         revRules[dstPK][0],
+		Attach:          biRt.Attach,
+		Replaces:        biRt.Replaces,
+		RemoteReverseID: revRules[srcPK][0].KeyRouteID(),
 	}
-	initEdge := routing.EdgeRules{Desc: revRt.Desc, Forward: fwdRules[srcPK][0], Reverse: revRules[srcPK][0]}
-	respEdge := routing.EdgeRules{Desc: fwdRt.Desc, Forward: fwdRules[dstPK][0], Reverse: revRules[dstPK][0]}
 
 	log.Infof("Generated routing rules:\nInitiating edge: %v\nResponding edge: %v\nIntermediaries: %v",
 		initEdge.String(), respEdge.String(), interRules.String())
 
 	// Broadcast intermediary rules to intermediary routers.
-	if err := BroadcastIntermediaryRules(ctx, log, rtIDR, interRules); err != nil {
-		return routing.EdgeRules{}, err
+	for pk, rules := range interRules {
+		installed[pk] = append(installed[pk], rules...)
 	}
 
-	// Broadcast rules to responding router.
-	log.Debug("Broadcasting responding rules...")
-	ok, err := rtIDR.Client(biRt.Desc.DstPK()).AddEdgeRules(ctx, respEdge)
-	if err != nil || !ok {
-		return routing.EdgeRules{}, fmt.Errorf("failed to broadcast rules to destination router: %v", err)
+	if err := BroadcastIntermediaryRules(ctx, log, rtIDR, interRules); err != nil {
+		return routing.EdgeRules{}, routing.EdgeRules{}, err
 	}
 
-	// Return rules to initiating router.
-	return initEdge, nil
+	return initEdge, respEdge, nil
 }
 
 // ReserveRouteIDs dials to all routers and reserves required route IDs from them.
@@ -186,11 +272,79 @@ func ReserveRouteIDs(ctx context.Context, log logrus.FieldLogger, dialer snet.Di
 	}()
 
 	if err = idR.ReserveIDs(ctx); err != nil {
+		RollbackRouteGroup(log, idR, nil)
 		return nil, fmt.Errorf("failed to reserve route ids: %w", err)
 	}
 	return idR, nil
 }
 
+// RollbackRouteGroup undoes a failed route group creation. Rules of 'installed' are deleted and
+// route IDs reserved by 'idR' are released on every router touched. Only the rules of route IDs
+// reserved by 'idR' are deleted, routers refuse to delete the others anyway. Routers are contacted
+// concurrently within 'rollbackTimeout', failures are only logged.
+func RollbackRouteGroup(log logrus.FieldLogger, idR IDReserver, installed RulesMap) {
+	reserved := idR.ReservedIDs()
+
+	pks := make(map[cipher.PubKey]struct{}, len(reserved)+len(installed))
+	for pk := range reserved {
+		pks[pk] = struct{}{}
+	}
+
+	for pk := range installed {
+		pks[pk] = struct{}{}
+	}
+
+	log.WithField("routers", len(pks)).Debug("Rolling back route group...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for pk := range pks {
+		rc := idR.Client(pk)
+		if rc == nil {
+			continue
+		}
+
+		owned := make(map[routing.RouteID]struct{}, len(reserved[pk]))
+		for _, id := range reserved[pk] {
+			owned[id] = struct{}{}
+		}
+
+		ruleIDs := make([]routing.RouteID, 0, len(installed[pk]))
+		for _, rule := range installed[pk] {
+			id := rule.KeyRouteID()
+			if _, ok := owned[id]; !ok {
+				log.Warnf("Rule of route ID %d on %s isn't reserved by the request, not deleting.", id, pk)
+				continue
+			}
+
+			ruleIDs = append(ruleIDs, id)
+		}
+
+		wg.Add(1)
+
+		go func(pk cipher.PubKey, rc *routerclient.Client, ruleIDs, rtIDs []routing.RouteID) {
+			defer wg.Done()
+
+			if len(ruleIDs) > 0 {
+				if _, err := rc.DeleteRules(ctx, ruleIDs); err != nil {
+					log.WithError(err).Warnf("Failed to delete rules of %s on rollback.", pk)
+				}
+			}
+
+			if len(rtIDs) > 0 {
+				if _, err := rc.ReleaseIDs(ctx, rtIDs); err != nil {
+					log.WithError(err).Warnf("Failed to release route IDs of %s on rollback.", pk)
+				}
+			}
+		}(pk, rc, ruleIDs, reserved[pk])
+	}
+
+	wg.Wait()
+}
+
 // GenerateRules generates rules for given forward and reverse routes.
 // The outputs are as follows:
 // - maps that relate slices of forward, consume and intermediary routing rules to a given visor's public key.
@@ -17,7 +17,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skywire/pkg/router"
 	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/setup/setupmetrics"
 )
 
 func TestMain(m *testing.M) {
@@ -107,6 +109,125 @@ func TestCreateRouteGroup(t *testing.T) {
 	}
 }
 
+func TestCreateRouteGroup_rollback(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+	pkD, _ := cipher.GenerateKeyPair()
+
+	fwdPKs := []cipher.PubKey{pkA, pkB, pkC, pkD}
+	revPKs := []cipher.PubKey{pkD, pkC, pkB, pkA}
+
+	routers := make(map[cipher.PubKey]interface{}, len(fwdPKs))
+	for _, pk := range fwdPKs {
+		routers[pk] = newMockRouterGateway(pk)
+	}
+
+	// responding router fails after the intermediary rules are installed
+	routers[pkD].(*mockRouterGateway).failEdgeRules = true
+
+	dialer := newMockDialer(t, routers)
+	biRt := biRouteFromKeys(fwdPKs, revPKs, 1, 5)
+
+	var phases []setupmetrics.Phase
+	var failedPhase setupmetrics.Phase
+
+	observe := func(phase setupmetrics.Phase) func(error) {
+		phases = append(phases, phase)
+
+		return func(err error) {
+			if err != nil {
+				failedPhase = phase
+			}
+		}
+	}
+
+	_, err := createRouteGroup(context.TODO(), dialer, biRt, observe)
+	require.Error(t, err)
+
+	require.Equal(t, []setupmetrics.Phase{
+		setupmetrics.PhaseValidate,
+		setupmetrics.PhaseReserveIDs,
+		setupmetrics.PhaseIntermediaryRules,
+		setupmetrics.PhaseEdgeRules,
+	}, phases)
+	require.Equal(t, setupmetrics.PhaseEdgeRules, failedPhase)
+
+	for pk, r := range routers {
+		mr := r.(*mockRouterGateway)
+		mr.mx.Lock()
+
+		// all reserved route IDs are released
+		require.Len(t, mr.releasedRtIDs, int(mr.lastRtID), "router %s", pk)
+
+		// all the rules sent to the router are deleted
+		var sentRtIDs []routing.RouteID
+		for _, rules := range mr.interRules {
+			for _, rule := range rules {
+				sentRtIDs = append(sentRtIDs, rule.KeyRouteID())
+			}
+		}
+
+		if pk == pkD {
+			require.Len(t, mr.deletedRtIDs, 2)
+		} else {
+			require.ElementsMatch(t, sentRtIDs, mr.deletedRtIDs, "router %s", pk)
+		}
+
+		mr.mx.Unlock()
+	}
+}
+
+func TestRollbackRouteGroup_owned(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+
+	// route 5 of A and 6 of B are reserved by the request, route 9 of A belongs to someone else
+	rA := &router.MockRouter{}
+	rA.On("ReserveKeys", 1).Return([]routing.RouteID{5}, nil)
+	rA.On("DelRules", []routing.RouteID{5}).Return()
+	rA.On("ReleaseKeys", []routing.RouteID{5}).Return()
+
+	rB := &router.MockRouter{}
+	rB.On("ReserveKeys", 1).Return([]routing.RouteID{6}, nil)
+	rB.On("DelRules", []routing.RouteID{6}).Return()
+	rB.On("ReleaseKeys", []routing.RouteID{6}).Return()
+
+	dialer := newMockDialer(t, map[cipher.PubKey]interface{}{
+		pkA: router.NewRPCGateway(rA),
+		pkB: router.NewRPCGateway(rB),
+	})
+
+	ctx := context.TODO()
+
+	idR, err := NewIDReserver(ctx, dialer, [][]routing.Hop{makeHops(pkA, pkB)})
+	require.NoError(t, err)
+	require.NoError(t, idR.ReserveIDs(ctx))
+
+	installed := RulesMap{
+		pkA: {
+			routing.IntermediaryForwardRule(0, 5, 1, uuid.New()),
+			routing.IntermediaryForwardRule(0, 9, 1, uuid.New()),
+		},
+		pkB: {routing.IntermediaryForwardRule(0, 6, 1, uuid.New())},
+	}
+
+	RollbackRouteGroup(logging.MustGetLogger("rollback"), idR, installed)
+
+	rA.AssertExpectations(t)
+	rB.AssertExpectations(t)
+	rA.AssertNumberOfCalls(t, "DelRules", 1)
+
+	// router refuses to delete the rule which isn't reserved by the request
+	ok, err := idR.Client(pkA).DeleteRules(ctx, []routing.RouteID{9})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), router.ErrRouteIDNotOwned.Error())
+	require.False(t, ok)
+	rA.AssertNumberOfCalls(t, "DelRules", 1)
+
+	require.NoError(t, idR.Close())
+}
+
 // checkRtIDKeysOfRouterRules ensures that the rules advertised to the router (from the setup logic) has route ID keys
 // which are valid.
 func checkRtIDKeysOfRouterRules(t *testing.T, r *mockRouterGateway) {
@@ -185,13 +306,17 @@ func determineTpID(pk1, pk2 cipher.PubKey) (tpID uuid.UUID) {
 }
 
 // mockRouterGateway mocks router.RPCGateway and has an internal state machine that records all remote calls.
-// mockRouterGateway acts as a well behaved router, and no error will be returned on any of it's endpoints.
+// mockRouterGateway acts as a well behaved router, and no error will be returned on any of it's endpoints,
+// unless 'failEdgeRules' is set.
 type mockRouterGateway struct {
-	pk         cipher.PubKey       // router's public key
-	lastRtID   uint32              // last route ID that was reserved (the first returned rtID would be 1 if this starts as 0).
-	edgeRules  []routing.EdgeRules // edge rules added by remote.
-	interRules [][]routing.Rule    // intermediary rules added by remote.
-	mx         sync.Mutex
+	pk            cipher.PubKey       // router's public key
+	lastRtID      uint32              // last route ID that was reserved (the first returned rtID would be 1 if this starts as 0).
+	edgeRules     []routing.EdgeRules // edge rules added by remote.
+	interRules    [][]routing.Rule    // intermediary rules added by remote.
+	deletedRtIDs  []routing.RouteID   // route IDs of rules deleted by remote.
+	releasedRtIDs []routing.RouteID   // route IDs released by remote.
+	failEdgeRules bool                // whether adding edge rules fails.
+	mx            sync.Mutex
 }
 
 func newMockRouterGateway(pk cipher.PubKey) *mockRouterGateway {
@@ -202,6 +327,10 @@ func (gw *mockRouterGateway) AddEdgeRules(rules routing.EdgeRules, ok *bool) err
 	gw.mx.Lock()
 	defer gw.mx.Unlock()
 
+	if gw.failEdgeRules {
+		return routing.Failure{Code: routing.FailureAddRules, Msg: "failed to add edge rules"}
+	}
+
 	gw.edgeRules = append(gw.edgeRules, rules)
 	*ok = true
 	return nil
@@ -229,6 +358,24 @@ func (gw *mockRouterGateway) ReserveIDs(n uint8, routeIDs *[]routing.RouteID) er
 	return nil
 }
 
+func (gw *mockRouterGateway) DeleteRules(rtIDs []routing.RouteID, ok *bool) error {
+	gw.mx.Lock()
+	defer gw.mx.Unlock()
+
+	gw.deletedRtIDs = append(gw.deletedRtIDs, rtIDs...)
+	*ok = true
+	return nil
+}
+
+func (gw *mockRouterGateway) ReleaseIDs(rtIDs []routing.RouteID, ok *bool) error {
+	gw.mx.Lock()
+	defer gw.mx.Unlock()
+
+	gw.releasedRtIDs = append(gw.releasedRtIDs, rtIDs...)
+	*ok = true
+	return nil
+}
+
 // There are no distinctive goals for this test yet.
 // As of writing, we only check whether GenerateRules() returns any errors.
 func TestGenerateRules(t *testing.T) {
@@ -0,0 +1,107 @@
+package setup
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/dmsg/httputil"
+
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/setup/setupmetrics"
+)
+
+// RequestInfo describes an in-flight route setup request.
+type RequestInfo struct {
+	ID           uint64             `json:"id"`
+	ReqPK        cipher.PubKey      `json:"requester_pk"`
+	SrcPK        cipher.PubKey      `json:"src_pk"`
+	DstPK        cipher.PubKey      `json:"dst_pk"`
+	Hops         int                `json:"hops"`
+	Phase        setupmetrics.Phase `json:"phase"`
+	Started      time.Time          `json:"started"`
+	PhaseStarted time.Time          `json:"phase_started"`
+}
+
+// RequestTracker keeps track of the in-flight route setup requests and their current phases.
+type RequestTracker struct {
+	mx   sync.Mutex
+	next uint64
+	reqs map[uint64]*RequestInfo
+}
+
+// NewRequestTracker constructs new RequestTracker.
+func NewRequestTracker() *RequestTracker {
+	return &RequestTracker{
+		reqs: make(map[uint64]*RequestInfo),
+	}
+}
+
+// InFlight returns the in-flight requests, the oldest ones come first.
+func (t *RequestTracker) InFlight() []RequestInfo {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	reqs := make([]RequestInfo, 0, len(t.reqs))
+	for _, req := range t.reqs {
+		reqs = append(reqs, *req)
+	}
+
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].ID < reqs[j].ID
+	})
+
+	return reqs
+}
+
+// ServeHTTP lists the in-flight requests in JSON.
+func (t *RequestTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	httputil.WriteJSON(w, r, http.StatusOK, t.InFlight())
+}
+
+// add starts tracking request of `reqPK` to create `biRt`, returned func stops tracking it.
+func (t *RequestTracker) add(reqPK cipher.PubKey, biRt routing.BidirectionalRoute) (id uint64, remove func()) {
+	if t == nil {
+		return 0, func() {}
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.next++
+	id = t.next
+
+	now := time.Now()
+	t.reqs[id] = &RequestInfo{
+		ID:           id,
+		ReqPK:        reqPK,
+		SrcPK:        biRt.Desc.SrcPK(),
+		DstPK:        biRt.Desc.DstPK(),
+		Hops:         len(biRt.Forward),
+		Started:      now,
+		PhaseStarted: now,
+	}
+
+	return id, func() {
+		t.mx.Lock()
+		defer t.mx.Unlock()
+
+		delete(t.reqs, id)
+	}
+}
+
+func (t *RequestTracker) setPhase(id uint64, phase setupmetrics.Phase) {
+	if t == nil {
+		return
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if req, ok := t.reqs[id]; ok {
+		req.Phase = phase
+		req.PhaseStarted = time.Now()
+	}
+}
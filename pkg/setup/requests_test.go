@@ -0,0 +1,48 @@
+package setup
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire/pkg/setup/setupmetrics"
+)
+
+func TestRequestTracker(t *testing.T) {
+	tr := NewRequestTracker()
+
+	reqPK, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+	biRt := biRouteFromKeys([]cipher.PubKey{reqPK, pkB, pkC}, []cipher.PubKey{pkC, reqPK}, 1, 2)
+
+	id1, remove1 := tr.add(reqPK, biRt)
+	id2, remove2 := tr.add(reqPK, biRt)
+	tr.setPhase(id2, setupmetrics.PhaseReserveIDs)
+
+	reqs := tr.InFlight()
+	require.Len(t, reqs, 2)
+	require.Equal(t, id1, reqs[0].ID)
+	require.Equal(t, 2, reqs[0].Hops)
+	require.Equal(t, setupmetrics.Phase(""), reqs[0].Phase)
+	require.Equal(t, setupmetrics.PhaseReserveIDs, reqs[1].Phase)
+
+	remove1()
+
+	rec := httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/requests", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var served []RequestInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served, 1)
+	require.Equal(t, id2, served[0].ID)
+	require.Equal(t, reqPK, served[0].ReqPK)
+
+	remove2()
+	require.Empty(t, tr.InFlight())
+}
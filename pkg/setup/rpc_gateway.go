@@ -2,6 +2,7 @@ package setup
 
 import (
 	"context"
+	"errors"
 	"net"
 	"time"
 
@@ -16,6 +17,8 @@ import (
 // RPCGateway is a RPC interface for setup node.
 type RPCGateway struct {
 	Metrics setupmetrics.Metrics
+	Limiter *Limiter
+	Tracker *RequestTracker
 	Ctx     context.Context
 	Conn    net.Conn
 	ReqPK   cipher.PubKey
@@ -26,7 +29,24 @@ type RPCGateway struct {
 // DialRouteGroup dials RouteGroups for route and rules.
 func (g *RPCGateway) DialRouteGroup(route routing.BidirectionalRoute, rules *routing.EdgeRules) (err error) {
 	log := logging.MustGetLogger("request:" + g.ReqPK.String())
-	defer g.Metrics.RecordRequest()(rules, &err)
+
+	release, err := g.Limiter.Acquire(g.ReqPK)
+	if err != nil {
+		var rejErr *RejectionError
+		if errors.As(err, &rejErr) {
+			g.Metrics.RecordRejection(rejErr.Reason)
+		}
+
+		log.WithError(err).Warn("Request rejected.")
+
+		return err
+	}
+	defer release()
+
+	defer g.Metrics.RecordRequest(len(route.Forward))(rules, &err)
+
+	id, remove := g.Tracker.add(g.ReqPK, route)
+	defer remove()
 
 	ctx, cancel := context.WithTimeout(g.Ctx, g.Timeout)
 	defer cancel()
@@ -38,8 +58,32 @@ func (g *RPCGateway) DialRouteGroup(route routing.BidirectionalRoute, rules *rou
 		}
 	}()
 
-	initRules, err := CreateRouteGroup(ctx, g.Dialer, route)
+	var failedPhase setupmetrics.Phase
+
+	observe := func(phase setupmetrics.Phase) func(error) {
+		g.Tracker.setPhase(id, phase)
+		endPhase := g.Metrics.RecordPhase(phase)
+
+		return func(err error) {
+			if err != nil {
+				failedPhase = phase
+			}
+
+			endPhase(&err)
+		}
+	}
+
+	initRules, err := createRouteGroup(ctx, g.Dialer, route, observe)
 	if err != nil {
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			g.Metrics.RecordFailure(setupmetrics.FailureTimeout)
+		case failedPhase == "":
+			g.Metrics.RecordFailure(setupmetrics.FailureUnknown)
+		default:
+			g.Metrics.RecordFailure(string(failedPhase))
+		}
+
 		return err
 	}
 
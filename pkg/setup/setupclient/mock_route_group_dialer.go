@@ -19,7 +19,7 @@ type MockRouteGroupDialer struct {
 }
 
 // Dial provides a mock function with given fields: ctx, log, n, setupNodes, req
-func (_m *MockRouteGroupDialer) Dial(ctx context.Context, log *logging.Logger, n *snet.Network, setupNodes []cipher.PubKey, req routing.BidirectionalRoute) (routing.EdgeRules, error) {
+func (_m *MockRouteGroupDialer) Dial(ctx context.Context, log *logging.Logger, n *snet.Network, setupNodes []cipher.PubKey, req routing.BidirectionalRoute) (routing.EdgeRules, cipher.PubKey, error) {
 	ret := _m.Called(ctx, log, n, setupNodes, req)
 
 	var r0 routing.EdgeRules
@@ -29,12 +29,19 @@ func (_m *MockRouteGroupDialer) Dial(ctx context.Context, log *logging.Logger, n
 		r0 = ret.Get(0).(routing.EdgeRules)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *logging.Logger, *snet.Network, []cipher.PubKey, routing.BidirectionalRoute) error); ok {
+	var r1 cipher.PubKey
+	if rf, ok := ret.Get(1).(func(context.Context, *logging.Logger, *snet.Network, []cipher.PubKey, routing.BidirectionalRoute) cipher.PubKey); ok {
 		r1 = rf(ctx, log, n, setupNodes, req)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(cipher.PubKey)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, *logging.Logger, *snet.Network, []cipher.PubKey, routing.BidirectionalRoute) error); ok {
+		r2 = rf(ctx, log, n, setupNodes, req)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
@@ -2,7 +2,14 @@ package setupclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/skycoin/skycoin/src/util/logging"
@@ -12,8 +19,10 @@ import (
 )
 
 //go:generate mockery -name RouteGroupDialer -case underscore -inpkg
+//go:generate mockery -name DirectDialer -case underscore -inpkg
 
-// RouteGroupDialer is an interface for RouteGroup dialers
+// RouteGroupDialer is an interface for RouteGroup dialers.
+// Dial returns public key of the setup node which set the route group up.
 type RouteGroupDialer interface {
 	Dial(
 		ctx context.Context,
@@ -21,25 +30,124 @@ type RouteGroupDialer interface {
 		n *snet.Network,
 		setupNodes []cipher.PubKey,
 		req routing.BidirectionalRoute,
+	) (routing.EdgeRules, cipher.PubKey, error)
+}
+
+// DirectDialer sets route groups up by the initiating visor itself, without setup nodes.
+// RPC of the local router is served by `local`.
+type DirectDialer interface {
+	DialDirect(
+		ctx context.Context,
+		log *logging.Logger,
+		n *snet.Network,
+		local ConnServer,
+		req routing.BidirectionalRoute,
 	) (routing.EdgeRules, error)
 }
 
-type setupNodeDialer struct{}
+// ConnServer serves RPC on connections, e.g. *rpc.Server.
+type ConnServer interface {
+	ServeConn(conn io.ReadWriteCloser)
+}
+
+var (
+	// ErrNoSetupNodes is returned when there are no setup nodes to set route group up.
+	ErrNoSetupNodes = errors.New("no setup nodes")
+
+	// ErrRequestRejected is returned when the request exceeds limits of the setup node.
+	ErrRequestRejected = errors.New("request rejected by setup node")
+)
+
+const (
+	// nodeBackoff is the time a failed setup node is tried only after the healthy ones.
+	nodeBackoff = time.Minute
+	// rttWeight is the weight of a new RTT sample in the smoothed RTT of a setup node.
+	rttWeight = 8
+)
+
+// nodeStats keeps health and RTT of a setup node.
+type nodeStats struct {
+	rtt      time.Duration // smoothed RTT of route setup requests, zero if unknown
+	failures int           // number of consecutive failures
+	failedAt time.Time
+	inFlight int
+}
+
+func (s *nodeStats) healthy(now time.Time) bool {
+	return s.failures == 0 || now.Sub(s.failedAt) > nodeBackoff
+}
+
+// load estimates the time a new request to the node takes.
+func (s *nodeStats) load() time.Duration {
+	return s.rtt * time.Duration(s.inFlight+1)
+}
+
+type setupNodeDialer struct {
+	mx    sync.Mutex
+	stats map[cipher.PubKey]*nodeStats
+}
 
 // NewSetupNodeDialer returns a wrapper for (*Client).DialRouteGroup.
+// Requests are spread across setup nodes by their health, RTT and number of requests in flight.
+// Request which failed to reach a setup node is retried on the next one while the dial context is not done,
+// errors returned by the setup node itself are returned right away.
 func NewSetupNodeDialer() RouteGroupDialer {
-	return new(setupNodeDialer)
+	return &setupNodeDialer{
+		stats: make(map[cipher.PubKey]*nodeStats),
+	}
 }
 
-// Dial dials RouteGroup.
+// Dial dials RouteGroup. Returns public key of the setup node which set the route group up.
 func (d *setupNodeDialer) Dial(
 	ctx context.Context,
 	log *logging.Logger,
 	n *snet.Network,
 	setupNodes []cipher.PubKey,
 	req routing.BidirectionalRoute,
-) (routing.EdgeRules, error) {
-	client, err := NewClient(ctx, log, n, setupNodes)
+) (routing.EdgeRules, cipher.PubKey, error) {
+	if len(setupNodes) == 0 {
+		return routing.EdgeRules{}, cipher.PubKey{}, ErrNoSetupNodes
+	}
+
+	var err error
+
+	for _, setupPK := range d.order(setupNodes) {
+		var rules routing.EdgeRules
+
+		rules, err = d.dialNode(ctx, log, n, setupPK, req)
+		if err == nil {
+			return rules, setupPK, nil
+		}
+
+		log.WithError(err).Warnf("Failed to set route group up via setup node %s", setupPK)
+
+		if !retriable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return routing.EdgeRules{}, cipher.PubKey{}, fmt.Errorf("route setup: %w", err)
+}
+
+// retriable checks whether the request which failed with `err` may be retried on another setup node.
+// Errors returned by the setup node itself (e.g. rejection by its limits or invalid route) are
+// deterministic, retrying them only multiplies the load and may leave duplicate rules on visors.
+func retriable(err error) bool {
+	var srvErr rpc.ServerError
+	return !errors.As(err, &srvErr)
+}
+
+func (d *setupNodeDialer) dialNode(
+	ctx context.Context,
+	log *logging.Logger,
+	n *snet.Network,
+	setupPK cipher.PubKey,
+	req routing.BidirectionalRoute,
+) (rules routing.EdgeRules, err error) {
+	start := d.begin(setupPK)
+	defer func() { d.end(setupPK, start, err) }()
+
+	client, err := NewClient(ctx, log, n, []cipher.PubKey{setupPK})
 	if err != nil {
 		return routing.EdgeRules{}, err
 	}
@@ -50,10 +158,103 @@ func (d *setupNodeDialer) Dial(
 		}
 	}()
 
-	resp, err := client.DialRouteGroup(ctx, req)
-	if err != nil {
-		return routing.EdgeRules{}, fmt.Errorf("route setup: %w", err)
+	return client.DialRouteGroup(ctx, req)
+}
+
+// order returns setup nodes in the order they should be tried: healthy ones with the least load
+// come first, nodes with no requests made yet are tried before the measured ones.
+// Nodes of equal load are shuffled.
+func (d *setupNodeDialer) order(setupNodes []cipher.PubKey) []cipher.PubKey {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	ordered := make([]cipher.PubKey, len(setupNodes))
+	copy(ordered, setupNodes)
+
+	rand.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+
+	now := time.Now()
+	stats := make([]nodeStats, len(ordered))
+
+	for i, pk := range ordered {
+		if s, ok := d.stats[pk]; ok {
+			stats[i] = *s
+		}
+	}
+
+	sort.Stable(byLoad{pks: ordered, stats: stats, now: now})
+
+	return ordered
+}
+
+func (d *setupNodeDialer) begin(setupPK cipher.PubKey) time.Time {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.nodeStats(setupPK).inFlight++
+
+	return time.Now()
+}
+
+// end records the result of a request. Errors returned by the setup node itself
+// (e.g. failure of a visor on the route) don't affect health of the node.
+func (d *setupNodeDialer) end(setupPK cipher.PubKey, start time.Time, err error) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	s := d.nodeStats(setupPK)
+	s.inFlight--
+
+	var srvErr rpc.ServerError
+
+	switch {
+	case err == nil, errors.As(err, &srvErr):
+		s.failures = 0
+
+		rtt := time.Since(start)
+		if s.rtt == 0 {
+			s.rtt = rtt
+		} else {
+			s.rtt += (rtt - s.rtt) / rttWeight
+		}
+	case errors.Is(err, context.Canceled):
+		// Canceled by the caller, nothing is known about the node.
+	default:
+		s.failures++
+		s.failedAt = time.Now()
+	}
+}
+
+func (d *setupNodeDialer) nodeStats(setupPK cipher.PubKey) *nodeStats {
+	s, ok := d.stats[setupPK]
+	if !ok {
+		s = new(nodeStats)
+		d.stats[setupPK] = s
+	}
+
+	return s
+}
+
+// byLoad sorts setup nodes by health and load.
+type byLoad struct {
+	pks   []cipher.PubKey
+	stats []nodeStats
+	now   time.Time
+}
+
+func (b byLoad) Len() int { return len(b.pks) }
+
+func (b byLoad) Swap(i, j int) {
+	b.pks[i], b.pks[j] = b.pks[j], b.pks[i]
+	b.stats[i], b.stats[j] = b.stats[j], b.stats[i]
+}
+
+func (b byLoad) Less(i, j int) bool {
+	if hi, hj := b.stats[i].healthy(b.now), b.stats[j].healthy(b.now); hi != hj {
+		return hi
 	}
 
-	return resp, nil
+	return b.stats[i].load() < b.stats[j].load()
 }
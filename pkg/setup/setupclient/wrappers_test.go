@@ -0,0 +1,60 @@
+package setupclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupNodeDialer_order(t *testing.T) {
+	d := NewSetupNodeDialer().(*setupNodeDialer)
+
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+	pkC, _ := cipher.GenerateKeyPair()
+	pkD, _ := cipher.GenerateKeyPair()
+
+	setupNodes := []cipher.PubKey{pkA, pkB, pkC, pkD}
+
+	// A is fast, B is slow, C failed, D is never used.
+	start := time.Now()
+	d.begin(pkA)
+	d.end(pkA, start.Add(-time.Millisecond), nil)
+
+	d.begin(pkB)
+	d.end(pkB, start.Add(-time.Second), nil)
+
+	d.begin(pkC)
+	d.end(pkC, start, errors.New("failed to dial to a setup node"))
+
+	require.Equal(t, []cipher.PubKey{pkD, pkA, pkB, pkC}, d.order(setupNodes))
+
+	// Requests in flight make A slower than B.
+	for i := 0; i < 2000; i++ {
+		d.begin(pkA)
+	}
+
+	require.Equal(t, []cipher.PubKey{pkD, pkB, pkA, pkC}, d.order(setupNodes))
+
+	// Errors of the setup node and cancellation don't affect the health.
+	d.end(pkA, start, rpc.ServerError("route setup failed"))
+	d.end(pkA, start, context.Canceled)
+	require.Equal(t, 0, d.stats[pkA].failures)
+
+	// Failed node is healthy again after the backoff.
+	d.stats[pkC].failedAt = start.Add(-2 * nodeBackoff)
+	require.True(t, d.stats[pkC].healthy(time.Now()))
+}
+
+func TestRetriable(t *testing.T) {
+	require.True(t, retriable(errors.New("failed to dial to a setup node")))
+	require.True(t, retriable(context.DeadlineExceeded))
+	require.False(t, retriable(rpc.ServerError("request rejected by setup node: rate_limit")))
+	require.False(t, retriable(fmt.Errorf("route setup: %w", rpc.ServerError("invalid route"))))
+}
@@ -13,6 +13,17 @@ func NewEmpty() Empty {
 type Empty struct{}
 
 // RecordRequest implements `Metrics`.
-func (Empty) RecordRequest() func(*routing.EdgeRules, *error) {
+func (Empty) RecordRequest(int) func(*routing.EdgeRules, *error) {
 	return func(*routing.EdgeRules, *error) {}
 }
+
+// RecordPhase implements `Metrics`.
+func (Empty) RecordPhase(Phase) func(*error) {
+	return func(*error) {}
+}
+
+// RecordFailure implements `Metrics`.
+func (Empty) RecordFailure(string) {}
+
+// RecordRejection implements `Metrics`.
+func (Empty) RecordRejection(string) {}
@@ -1,6 +1,8 @@
 package setupmetrics
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/VictoriaMetrics/metrics"
@@ -10,9 +12,46 @@ import (
 	"github.com/skycoin/skywire/pkg/routing"
 )
 
+// Phase is a phase of route group creation.
+type Phase string
+
+// Phases of route group creation, in the order they are gone through.
+const (
+	PhaseValidate          Phase = "validate"
+	PhaseReserveIDs        Phase = "reserve_ids"
+	PhaseIntermediaryRules Phase = "intermediary_rules"
+	PhaseEdgeRules         Phase = "edge_rules"
+)
+
+// FailureTimeout is the failure category of requests which exceeded the deadline.
+// Other failures are categorized by the phase they happened in.
+const FailureTimeout = "timeout"
+
+// FailureUnknown is the failure category of requests which failed outside of any phase.
+const FailureUnknown = "unknown"
+
+// MaxLabeledHops is the greatest number of hops which gets its own label, durations of requests
+// with longer routes are recorded under a single label, so requesters can't create unbounded number of histograms.
+const MaxLabeledHops = 8
+
+// hopsLabel returns the label value of `hops`.
+func hopsLabel(hops int) string {
+	switch {
+	case hops < 1:
+		return "0"
+	case hops > MaxLabeledHops:
+		return fmt.Sprintf(">%d", MaxLabeledHops)
+	default:
+		return strconv.Itoa(hops)
+	}
+}
+
 // Metrics collects metrics in prometheus format.
 type Metrics interface {
-	RecordRequest() func(*routing.EdgeRules, *error)
+	RecordRequest(hops int) func(*routing.EdgeRules, *error)
+	RecordPhase(phase Phase) func(*error)
+	RecordFailure(category string)
+	RecordRejection(reason string)
 }
 
 // VictoriaMetrics implements `Metrics` using Victoria Metrics.
@@ -26,23 +65,49 @@ type VictoriaMetrics struct {
 func NewVictoriaMetrics() *VictoriaMetrics {
 	return &VictoriaMetrics{
 		activeRequests:        metricsutil.NewVictoriaMetricsIntGauge("active_request_count"),
-		reqDurationsFailed:    metrics.GetOrCreateHistogram("request_durations{success=\"true\"}"),
-		reqDurationsSuccesses: metrics.GetOrCreateHistogram("request_durations{success=\"false\"}"),
+		reqDurationsFailed:    metrics.GetOrCreateHistogram("request_durations{success=\"false\"}"),
+		reqDurationsSuccesses: metrics.GetOrCreateHistogram("request_durations{success=\"true\"}"),
 	}
 }
 
-// RecordRequest implements `Metrics`.
-func (m *VictoriaMetrics) RecordRequest() func(rules *routing.EdgeRules, err *error) {
+// RecordRequest implements `Metrics`. Durations are also broken down by the number of hops of the forward route,
+// routes longer than MaxLabeledHops share the same label.
+func (m *VictoriaMetrics) RecordRequest(hops int) func(rules *routing.EdgeRules, err *error) {
 	start := time.Now()
 	m.activeRequests.Inc()
 
 	return func(rules *routing.EdgeRules, err *error) {
-		if *err == nil {
+		success := *err == nil
+
+		if success {
 			m.reqDurationsSuccesses.UpdateDuration(start)
 		} else {
 			m.reqDurationsFailed.UpdateDuration(start)
 		}
 
+		metrics.GetOrCreateHistogram(fmt.Sprintf("request_durations_by_hops{hops=%q,success=\"%t\"}", hopsLabel(hops), success)).
+			UpdateDuration(start)
+
 		m.activeRequests.Dec()
 	}
 }
+
+// RecordPhase implements `Metrics`.
+func (m *VictoriaMetrics) RecordPhase(phase Phase) func(err *error) {
+	start := time.Now()
+
+	return func(err *error) {
+		metrics.GetOrCreateHistogram(fmt.Sprintf("phase_durations{phase=%q,success=\"%t\"}", phase, *err == nil)).
+			UpdateDuration(start)
+	}
+}
+
+// RecordFailure implements `Metrics`.
+func (m *VictoriaMetrics) RecordFailure(category string) {
+	metrics.GetOrCreateCounter(fmt.Sprintf("request_failures{category=%q}", category)).Inc()
+}
+
+// RecordRejection implements `Metrics`.
+func (m *VictoriaMetrics) RecordRejection(reason string) {
+	metrics.GetOrCreateCounter(fmt.Sprintf("request_rejections{reason=%q}", reason)).Inc()
+}
@@ -0,0 +1,16 @@
+package setupmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHopsLabel(t *testing.T) {
+	require.Equal(t, "0", hopsLabel(-1))
+	require.Equal(t, "0", hopsLabel(0))
+	require.Equal(t, "1", hopsLabel(1))
+	require.Equal(t, "8", hopsLabel(MaxLabeledHops))
+	require.Equal(t, ">8", hopsLabel(MaxLabeledHops+1))
+	require.Equal(t, ">8", hopsLabel(1<<16))
+}
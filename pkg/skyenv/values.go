@@ -85,6 +85,8 @@ const (
 
 	VPNServerName        = "vpn-server"
 	VPNServerPort uint16 = 44
+	// VPNServerDatagramPort is the port VPN server accepts client datagrams on.
+	VPNServerDatagramPort uint16 = 45
 
 	VPNClientName = "vpn-client"
 	// TODO(darkrengarius): this one's not needed for the app to run but lack of it causes errors
@@ -114,6 +116,8 @@ const (
 const (
 	DefaultTpLogStore = DefaultSkywirePath + "/transport_logs"
 	PackageTpLogStore = PackageSkywirePath + "/transport_logs"
+
+	DefaultRoutingTableDB = DefaultSkywirePath + "/routing.db"
 )
 
 // Default hypervisor constants
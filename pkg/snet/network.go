@@ -314,7 +314,7 @@ func (n *Network) Close() error {
 	for k, v := range n.clients.Direct {
 		if v != nil {
 			wg.Add(1)
-			go func() {
+			go func(k string, v directtp.Client) {
 				err := v.Close()
 
 				directErrorsMu.Lock()
@@ -322,7 +322,7 @@ func (n *Network) Close() error {
 				directErrorsMu.Unlock()
 
 				wg.Done()
-			}()
+			}(k, v)
 		}
 	}
 
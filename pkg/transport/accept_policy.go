@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/dmsg/cipher"
+)
+
+// ErrTransportRejected is returned when an incoming transport is rejected by the accept policy.
+var ErrTransportRejected = errors.New("transport rejected by accept policy")
+
+// AcceptPolicy determines which incoming transports are accepted. Empty allow lists allow any
+// public key / transport type, deny lists take precedence over allow lists. Zero 'MaxPerRemote'
+// means no limit of transports per remote visor. Transports dialed by the visor are not affected.
+// Rejected transports are refused within the settlement handshake, so the dialing visor knows about it.
+type AcceptPolicy struct {
+	AllowPKs     []cipher.PubKey `json:"allow_pks,omitempty"`
+	DenyPKs      []cipher.PubKey `json:"deny_pks,omitempty"`
+	AllowTypes   []string        `json:"allow_types,omitempty"`
+	DenyTypes    []string        `json:"deny_types,omitempty"`
+	MaxPerRemote int             `json:"max_per_remote,omitempty"`
+}
+
+// Check checks whether a transport of type `tpType` from `remote` is accepted,
+// `count` is the number of other transports to `remote` the visor has.
+func (p AcceptPolicy) Check(tpType string, remote cipher.PubKey, count int) error {
+	if hasPK(p.DenyPKs, remote) || (len(p.AllowPKs) > 0 && !hasPK(p.AllowPKs, remote)) {
+		return fmt.Errorf("%w: public key %s is not allowed", ErrTransportRejected, remote)
+	}
+
+	if hasString(p.DenyTypes, tpType) || (len(p.AllowTypes) > 0 && !hasString(p.AllowTypes, tpType)) {
+		return fmt.Errorf("%w: type %s is not allowed", ErrTransportRejected, tpType)
+	}
+
+	if p.MaxPerRemote > 0 && count >= p.MaxPerRemote {
+		return fmt.Errorf("%w: limit of %d transports per remote is reached", ErrTransportRejected, p.MaxPerRemote)
+	}
+
+	return nil
+}
+
+func hasPK(pks []cipher.PubKey, pk cipher.PubKey) bool {
+	for _, v := range pks {
+		if v == pk {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptPolicy_Check(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+
+	tests := []struct {
+		name     string
+		policy   AcceptPolicy
+		tpType   string
+		remote   cipher.PubKey
+		count    int
+		accepted bool
+	}{
+		{"empty policy", AcceptPolicy{}, "stcpr", pkA, 10, true},
+		{"allowed pk", AcceptPolicy{AllowPKs: []cipher.PubKey{pkA}}, "stcpr", pkA, 0, true},
+		{"not allowed pk", AcceptPolicy{AllowPKs: []cipher.PubKey{pkA}}, "stcpr", pkB, 0, false},
+		{"denied pk", AcceptPolicy{AllowPKs: []cipher.PubKey{pkA}, DenyPKs: []cipher.PubKey{pkA}}, "stcpr", pkA, 0, false},
+		{"allowed type", AcceptPolicy{AllowTypes: []string{"dmsg"}}, "dmsg", pkA, 0, true},
+		{"not allowed type", AcceptPolicy{AllowTypes: []string{"dmsg"}}, "stcpr", pkA, 0, false},
+		{"denied type", AcceptPolicy{DenyTypes: []string{"sudph"}}, "sudph", pkA, 0, false},
+		{"below limit", AcceptPolicy{MaxPerRemote: 2}, "dmsg", pkA, 1, true},
+		{"limit reached", AcceptPolicy{MaxPerRemote: 2}, "dmsg", pkA, 2, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Check(tc.tpType, tc.remote, tc.count)
+			if tc.accepted {
+				require.NoError(t, err)
+			} else {
+				require.True(t, errors.Is(err, ErrTransportRejected))
+			}
+		})
+	}
+}
+
+func TestManager_checkAcceptPolicy(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+
+	tpA := &ManagedTransport{rPK: pkA}
+	tm := &Manager{
+		tps:    map[uuid.UUID]*ManagedTransport{uuid.New(): tpA, uuid.New(): {rPK: pkB}},
+		policy: AcceptPolicy{MaxPerRemote: 1},
+	}
+
+	require.True(t, errors.Is(tm.checkAcceptPolicy("stcpr", pkA, uuid.New()), ErrTransportRejected))
+
+	// transport which is being re-established doesn't count
+	for id, tp := range tm.tps {
+		if tp == tpA {
+			require.NoError(t, tm.checkAcceptPolicy("stcpr", pkA, id))
+		}
+	}
+
+	tm.SetAcceptPolicy(AcceptPolicy{MaxPerRemote: 2})
+	require.NoError(t, tm.checkAcceptPolicy("stcpr", pkA, uuid.New()))
+}
@@ -0,0 +1,170 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+// BondType is the type bond IDs are made of, there is a single bond per pair of visors.
+const BondType = "bond"
+
+var (
+	// ErrBondNotFound is returned when there is no bond of the given ID.
+	ErrBondNotFound = errors.New("bond not found")
+
+	// ErrNoBondMembers is returned when there are no transports to write to within a bond.
+	ErrNoBondMembers = errors.New("no transports of bond")
+
+	// ErrBondExists is returned when saving a bond to the remote which is already bonded to.
+	ErrBondExists = errors.New("bond to remote already exists")
+)
+
+// BondConfig configures a bond of the transports to the remote visor. Transports of any type are bonded
+// if 'Types' is empty.
+type BondConfig struct {
+	Remote cipher.PubKey `json:"remote"`
+	Types  []string      `json:"types,omitempty"`
+}
+
+// Bond aggregates the managed transports to the same remote visor. Packets sent via the bond are
+// balanced over its members which are up, so the bond survives losing any of them. Members are updated
+// by the manager as transports are saved and deleted, so transports created or re-created after the bond
+// are its members as well.
+// Bond ID may be referenced by routing rules as any other transport ID.
+type Bond struct {
+	ID     uuid.UUID
+	remote cipher.PubKey
+	types  []string // any type if empty
+	next   uint32
+
+	members   []*ManagedTransport // ordered by type, replaced as a whole on refresh
+	membersMx sync.RWMutex
+}
+
+// MakeBondID makes ID of the bond between visors `keyA` and `keyB`, it's the same for both of them.
+func MakeBondID(keyA, keyB cipher.PubKey) uuid.UUID {
+	return MakeTransportID(keyA, keyB, BondType)
+}
+
+// Remote returns the remote public key.
+func (b *Bond) Remote() cipher.PubKey { return b.remote }
+
+// Types returns types of the member transports, transports of any type are members if it's empty.
+func (b *Bond) Types() []string { return b.types }
+
+// Config returns the config the bond is made of.
+func (b *Bond) Config() BondConfig {
+	return BondConfig{Remote: b.remote, Types: b.types}
+}
+
+// TransportID returns the bond ID, routing rules refer to the bond by.
+func (b *Bond) TransportID() uuid.UUID { return b.ID }
+
+// Members returns the member transports ordered by type. The returned slice must not be modified.
+func (b *Bond) Members() []*ManagedTransport {
+	b.membersMx.RLock()
+	defer b.membersMx.RUnlock()
+
+	return b.members
+}
+
+// IsUp checks whether any of the members is up.
+func (b *Bond) IsUp() bool {
+	for _, tp := range b.Members() {
+		if tp.IsUp() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// refresh picks the members out of the transports `tps`.
+func (b *Bond) refresh(tps map[uuid.UUID]*ManagedTransport) {
+	var members []*ManagedTransport
+
+	for _, tp := range tps {
+		if tp.Remote() == b.remote && b.hasType(tp.Type()) {
+			members = append(members, tp)
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Type() < members[j].Type()
+	})
+
+	b.membersMx.Lock()
+	b.members = members
+	b.membersMx.Unlock()
+}
+
+// Pick returns the member to send the next packet via. Members which are up are picked by turns,
+// if none of them is up, the first member is picked. Nil is returned if there are no members.
+func (b *Bond) Pick() *ManagedTransport {
+	members := b.Members()
+	if len(members) == 0 {
+		return nil
+	}
+
+	up := members[:0:0]
+	for _, tp := range members {
+		if tp.IsUp() {
+			up = append(up, tp)
+		}
+	}
+
+	if len(up) == 0 {
+		return members[0]
+	}
+
+	// modulo is taken in uint32, so the index doesn't overflow int on 32-bit platforms
+	return up[(atomic.AddUint32(&b.next, 1)-1)%uint32(len(up))]
+}
+
+// WritePacket writes a packet via the picked member, other members are tried if the write fails.
+func (b *Bond) WritePacket(ctx context.Context, packet routing.Packet) error {
+	first := b.Pick()
+	if first == nil {
+		return ErrNoBondMembers
+	}
+
+	err := first.WritePacket(ctx, packet)
+	if err == nil {
+		return nil
+	}
+
+	for _, tp := range b.Members() {
+		if tp == first || !tp.IsUp() {
+			continue
+		}
+
+		if err = tp.WritePacket(ctx, packet); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("bond %s: %w", b.ID, err)
+}
+
+func (b *Bond) hasType(tpType string) bool {
+	if len(b.types) == 0 {
+		return true
+	}
+
+	for _, t := range b.types {
+		if t == tpType {
+			return true
+		}
+	}
+
+	return false
+}
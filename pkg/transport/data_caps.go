@@ -0,0 +1,246 @@
+package transport
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+)
+
+// Actions taken once a data cap is exceeded.
+const (
+	// DataCapDenyRoutes denies new route groups via the capped transports.
+	DataCapDenyRoutes = "deny_routes"
+	// DataCapClose closes the capped transports and denies new ones.
+	DataCapClose = "close"
+)
+
+// Data cap periods.
+const (
+	DataCapDaily   = "daily"
+	DataCapMonthly = "monthly"
+)
+
+// ErrDataCapExceeded is returned when the data cap of transport is exceeded.
+var ErrDataCapExceeded = errors.New("transport data cap exceeded")
+
+// DataCap limits traffic (received and sent bytes) of transports, traffic of all the matching
+// transports is summed up. Empty 'TpType' matches transports of any type, empty 'RemotePK' -
+// transports to any visor. Zero 'Daily'/'Monthly' means no limit. Periods start at UTC midnight.
+type DataCap struct {
+	TpType   string        `json:"tp_type,omitempty"`
+	RemotePK cipher.PubKey `json:"remote_pk,omitempty"`
+	Daily    uint64        `json:"daily,omitempty"`
+	Monthly  uint64        `json:"monthly,omitempty"`
+	Action   string        `json:"action"`
+}
+
+// action returns the action of the cap, routes are denied unless specified otherwise.
+func (c *DataCap) action() string {
+	if c.Action == "" {
+		return DataCapDenyRoutes
+	}
+
+	return c.Action
+}
+
+func (c *DataCap) matches(tpType string, remote cipher.PubKey) bool {
+	return (c.TpType == "" || c.TpType == tpType) && (c.RemotePK.Null() || c.RemotePK == remote)
+}
+
+// DataCapEvent is emitted once a data cap is exceeded.
+type DataCapEvent struct {
+	Cap    DataCap
+	Period string
+	Used   uint64
+}
+
+// DataCapCallback is called once a data cap is exceeded.
+type DataCapCallback func(event DataCapEvent)
+
+// dataCapUsage is the traffic counted against a data cap within the current periods.
+type dataCapUsage struct {
+	day      time.Time
+	month    time.Time
+	daily    uint64
+	monthly  uint64
+	exceeded string // the period which cap is exceeded, empty if it's not
+}
+
+// dataCaps keeps track of the traffic of transports against data caps. Traffic is counted by the transports
+// once per log write interval, traffic of the current periods from before the visor start is taken from
+// the log store once the transport is first seen.
+type dataCaps struct {
+	caps []DataCap
+	ls   LogStore
+	now  func() time.Time
+
+	mx         sync.Mutex
+	usage      []dataCapUsage // aligned with 'caps'
+	seen       map[uuid.UUID]struct{}
+	onExceeded DataCapCallback
+}
+
+func newDataCaps(caps []DataCap, ls LogStore) *dataCaps {
+	if len(caps) == 0 {
+		return nil
+	}
+
+	return &dataCaps{
+		caps:  caps,
+		ls:    ls,
+		now:   time.Now,
+		usage: make([]dataCapUsage, len(caps)),
+		seen:  make(map[uuid.UUID]struct{}),
+	}
+}
+
+func (dc *dataCaps) setCallback(f DataCapCallback) {
+	if dc == nil {
+		return
+	}
+
+	dc.mx.Lock()
+	dc.onExceeded = f
+	dc.mx.Unlock()
+}
+
+// register counts the logged traffic of the current periods of transport `id`, unless it was seen before.
+func (dc *dataCaps) register(id uuid.UUID, tpType string, remote cipher.PubKey) {
+	if dc == nil {
+		return
+	}
+
+	dc.mx.Lock()
+	if _, ok := dc.seen[id]; ok || dc.ls == nil {
+		dc.mx.Unlock()
+		return
+	}
+	dc.seen[id] = struct{}{}
+	dc.mx.Unlock()
+
+	now := dc.now()
+	day, month := dataCapPeriods(now)
+
+	history, err := dc.ls.History(id, month, now)
+	if err != nil {
+		log.WithError(err).Warnf("Failed to get traffic history of transport %s.", id)
+		return
+	}
+
+	var daily, monthly uint64
+	for _, b := range history {
+		monthly += b.RecvBytes + b.SentBytes
+
+		if !b.Start.Before(day) {
+			daily += b.RecvBytes + b.SentBytes
+		}
+	}
+
+	dc.count(tpType, remote, daily, monthly)
+}
+
+// add counts `n` bytes of traffic of transport of `tpType` to `remote`.
+func (dc *dataCaps) add(tpType string, remote cipher.PubKey, n uint64) {
+	if dc == nil {
+		return
+	}
+
+	dc.count(tpType, remote, n, n)
+}
+
+func (dc *dataCaps) count(tpType string, remote cipher.PubKey, daily, monthly uint64) {
+	day, month := dataCapPeriods(dc.now())
+
+	var events []DataCapEvent
+
+	dc.mx.Lock()
+	for i := range dc.caps {
+		c := &dc.caps[i]
+		if !c.matches(tpType, remote) {
+			continue
+		}
+
+		u := &dc.usage[i]
+		if !u.month.Equal(month) {
+			*u = dataCapUsage{month: month}
+		}
+
+		if !u.day.Equal(day) {
+			u.day, u.daily = day, 0
+
+			if u.exceeded == DataCapDaily {
+				u.exceeded = ""
+			}
+		}
+
+		u.daily += daily
+		u.monthly += monthly
+
+		if u.exceeded != "" {
+			continue
+		}
+
+		switch {
+		case c.Monthly > 0 && u.monthly > c.Monthly:
+			u.exceeded = DataCapMonthly
+			events = append(events, DataCapEvent{Cap: *c, Period: DataCapMonthly, Used: u.monthly})
+		case c.Daily > 0 && u.daily > c.Daily:
+			u.exceeded = DataCapDaily
+			events = append(events, DataCapEvent{Cap: *c, Period: DataCapDaily, Used: u.daily})
+		}
+	}
+	onExceeded := dc.onExceeded
+	dc.mx.Unlock()
+
+	if onExceeded == nil {
+		return
+	}
+
+	for _, event := range events {
+		go onExceeded(event)
+	}
+}
+
+// exceeded returns the action to take for transport of `tpType` to `remote`.
+// It's empty if no data cap is exceeded, `DataCapClose` takes precedence.
+func (dc *dataCaps) exceeded(tpType string, remote cipher.PubKey) string {
+	if dc == nil {
+		return ""
+	}
+
+	day, month := dataCapPeriods(dc.now())
+
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+
+	action := ""
+
+	for i := range dc.caps {
+		c, u := &dc.caps[i], &dc.usage[i]
+		if !c.matches(tpType, remote) || u.exceeded == "" || !u.month.Equal(month) {
+			continue
+		}
+
+		if u.exceeded == DataCapDaily && !u.day.Equal(day) {
+			continue
+		}
+
+		if action != DataCapClose {
+			action = c.action()
+		}
+	}
+
+	return action
+}
+
+// dataCapPeriods returns the starts of the day and the month of `t` in UTC.
+func dataCapPeriods(t time.Time) (day, month time.Time) {
+	t = t.UTC()
+	day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return day, month
+}
@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataCaps(t *testing.T) {
+	pkA, _ := cipher.GenerateKeyPair()
+	pkB, _ := cipher.GenerateKeyPair()
+
+	t.Run("no caps", func(t *testing.T) {
+		dc := newDataCaps(nil, InMemoryTransportLogStore())
+		require.Nil(t, dc)
+
+		dc.add("stcpr", pkA, 100)
+		require.Empty(t, dc.exceeded("stcpr", pkA))
+	})
+
+	t.Run("periods", func(t *testing.T) {
+		now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+
+		dc := newDataCaps([]DataCap{
+			{TpType: "stcpr", Daily: 100, Monthly: 250},
+			{RemotePK: pkB, Daily: 10, Action: DataCapClose},
+		}, nil)
+		dc.now = func() time.Time { return now }
+
+		events := make(chan DataCapEvent, 2)
+		dc.setCallback(func(e DataCapEvent) { events <- e })
+
+		dc.add("stcpr", pkA, 100)
+		require.Empty(t, dc.exceeded("stcpr", pkA))
+
+		dc.add("stcpr", pkA, 1)
+		require.Equal(t, DataCapDenyRoutes, dc.exceeded("stcpr", pkA))
+		require.Empty(t, dc.exceeded("dmsg", pkA))
+
+		e := <-events
+		require.Equal(t, DataCapDaily, e.Period)
+		require.Equal(t, uint64(101), e.Used)
+
+		// Daily cap is reset the next day, monthly traffic is still counted.
+		now = now.Add(12 * time.Hour)
+		require.Empty(t, dc.exceeded("stcpr", pkA))
+
+		dc.add("stcpr", pkA, 100)
+		require.Empty(t, dc.exceeded("stcpr", pkA))
+
+		now = now.Add(24 * time.Hour)
+		dc.add("stcpr", pkA, 60)
+		require.Equal(t, DataCapDenyRoutes, dc.exceeded("stcpr", pkA))
+
+		e = <-events
+		require.Equal(t, DataCapMonthly, e.Period)
+		require.Equal(t, uint64(261), e.Used)
+
+		// Close takes precedence.
+		dc.add("stcpr", pkB, 11)
+		require.Equal(t, DataCapClose, dc.exceeded("stcpr", pkB))
+		require.Equal(t, DataCapClose, dc.exceeded("dmsg", pkB))
+	})
+
+	t.Run("logged traffic", func(t *testing.T) {
+		ls := InMemoryTransportLogStore()
+		id := uuid.New()
+
+		require.NoError(t, ls.Record(id, &LogEntry{RecvBytes: 60, SentBytes: 50}))
+
+		dc := newDataCaps([]DataCap{{RemotePK: pkA, Monthly: 100}}, ls)
+
+		dc.register(id, "dmsg", pkA)
+		require.Equal(t, DataCapDenyRoutes, dc.exceeded("dmsg", pkA))
+
+		// Logged traffic is counted once.
+		dc = newDataCaps([]DataCap{{RemotePK: pkA, Monthly: 200}}, ls)
+		dc.register(id, "dmsg", pkA)
+		dc.register(id, "dmsg", pkA)
+		require.Empty(t, dc.exceeded("dmsg", pkA))
+	})
+}
@@ -63,6 +63,7 @@ func (td *mockDiscoveryClient) GetTransportByID(ctx context.Context, id uuid.UUI
 		IsUp:       entry.IsUp,
 		Registered: entry.Registered,
 		Statuses:   entry.Statuses,
+		Probe:      entry.Probe,
 	}, nil
 }
 
@@ -111,6 +112,9 @@ func (td *mockDiscoveryClient) UpdateStatuses(ctx context.Context, statuses ...*
 
 		td.Lock()
 		entry.IsUp = status.IsUp
+		if status.Probe != nil {
+			entry.Probe = status.Probe
+		}
 		td.entries[status.ID] = *entry
 		td.Unlock()
 	}
@@ -121,6 +121,9 @@ type SignedEntry struct {
 	Entry      *Entry        `json:"entry"`
 	Signatures [2]cipher.Sig `json:"signatures"`
 	Registered int64         `json:"registered,omitempty"`
+
+	// Features are announced by the initiating visor within the settlement handshake.
+	Features Features `json:"features,omitempty"`
 }
 
 // Sign sets Signature for a given PubKey in correct position
@@ -169,6 +172,9 @@ type Status struct {
 
 	// Updated is the epoch timestamp of when the status is last updated.
 	Updated int64 `json:"updated,omitempty"`
+
+	// Probe holds the measurements of the Transport made by the reporting edge, if any.
+	Probe *ProbeStats `json:"probe,omitempty"`
 }
 
 // EntryWithStatus stores Entry and Statuses returned by both Edges.
@@ -177,6 +183,9 @@ type EntryWithStatus struct {
 	IsUp       bool    `json:"is_up"`
 	Registered int64   `json:"registered"`
 	Statuses   [2]bool `json:"statuses"`
+
+	// Probe holds the latest measurements of the Transport reported by its edges, if any.
+	Probe *ProbeStats `json:"probe,omitempty"`
 }
 
 // String implements stringer
@@ -70,22 +70,46 @@ func receiveAndVerifyEntry(r io.Reader, expected *Entry, remotePK cipher.PubKey)
 	return &recvSE, nil
 }
 
+// Features represents optional transport features negotiated within the settlement handshake.
+// The initiating visor announces its features within the sent entry, the responding visor
+// replies with the features supported by both edges within the higher bits of the accept byte.
+// Visors unaware of features announce none and accept with a plain '1'.
+type Features byte
+
+const (
+	// FeatureHopLimit is set when packets are written with versioned headers which carry a hop limit.
+	// Otherwise, packets are written with legacy headers.
+	FeatureHopLimit Features = 1 << iota
+
+	// FeatureProbe is set when the edges exchange TransportProbePackets to measure the transport.
+	FeatureProbe
+)
+
+// SupportedFeatures are the transport features supported by this visor.
+const SupportedFeatures = FeatureHopLimit | FeatureProbe
+
+// Has checks whether all of the `features` are set.
+func (f Features) Has(features Features) bool {
+	return f&features == features
+}
+
 // SettlementHS represents a settlement handshake.
 // This is the handshake responsible for registering a transport to transport discovery.
-type SettlementHS func(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) error
+// It results in the features supported by both edges of the transport.
+type SettlementHS func(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) (Features, error)
 
 // Do performs the settlement handshake.
-func (hs SettlementHS) Do(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) (err error) {
+func (hs SettlementHS) Do(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) (features Features, err error) {
 	done := make(chan struct{})
 	go func() {
-		err = hs(ctx, dc, conn, sk)
+		features, err = hs(ctx, dc, conn, sk)
 		close(done)
 	}()
 	select {
 	case <-done:
-		return err
+		return features, err
 	case <-ctx.Done():
-		return ctx.Err()
+		return 0, ctx.Err()
 	}
 }
 
@@ -94,7 +118,7 @@ func (hs SettlementHS) Do(ctx context.Context, dc DiscoveryClient, conn *snet.Co
 // The handshake logic only REGISTERS the transport, and does not update the status of the transport.
 func MakeSettlementHS(init bool) SettlementHS {
 	// initiating logic.
-	initHS := func(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) (err error) {
+	initHS := func(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) (_ Features, err error) {
 		entry := makeEntryFromTpConn(conn)
 
 		// TODO(evanlinjin): Probably not needed as this is called in mTp already. Need to double check.
@@ -108,37 +132,63 @@ func MakeSettlementHS(init bool) SettlementHS {
 		// create signed entry and send it to responding visor.
 		se, err := NewSignedEntry(&entry, conn.LocalPK(), sk)
 		if err != nil {
-			return fmt.Errorf("failed to sign entry: %w", err)
+			return 0, fmt.Errorf("failed to sign entry: %w", err)
 		}
+		se.Features = SupportedFeatures
 		if err := json.NewEncoder(conn).Encode(se); err != nil {
-			return fmt.Errorf("failed to write entry: %w", err)
+			return 0, fmt.Errorf("failed to write entry: %w", err)
 		}
 
 		// await okay signal.
 		accepted := make([]byte, 1)
 		if _, err := io.ReadFull(conn, accepted); err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			return 0, fmt.Errorf("failed to read response: %w", err)
 		}
 		if accepted[0] == 0 {
-			return fmt.Errorf("transport settlement rejected by remote")
+			return 0, fmt.Errorf("transport settlement rejected by remote")
 		}
-		return nil
+		return Features(accepted[0]>>1) & SupportedFeatures, nil
 	}
 
-	// responding logic.
-	respHS := func(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) error {
+	if init {
+		return initHS
+	}
+	return MakeRespondingSettlementHS(nil)
+}
+
+// SettlementCheck is called by the responding visor once the received entry is verified,
+// the transport settlement is rejected if it returns an error.
+type SettlementCheck func(conn *snet.Conn) error
+
+// MakeRespondingSettlementHS creates a responding settlement handshake which settlement is subject to `check`.
+// Nil `check` accepts any transport.
+func MakeRespondingSettlementHS(check SettlementCheck) SettlementHS {
+	return func(ctx context.Context, dc DiscoveryClient, conn *snet.Conn, sk cipher.SecKey) (Features, error) {
 		entry := makeEntryFromTpConn(conn)
 
 		// receive, verify and sign entry.
 		recvSE, err := receiveAndVerifyEntry(conn, &entry, conn.RemotePK())
 		if err != nil {
-			return err
+			return 0, err
+		}
+
+		if check != nil {
+			if err := check(conn); err != nil {
+				if _, wErr := conn.Write([]byte{0}); wErr != nil {
+					log.WithError(wErr).Debug("Failed to reject transport settlement.")
+				}
+				return 0, err
+			}
 		}
 
 		if err := recvSE.Sign(conn.LocalPK(), sk); err != nil {
-			return fmt.Errorf("failed to sign received entry: %w", err)
+			return 0, fmt.Errorf("failed to sign received entry: %w", err)
 		}
 
+		// features are only meant for the handshake, they are not registered.
+		features := recvSE.Features & SupportedFeatures
+		recvSE.Features = 0
+
 		entry = *recvSE.Entry
 
 		// Ensure transport is registered.
@@ -152,14 +202,9 @@ func MakeSettlementHS(init bool) SettlementHS {
 		}
 
 		// inform initiating visor.
-		if _, err := conn.Write([]byte{1}); err != nil {
-			return fmt.Errorf("failed to accept transport settlement: write failed: %w", err)
+		if _, err := conn.Write([]byte{1 | byte(features)<<1}); err != nil {
+			return 0, fmt.Errorf("failed to accept transport settlement: write failed: %w", err)
 		}
-		return nil
-	}
-
-	if init {
-		return initHS
+		return features, nil
 	}
-	return respHS
 }
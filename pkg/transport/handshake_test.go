@@ -2,6 +2,8 @@ package transport_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skywire/pkg/skyenv"
+	"github.com/skycoin/skywire/pkg/snet"
 	"github.com/skycoin/skywire/pkg/snet/snettest"
 	"github.com/skycoin/skywire/pkg/transport"
 )
@@ -33,7 +36,11 @@ func TestSettlementHS(t *testing.T) {
 				errCh1 <- err
 				return
 			}
-			errCh1 <- transport.MakeSettlementHS(false).Do(context.TODO(), tpDisc, conn1, keys[1].SK)
+			features, err := transport.MakeSettlementHS(false).Do(context.TODO(), tpDisc, conn1, keys[1].SK)
+			if err == nil && features != transport.SupportedFeatures {
+				err = fmt.Errorf("unexpected responder features: %d", features)
+			}
+			errCh1 <- err
 		}()
 
 		const entryTimeout = 5 * time.Second
@@ -53,10 +60,38 @@ func TestSettlementHS(t *testing.T) {
 
 		conn0, err := nEnv.Nets[0].Dial(context.TODO(), dmsg.Type, keys[1].PK, skyenv.DmsgTransportPort)
 		require.NoError(t, err)
-		require.NoError(t, transport.MakeSettlementHS(true).Do(context.TODO(), tpDisc, conn0, keys[0].SK))
+		features, err := transport.MakeSettlementHS(true).Do(context.TODO(), tpDisc, conn0, keys[0].SK)
+		require.NoError(t, err)
+		require.Equal(t, transport.SupportedFeatures, features)
 
 		require.NoError(t, <-errCh1)
 	})
+
+	// TEST: Settlement is rejected by the responding visor once the check fails.
+	t.Run("Reject", func(t *testing.T) {
+		lis1, err := nEnv.Nets[1].Listen(dmsg.Type, skyenv.DmsgTransportPort+1)
+		require.NoError(t, err)
+
+		errCh1 := make(chan error, 1)
+		go func() {
+			defer close(errCh1)
+			conn1, err := lis1.AcceptConn()
+			if err != nil {
+				errCh1 <- err
+				return
+			}
+			reject := func(*snet.Conn) error { return transport.ErrTransportRejected }
+			_, err = transport.MakeRespondingSettlementHS(reject).Do(context.TODO(), tpDisc, conn1, keys[1].SK)
+			errCh1 <- err
+		}()
+
+		conn0, err := nEnv.Nets[0].Dial(context.TODO(), dmsg.Type, keys[1].PK, skyenv.DmsgTransportPort+1)
+		require.NoError(t, err)
+		_, err = transport.MakeSettlementHS(true).Do(context.TODO(), tpDisc, conn0, keys[0].SK)
+		require.EqualError(t, err, "transport settlement rejected by remote")
+
+		require.True(t, errors.Is(<-errCh1, transport.ErrTransportRejected))
+	})
 }
 
 // TODO(evanlinjin): This will need further testing.
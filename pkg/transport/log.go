@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -70,21 +71,136 @@ func (le *LogEntry) GobDecode(b []byte) error {
 	return nil
 }
 
+// LogBucketSize is the time span of a single LogBucket.
+const LogBucketSize = time.Hour
+
+// DefaultLogRetention is the time LogBuckets are kept for unless configured otherwise.
+const DefaultLogRetention = 30 * 24 * time.Hour
+
+// LogBucket is the traffic of a transport within [Start, Start+LogBucketSize).
+type LogBucket struct {
+	Start     time.Time `json:"start"`
+	RecvBytes uint64    `json:"recv"`
+	SentBytes uint64    `json:"sent"`
+}
+
 // LogStore stores transport log entries.
+// Besides the totals, traffic is kept in LogBuckets for the retention period.
 type LogStore interface {
 	Entry(id uuid.UUID) (*LogEntry, error)
 	Record(id uuid.UUID, entry *LogEntry) error
+	// History returns buckets of transport `id` which overlap [since, until), the oldest come first.
+	History(id uuid.UUID, since, until time.Time) ([]LogBucket, error)
+	// Delete removes the entry and the history of transport `id`.
+	Delete(id uuid.UUID) error
+}
+
+// logHistory splits traffic recorded to the log store into LogBuckets. Entries recorded are totals
+// of the transport, so the traffic of the bucket is the difference between the consecutive totals.
+type logHistory struct {
+	retention time.Duration
+	sources   map[uuid.UUID]*LogEntry // entries the totals are recorded from
+	last      map[uuid.UUID]LogEntry
+	buckets   map[uuid.UUID][]LogBucket
+}
+
+func newLogHistory(retention time.Duration) logHistory {
+	if retention <= 0 {
+		retention = DefaultLogRetention
+	}
+
+	return logHistory{
+		retention: retention,
+		sources:   make(map[uuid.UUID]*LogEntry),
+		last:      make(map[uuid.UUID]LogEntry),
+		buckets:   make(map[uuid.UUID][]LogBucket),
+	}
+}
+
+// add attributes the traffic since the previous record of transport `id` to the bucket of `now`.
+// Totals of a new entry count from zero, it's recorded once transport is re-created under the same ID.
+// Each total less than the previous one means the counter was reset, it counts from zero as well.
+func (h *logHistory) add(id uuid.UUID, entry *LogEntry, now time.Time) {
+	recv := atomic.LoadUint64(&entry.RecvBytes)
+	sent := atomic.LoadUint64(&entry.SentBytes)
+
+	last := h.last[id]
+	if h.sources[id] != entry {
+		last = LogEntry{}
+		h.sources[id] = entry
+	}
+
+	h.last[id] = LogEntry{RecvBytes: recv, SentBytes: sent}
+
+	recv = logDelta(recv, last.RecvBytes)
+	sent = logDelta(sent, last.SentBytes)
+
+	buckets := h.prune(h.buckets[id], now)
+	start := now.UTC().Truncate(LogBucketSize)
+
+	if n := len(buckets); n == 0 || buckets[n-1].Start.Before(start) {
+		buckets = append(buckets, LogBucket{Start: start})
+	}
+
+	buckets[len(buckets)-1].RecvBytes += recv
+	buckets[len(buckets)-1].SentBytes += sent
+
+	h.buckets[id] = buckets
+}
+
+// logDelta returns the traffic since the `last` total, total counts from zero once it's less than `last`.
+func logDelta(cur, last uint64) uint64 {
+	if cur >= last {
+		return cur - last
+	}
+
+	return cur
+}
+
+// prune drops the buckets which are out of the retention period.
+func (h *logHistory) prune(buckets []LogBucket, now time.Time) []LogBucket {
+	expired := now.Add(-h.retention)
+
+	i := 0
+	for i < len(buckets) && buckets[i].Start.Add(LogBucketSize).Before(expired) {
+		i++
+	}
+
+	return buckets[i:]
+}
+
+// forget drops the history of transport `id`.
+func (h *logHistory) forget(id uuid.UUID) {
+	delete(h.sources, id)
+	delete(h.last, id)
+	delete(h.buckets, id)
+}
+
+// history returns buckets of transport `id` which overlap [since, until).
+func (h *logHistory) history(id uuid.UUID, since, until time.Time) []LogBucket {
+	var res []LogBucket
+
+	for _, b := range h.prune(h.buckets[id], time.Now()) {
+		if b.Start.Add(LogBucketSize).After(since) && b.Start.Before(until) {
+			res = append(res, b)
+		}
+	}
+
+	return res
 }
 
 type inMemoryTransportLogStore struct {
 	entries map[uuid.UUID]*LogEntry
+	hist    logHistory
 	mu      sync.Mutex
 }
 
 // InMemoryTransportLogStore implements in-memory TransportLogStore.
+// History is kept for DefaultLogRetention.
 func InMemoryTransportLogStore() LogStore {
 	return &inMemoryTransportLogStore{
 		entries: make(map[uuid.UUID]*LogEntry),
+		hist:    newLogHistory(DefaultLogRetention),
 	}
 }
 
@@ -105,20 +221,50 @@ func (tls *inMemoryTransportLogStore) Record(id uuid.UUID, entry *LogEntry) erro
 		tls.entries = make(map[uuid.UUID]*LogEntry)
 	}
 	tls.entries[id] = entry
+	tls.hist.add(id, entry, time.Now())
+	tls.mu.Unlock()
+	return nil
+}
+
+func (tls *inMemoryTransportLogStore) History(id uuid.UUID, since, until time.Time) ([]LogBucket, error) {
+	tls.mu.Lock()
+	defer tls.mu.Unlock()
+
+	return tls.hist.history(id, since, until), nil
+}
+
+func (tls *inMemoryTransportLogStore) Delete(id uuid.UUID) error {
+	tls.mu.Lock()
+	delete(tls.entries, id)
+	tls.hist.forget(id)
 	tls.mu.Unlock()
 	return nil
 }
 
+// fileLogFlushInterval is the minimal interval the history file of a transport is written at.
+const fileLogFlushInterval = time.Minute
+
 type fileTransportLogStore struct {
-	dir string
+	dir     string
+	hist    logHistory
+	flushed map[uuid.UUID]time.Time // when the history file of the transport was written
+	dirty   map[uuid.UUID]struct{}  // transports with history not written yet
+	mu      sync.Mutex
 }
 
 // FileTransportLogStore implements file TransportLogStore.
-func FileTransportLogStore(dir string) (LogStore, error) {
+// Totals are stored in '<id>.log' files, buckets newer than `retention` - in '<id>.history' files.
+// History files are written at most every `fileLogFlushInterval` and on Close.
+func FileTransportLogStore(dir string, retention time.Duration) (LogStore, error) {
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, err
 	}
-	return &fileTransportLogStore{dir}, nil
+	return &fileTransportLogStore{
+		dir:     dir,
+		hist:    newLogHistory(retention),
+		flushed: make(map[uuid.UUID]time.Time),
+		dirty:   make(map[uuid.UUID]struct{}),
+	}, nil
 }
 
 func (tls *fileTransportLogStore) Entry(id uuid.UUID) (*LogEntry, error) {
@@ -141,7 +287,115 @@ func (tls *fileTransportLogStore) Entry(id uuid.UUID) (*LogEntry, error) {
 }
 
 func (tls *fileTransportLogStore) Record(id uuid.UUID, entry *LogEntry) error {
-	f, err := os.OpenFile(filepath.Join(tls.dir, fmt.Sprintf("%s.log", id)), os.O_RDWR|os.O_CREATE, 0600)
+	if err := tls.writeJSON(fmt.Sprintf("%s.log", id), entry); err != nil {
+		return err
+	}
+
+	tls.mu.Lock()
+	defer tls.mu.Unlock()
+
+	if err := tls.loadHistory(id); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tls.hist.add(id, entry, now)
+	tls.dirty[id] = struct{}{}
+
+	if now.Sub(tls.flushed[id]) < fileLogFlushInterval {
+		return nil
+	}
+
+	return tls.writeHistory(id, now)
+}
+
+func (tls *fileTransportLogStore) History(id uuid.UUID, since, until time.Time) ([]LogBucket, error) {
+	tls.mu.Lock()
+	defer tls.mu.Unlock()
+
+	if err := tls.loadHistory(id); err != nil {
+		return nil, err
+	}
+
+	return tls.hist.history(id, since, until), nil
+}
+
+func (tls *fileTransportLogStore) Delete(id uuid.UUID) error {
+	tls.mu.Lock()
+	defer tls.mu.Unlock()
+
+	tls.hist.forget(id)
+	delete(tls.flushed, id)
+	delete(tls.dirty, id)
+
+	for _, name := range []string{fmt.Sprintf("%s.log", id), fmt.Sprintf("%s.history", id)} {
+		if err := os.Remove(filepath.Join(tls.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes the pending history.
+func (tls *fileTransportLogStore) Close() error {
+	tls.mu.Lock()
+	defer tls.mu.Unlock()
+
+	now := time.Now()
+	for id := range tls.dirty {
+		if err := tls.writeHistory(id, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeHistory writes buckets of transport `id` to the file.
+func (tls *fileTransportLogStore) writeHistory(id uuid.UUID, now time.Time) error {
+	if err := tls.writeJSON(fmt.Sprintf("%s.history", id), tls.hist.buckets[id]); err != nil {
+		return err
+	}
+
+	delete(tls.dirty, id)
+	tls.flushed[id] = now
+
+	return nil
+}
+
+// loadHistory reads buckets of transport `id` from the file, unless they are read already.
+func (tls *fileTransportLogStore) loadHistory(id uuid.UUID) error {
+	if _, ok := tls.hist.buckets[id]; ok {
+		return nil
+	}
+
+	var buckets []LogBucket
+
+	f, err := os.Open(filepath.Join(tls.dir, fmt.Sprintf("%s.history", id)))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("open: %w", err)
+	default:
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.WithError(err).Warn("Failed to close file")
+			}
+		}()
+
+		if err := json.NewDecoder(f).Decode(&buckets); err != nil {
+			return fmt.Errorf("json: %w", err)
+		}
+	}
+
+	tls.hist.buckets[id] = buckets
+
+	return nil
+}
+
+func (tls *fileTransportLogStore) writeJSON(name string, v interface{}) error {
+	f, err := os.OpenFile(filepath.Join(tls.dir, name), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return fmt.Errorf("open: %w", err)
 	}
@@ -151,7 +405,7 @@ func (tls *fileTransportLogStore) Record(id uuid.UUID, entry *LogEntry) error {
 		}
 	}()
 
-	if err := json.NewEncoder(f).Encode(entry); err != nil {
+	if err := json.NewEncoder(f).Encode(v); err != nil {
 		return fmt.Errorf("json: %w", err)
 	}
 
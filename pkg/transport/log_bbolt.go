@@ -0,0 +1,549 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	boltLogEntriesBucket = []byte("entries")
+	boltLogHistoryBucket = []byte("history")
+)
+
+const (
+	// BoltLogStoreDB is the name of the database file within the directory of the bbolt log store.
+	BoltLogStoreDB = "transport_logs.db"
+	// boltLogFlushInterval is the interval pending records are written to the database at.
+	boltLogFlushInterval = 10 * time.Second
+	// boltLogPruneInterval is the interval records out of the retention period are deleted at.
+	boltLogPruneInterval = LogBucketSize
+)
+
+// ErrLogStoreClosed is returned when the log store is used after it's closed.
+var ErrLogStoreClosed = errors.New("transport log store closed")
+
+type boltTransportLogStore struct {
+	db   *bbolt.DB
+	hist logHistory
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*LogEntry // records of transports active since the previous flush
+	dirty   map[uuid.UUID]struct{}
+	flushMx sync.Mutex // serializes flushes with deletions, so deleted records aren't written back
+
+	closed chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// BoltTransportLogStore implements TransportLogStore keeping entries of all transports in a single
+// bbolt database within `dir`. Records are written to the database in batches every
+// `boltLogFlushInterval` and on Close. Log files of the file log store found in `dir` are moved
+// to the database on start.
+// Entries and history of transports which have no traffic within `retention` are deleted every
+// `boltLogPruneInterval`, the database is compacted on start to give the freed space back.
+func BoltTransportLogStore(dir string, retention time.Duration) (LogStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, BoltLogStoreDB)
+	if err := compactLogDB(path); err != nil {
+		log.WithError(err).Warn("Failed to compact transport log db.")
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transport log db: %w", err)
+	}
+
+	ls := &boltTransportLogStore{
+		db:      db,
+		hist:    newLogHistory(retention),
+		entries: make(map[uuid.UUID]*LogEntry),
+		dirty:   make(map[uuid.UUID]struct{}),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if err := ls.init(dir); err != nil {
+		if cErr := db.Close(); cErr != nil {
+			log.WithError(cErr).Warn("Failed to close transport log db.")
+		}
+
+		return nil, err
+	}
+
+	if err := ls.prune(time.Now()); err != nil {
+		log.WithError(err).Warn("Failed to prune transport logs.")
+	}
+
+	go ls.flushLoop()
+
+	return ls, nil
+}
+
+// compactLogDB copies the database at `path` to a new file and replaces the database with it,
+// so pages freed by pruning are not kept in the file.
+func compactLogDB(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	tmpPath := path + ".compact"
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := copyLogDB(path, tmpPath); err != nil {
+		if rmErr := os.Remove(tmpPath); rmErr != nil && !os.IsNotExist(rmErr) {
+			log.WithError(rmErr).Warn("Failed to remove compacted transport log db.")
+		}
+
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// copyLogDB copies buckets of the database at `srcPath` to a new database at `dstPath`.
+func copyLogDB(srcPath, dstPath string) (err error) {
+	src, err := bbolt.Open(srcPath, 0600, &bbolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := src.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	dst, err := bbolt.Open(dstPath, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := dst.Close(); err == nil {
+			err = cErr
+		}
+	}()
+
+	return src.View(func(srcTx *bbolt.Tx) error {
+		return dst.Update(func(dstTx *bbolt.Tx) error {
+			return srcTx.ForEach(func(name []byte, srcB *bbolt.Bucket) error {
+				dstB, err := dstTx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+
+				// keys are put in order, so pages can be filled up
+				dstB.FillPercent = 1
+
+				return srcB.ForEach(dstB.Put)
+			})
+		})
+	})
+}
+
+// init creates the buckets and migrates log files of the file log store in `dir`.
+func (ls *boltTransportLogStore) init(dir string) error {
+	logFiles, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return err
+	}
+
+	var migrated []string
+
+	err = ls.db.Update(func(tx *bbolt.Tx) error {
+		entries, err := tx.CreateBucketIfNotExists(boltLogEntriesBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+
+		history, err := tx.CreateBucketIfNotExists(boltLogHistoryBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+
+		for _, logFile := range logFiles {
+			id, err := uuid.Parse(strings.TrimSuffix(filepath.Base(logFile), ".log"))
+			if err != nil {
+				continue
+			}
+
+			files, err := migrateLogFiles(entries, history, id, logFile)
+			if err != nil {
+				log.WithError(err).Warnf("Failed to migrate log of transport %s.", id)
+				continue
+			}
+
+			migrated = append(migrated, files...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(migrated) > 0 {
+		log.Infof("Migrated %d transport log files to %s.", len(migrated), BoltLogStoreDB)
+	}
+
+	for _, file := range migrated {
+		if err := os.Remove(file); err != nil {
+			log.WithError(err).Warnf("Failed to remove migrated log file %s.", file)
+		}
+	}
+
+	return nil
+}
+
+// migrateLogFiles puts the log entry of `logFile` and the history next to it to the database.
+// Returns the files migrated.
+func migrateLogFiles(entries, history *bbolt.Bucket, id uuid.UUID, logFile string) ([]string, error) {
+	entry, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(entry) {
+		return nil, errors.New("malformed log entry")
+	}
+
+	if err := entries.Put(id[:], entry); err != nil {
+		return nil, err
+	}
+
+	files := []string{logFile}
+	histFile := strings.TrimSuffix(logFile, ".log") + ".history"
+
+	buckets, err := ioutil.ReadFile(histFile)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	case json.Valid(buckets):
+		if err := history.Put(id[:], buckets); err != nil {
+			return nil, err
+		}
+
+		files = append(files, histFile)
+	}
+
+	return files, nil
+}
+
+func (ls *boltTransportLogStore) Entry(id uuid.UUID) (*LogEntry, error) {
+	ls.mu.Lock()
+	entry, ok := ls.entries[id]
+	ls.mu.Unlock()
+
+	if ok {
+		return entry, nil
+	}
+
+	var raw []byte
+
+	err := ls.db.View(func(tx *bbolt.Tx) error {
+		raw = append(raw, tx.Bucket(boltLogEntriesBucket).Get(id[:])...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		return nil, errors.New("transport log entry not found")
+	}
+
+	entry = &LogEntry{}
+	if err := json.Unmarshal(raw, entry); err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (ls *boltTransportLogStore) Record(id uuid.UUID, entry *LogEntry) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	// checked under the lock, so no records are made after the final flush
+	select {
+	case <-ls.closed:
+		return ErrLogStoreClosed
+	default:
+	}
+
+	if err := ls.loadHistory(id); err != nil {
+		return err
+	}
+
+	ls.entries[id] = entry
+	ls.hist.add(id, entry, time.Now())
+	ls.dirty[id] = struct{}{}
+
+	return nil
+}
+
+func (ls *boltTransportLogStore) History(id uuid.UUID, since, until time.Time) ([]LogBucket, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if err := ls.loadHistory(id); err != nil {
+		return nil, err
+	}
+
+	return ls.hist.history(id, since, until), nil
+}
+
+func (ls *boltTransportLogStore) Delete(id uuid.UUID) error {
+	ls.flushMx.Lock()
+	defer ls.flushMx.Unlock()
+
+	ls.mu.Lock()
+	delete(ls.entries, id)
+	delete(ls.dirty, id)
+	ls.hist.forget(id)
+	ls.mu.Unlock()
+
+	return ls.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(boltLogEntriesBucket).Delete(id[:]); err != nil {
+			return err
+		}
+
+		return tx.Bucket(boltLogHistoryBucket).Delete(id[:])
+	})
+}
+
+// Close writes the pending records and closes the database.
+func (ls *boltTransportLogStore) Close() error {
+	err := ErrLogStoreClosed
+
+	ls.once.Do(func() {
+		ls.mu.Lock()
+		close(ls.closed)
+		ls.mu.Unlock()
+		<-ls.done
+
+		err = ls.flush()
+		if cErr := ls.db.Close(); err == nil {
+			err = cErr
+		}
+	})
+
+	return err
+}
+
+// loadHistory reads buckets of transport `id` from the database, unless they are read already.
+func (ls *boltTransportLogStore) loadHistory(id uuid.UUID) error {
+	if _, ok := ls.hist.buckets[id]; ok {
+		return nil
+	}
+
+	var buckets []LogBucket
+
+	err := ls.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket(boltLogHistoryBucket).Get(id[:])
+		if raw == nil {
+			return nil
+		}
+
+		return json.Unmarshal(raw, &buckets)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load history of transport %s: %w", id, err)
+	}
+
+	ls.hist.buckets[id] = buckets
+
+	return nil
+}
+
+func (ls *boltTransportLogStore) flushLoop() {
+	defer close(ls.done)
+
+	ticker := time.NewTicker(boltLogFlushInterval)
+	defer ticker.Stop()
+
+	pruneTicker := time.NewTicker(boltLogPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ls.closed:
+			return
+		case <-ticker.C:
+			if err := ls.flush(); err != nil {
+				log.WithError(err).Warn("Failed to write transport logs.")
+			}
+		case now := <-pruneTicker.C:
+			if err := ls.prune(now); err != nil {
+				log.WithError(err).Warn("Failed to prune transport logs.")
+			}
+		}
+	}
+}
+
+// prune deletes entries and history of the transports which have no buckets within the retention period.
+// Transports recorded since the previous flush are left, they are written with the next flush.
+func (ls *boltTransportLogStore) prune(now time.Time) error {
+	ls.flushMx.Lock()
+	defer ls.flushMx.Unlock()
+
+	ls.mu.Lock()
+	active := make(map[uuid.UUID]struct{}, len(ls.entries))
+	for id := range ls.entries {
+		active[id] = struct{}{}
+	}
+	ls.mu.Unlock()
+
+	pruned := 0
+
+	err := ls.db.Update(func(tx *bbolt.Tx) error {
+		entries := tx.Bucket(boltLogEntriesBucket)
+		history := tx.Bucket(boltLogHistoryBucket)
+
+		var stale []uuid.UUID
+
+		err := entries.ForEach(func(k, _ []byte) error {
+			id, err := uuid.FromBytes(k)
+			if err != nil {
+				return nil
+			}
+
+			if _, ok := active[id]; ok {
+				return nil
+			}
+
+			var buckets []LogBucket
+			if raw := history.Get(k); raw != nil {
+				if err := json.Unmarshal(raw, &buckets); err != nil {
+					return fmt.Errorf("failed to read history of transport %s: %w", id, err)
+				}
+			}
+
+			if len(ls.hist.prune(buckets, now)) == 0 {
+				stale = append(stale, id)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range stale {
+			if err := entries.Delete(id[:]); err != nil {
+				return err
+			}
+
+			if err := history.Delete(id[:]); err != nil {
+				return err
+			}
+		}
+
+		pruned = len(stale)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if pruned > 0 {
+		log.Infof("Pruned logs of %d transports without traffic within the retention period.", pruned)
+	}
+
+	return nil
+}
+
+// flush writes the records made since the previous flush in a single transaction.
+func (ls *boltTransportLogStore) flush() error {
+	type record struct {
+		id      uuid.UUID
+		entry   []byte
+		buckets []byte
+	}
+
+	ls.flushMx.Lock()
+	defer ls.flushMx.Unlock()
+
+	ls.mu.Lock()
+	records := make([]record, 0, len(ls.dirty))
+
+	// Records of transports idle since the previous flush are in the database already, so they're
+	// evicted. The last totals are kept in the history, as they're needed to count the next record.
+	for id := range ls.entries {
+		if _, ok := ls.dirty[id]; !ok {
+			delete(ls.entries, id)
+		}
+	}
+
+	for id := range ls.hist.buckets {
+		if _, ok := ls.dirty[id]; !ok {
+			delete(ls.hist.buckets, id)
+		}
+	}
+
+	for id := range ls.dirty {
+		entry, err := json.Marshal(ls.entries[id])
+		if err != nil {
+			ls.mu.Unlock()
+			return err
+		}
+
+		buckets, err := json.Marshal(ls.hist.buckets[id])
+		if err != nil {
+			ls.mu.Unlock()
+			return err
+		}
+
+		records = append(records, record{id: id, entry: entry, buckets: buckets})
+	}
+
+	ls.dirty = make(map[uuid.UUID]struct{})
+	ls.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	err := ls.db.Update(func(tx *bbolt.Tx) error {
+		entries := tx.Bucket(boltLogEntriesBucket)
+		history := tx.Bucket(boltLogHistoryBucket)
+
+		for _, r := range records {
+			if err := entries.Put(r.id[:], r.entry); err != nil {
+				return err
+			}
+
+			if err := history.Put(r.id[:], r.buckets); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		// records are written with the next flush
+		ls.mu.Lock()
+		for _, r := range records {
+			ls.dirty[r.id] = struct{}{}
+		}
+		ls.mu.Unlock()
+	}
+
+	return err
+}
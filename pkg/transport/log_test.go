@@ -3,13 +3,17 @@ package transport_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
 
 	"github.com/skycoin/skywire/pkg/transport"
 )
@@ -34,12 +38,78 @@ func testTransportLogStore(t *testing.T, logStore transport.LogStore) {
 	require.NoError(t, err)
 	assert.Equal(t, uint64(300), entry.RecvBytes)
 	assert.Equal(t, uint64(400), entry.SentBytes)
+
+	entry1.AddRecv(50)
+	require.NoError(t, logStore.Record(id1, entry1))
+
+	now := time.Now()
+	history, err := logStore.History(id1, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+
+	var recv, sent uint64
+	for _, b := range history {
+		recv += b.RecvBytes
+		sent += b.SentBytes
+	}
+
+	assert.Equal(t, uint64(150), recv)
+	assert.Equal(t, uint64(200), sent)
+
+	history, err = logStore.History(id1, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// entries and history of deleted transports are gone
+	require.NoError(t, logStore.Delete(id1))
+
+	_, err = logStore.Entry(id1)
+	assert.Error(t, err)
+
+	history, err = logStore.History(id1, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	_, err = logStore.Entry(id2)
+	require.NoError(t, err)
 }
 
 func TestInMemoryTransportLogStore(t *testing.T) {
 	testTransportLogStore(t, transport.InMemoryTransportLogStore())
 }
 
+func TestInMemoryTransportLogStore_HistoryRecreated(t *testing.T) {
+	ls := transport.InMemoryTransportLogStore()
+	id := uuid.New()
+
+	entry := new(transport.LogEntry)
+	entry.AddRecv(100)
+	entry.AddSent(200)
+	require.NoError(t, ls.Record(id, entry))
+
+	// Transport re-created under the same ID counts from zero, even once its totals pass the old ones.
+	entry = new(transport.LogEntry)
+	entry.AddRecv(150)
+	entry.AddSent(20)
+	require.NoError(t, ls.Record(id, entry))
+
+	entry.AddSent(10)
+	require.NoError(t, ls.Record(id, entry))
+
+	now := time.Now()
+	history, err := ls.History(id, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	var recv, sent uint64
+	for _, b := range history {
+		recv += b.RecvBytes
+		sent += b.SentBytes
+	}
+
+	assert.Equal(t, uint64(250), recv)
+	assert.Equal(t, uint64(230), sent)
+}
+
 func TestFileTransportLogStore(t *testing.T) {
 	dir, err := ioutil.TempDir("", "log_store")
 	require.NoError(t, err)
@@ -47,11 +117,180 @@ func TestFileTransportLogStore(t *testing.T) {
 		require.NoError(t, os.RemoveAll(dir))
 	}()
 
-	ls, err := transport.FileTransportLogStore(dir)
+	ls, err := transport.FileTransportLogStore(dir, 0)
 	require.NoError(t, err)
 	testTransportLogStore(t, ls)
 }
 
+func TestFileTransportLogStore_History(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log_store")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	ls, err := transport.FileTransportLogStore(dir, time.Hour)
+	require.NoError(t, err)
+
+	id := uuid.New()
+	entry := new(transport.LogEntry)
+	entry.AddRecv(100)
+	require.NoError(t, ls.Record(id, entry))
+
+	// History survives restarts, totals of the new transport start from zero.
+	ls, err = transport.FileTransportLogStore(dir, time.Hour)
+	require.NoError(t, err)
+
+	entry = new(transport.LogEntry)
+	entry.AddRecv(10)
+	require.NoError(t, ls.Record(id, entry))
+
+	now := time.Now()
+	history, err := ls.History(id, now.Add(-2*time.Hour), now)
+	require.NoError(t, err)
+
+	var recv uint64
+	for _, b := range history {
+		recv += b.RecvBytes
+	}
+
+	assert.Equal(t, uint64(110), recv)
+
+	// History of frequent records is written in batches.
+	entry.AddRecv(5)
+	require.NoError(t, ls.Record(id, entry))
+
+	recvOf := func(ls transport.LogStore) uint64 {
+		history, err := ls.History(id, now.Add(-2*time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+
+		var recv uint64
+		for _, b := range history {
+			recv += b.RecvBytes
+		}
+
+		return recv
+	}
+
+	reopened, err := transport.FileTransportLogStore(dir, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(110), recvOf(reopened))
+
+	require.NoError(t, ls.(io.Closer).Close())
+
+	reopened, err = transport.FileTransportLogStore(dir, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(115), recvOf(reopened))
+}
+
+func TestBoltTransportLogStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log_store")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	// Logs of the file log store are moved to the database.
+	fileLS, err := transport.FileTransportLogStore(dir, 0)
+	require.NoError(t, err)
+
+	migratedID := uuid.New()
+	migratedEntry := new(transport.LogEntry)
+	migratedEntry.AddSent(500)
+	require.NoError(t, fileLS.Record(migratedID, migratedEntry))
+
+	ls, err := transport.BoltTransportLogStore(dir, 0)
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	testTransportLogStore(t, ls)
+
+	entry, err := ls.Entry(migratedID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(500), entry.SentBytes)
+
+	// Pending records are written on close.
+	recordedID := uuid.New()
+	recordedEntry := new(transport.LogEntry)
+	recordedEntry.AddRecv(42)
+	require.NoError(t, ls.Record(recordedID, recordedEntry))
+	require.NoError(t, ls.(io.Closer).Close())
+
+	ls, err = transport.BoltTransportLogStore(dir, 0)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, ls.(io.Closer).Close())
+	}()
+
+	entry, err = ls.Entry(recordedID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), entry.RecvBytes)
+
+	now := time.Now()
+	history, err := ls.History(migratedID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+}
+
+func TestBoltTransportLogStore_prune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log_store")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	ls, err := transport.BoltTransportLogStore(dir, time.Hour)
+	require.NoError(t, err)
+
+	activeID := uuid.New()
+	activeEntry := new(transport.LogEntry)
+	activeEntry.AddRecv(10)
+	require.NoError(t, ls.Record(activeID, activeEntry))
+	require.NoError(t, ls.(io.Closer).Close())
+
+	// Records made after close are refused.
+	assert.Equal(t, transport.ErrLogStoreClosed, ls.Record(activeID, activeEntry))
+
+	// Logs of a transport without traffic for longer than the retention period.
+	staleID := uuid.New()
+	staleHistory, err := json.Marshal([]transport.LogBucket{
+		{Start: time.Now().Add(-3 * time.Hour).UTC().Truncate(transport.LogBucketSize), RecvBytes: 5},
+	})
+	require.NoError(t, err)
+
+	db, err := bbolt.Open(filepath.Join(dir, transport.BoltLogStoreDB), 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte("entries")).Put(staleID[:], []byte(`{"recv":5,"sent":0}`)); err != nil {
+			return err
+		}
+
+		return tx.Bucket([]byte("history")).Put(staleID[:], staleHistory)
+	}))
+	require.NoError(t, db.Close())
+
+	// Stale logs are pruned on start.
+	ls, err = transport.BoltTransportLogStore(dir, time.Hour)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, ls.(io.Closer).Close())
+	}()
+
+	_, err = ls.Entry(staleID)
+	assert.Error(t, err)
+
+	entry, err := ls.Entry(activeID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), entry.RecvBytes)
+
+	// The database is compacted in place.
+	_, err = os.Stat(filepath.Join(dir, transport.BoltLogStoreDB+".compact"))
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestLogEntry_MarshalJSON(t *testing.T) {
 	entry := new(transport.LogEntry)
 	entry.AddSent(10)
@@ -11,6 +11,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/skycoin/dmsg/httputil"
 	"github.com/skycoin/dmsg/netutil"
@@ -23,6 +24,9 @@ import (
 
 const logWriteInterval = time.Second * 3
 
+// settlementTimeout is the timeout of the settlement handshake.
+const settlementTimeout = time.Second * 20
+
 // Records number of managedTransports.
 var mTpCount int32
 
@@ -51,6 +55,8 @@ type ManagedTransportConfig struct {
 	RemotePK    cipher.PubKey
 	NetName     string
 	AfterClosed TPCloseCallback
+
+	caps *dataCaps
 }
 
 // ManagedTransport manages a direct line of communication between two visor nodes.
@@ -65,9 +71,17 @@ type ManagedTransport struct {
 	Entry      Entry
 	LogEntry   *LogEntry
 	logUpdates uint32
+	capsBytes  uint64 // bytes of 'LogEntry' counted against the data caps
+	logMx      sync.Mutex
+	removed    bool // whether the log entry is deleted from the log store, protected by 'logMx'
+
+	dc     DiscoveryClient
+	ls     LogStore
+	caps   *dataCaps
+	probes *prober
 
-	dc DiscoveryClient
-	ls LogStore
+	probeReplies chan uint32 // sequence numbers of the remote probes to reply to
+	reporting    int32       // whether the probe stats are being reported, accessed atomically
 
 	isUp    bool  // records last successful status update to discovery
 	isUpErr error // records whether the last status update was successful or not
@@ -76,10 +90,11 @@ type ManagedTransport struct {
 	redialCancel context.CancelFunc // for canceling redialling logic
 	redialMx     sync.Mutex
 
-	n      *snet.Network
-	conn   *snet.Conn
-	connCh chan struct{}
-	connMx sync.Mutex
+	n        *snet.Network
+	conn     *snet.Conn
+	features Features // features negotiated for 'conn'
+	connCh   chan struct{}
+	connMx   sync.Mutex
 
 	done chan struct{}
 	once sync.Once
@@ -94,17 +109,20 @@ type ManagedTransport struct {
 // NewManagedTransport creates a new ManagedTransport.
 func NewManagedTransport(conf ManagedTransportConfig) *ManagedTransport {
 	mt := &ManagedTransport{
-		log:         logging.MustGetLogger(fmt.Sprintf("tp:%s", conf.RemotePK.String()[:6])),
-		rPK:         conf.RemotePK,
-		netName:     conf.NetName,
-		n:           conf.Net,
-		dc:          conf.DC,
-		ls:          conf.LS,
-		Entry:       makeEntry(conf.Net.LocalPK(), conf.RemotePK, conf.NetName),
-		LogEntry:    new(LogEntry),
-		connCh:      make(chan struct{}, 1),
-		done:        make(chan struct{}),
-		afterClosed: conf.AfterClosed,
+		log:          logging.MustGetLogger(fmt.Sprintf("tp:%s", conf.RemotePK.String()[:6])),
+		rPK:          conf.RemotePK,
+		netName:      conf.NetName,
+		n:            conf.Net,
+		dc:           conf.DC,
+		ls:           conf.LS,
+		caps:         conf.caps,
+		probes:       newProber(),
+		probeReplies: make(chan uint32, probeRepliesSize),
+		Entry:        makeEntry(conf.Net.LocalPK(), conf.RemotePK, conf.NetName),
+		LogEntry:     new(LogEntry),
+		connCh:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		afterClosed:  conf.AfterClosed,
 	}
 	mt.wg.Add(2)
 	return mt
@@ -135,13 +153,12 @@ func (mt *ManagedTransport) Serve(readCh chan<- routing.Packet) {
 
 	log.Info("Serving.")
 
+	go mt.replyProbes()
+
 	defer func() {
 		// Ensure logs tp logs are up to date before closing.
-		if mt.logMod() {
-			if err := mt.ls.Record(mt.Entry.ID, mt.LogEntry); err != nil {
-				log.WithError(err).Warn("Failed to record log entry.")
-			}
-		}
+		mt.countCaps()
+		mt.recordLog()
 
 		// End connection.
 		mt.connMx.Lock()
@@ -179,6 +196,10 @@ func (mt *ManagedTransport) Serve(readCh chan<- routing.Packet) {
 				log.WithError(err).Warn("Failed to read packet.")
 				continue
 			}
+			if p.Type() == routing.TransportProbePacket {
+				mt.handleProbe(p)
+				continue
+			}
 			select {
 			case <-mt.done:
 				return
@@ -188,19 +209,27 @@ func (mt *ManagedTransport) Serve(readCh chan<- routing.Packet) {
 		}
 	}()
 
-	// Logging & redialing loop.
+	// Logging, probing & redialing loop.
 	logTicker := time.NewTicker(logWriteInterval)
+	probeTicker := time.NewTicker(probeInterval)
+	reportTicker := time.NewTicker(probeReportInterval)
 	for {
 		select {
 		case <-mt.done:
 			logTicker.Stop()
+			probeTicker.Stop()
+			reportTicker.Stop()
 			return
 
+		case <-probeTicker.C:
+			mt.probe()
+
+		case <-reportTicker.C:
+			mt.reportProbes(ctx)
+
 		case <-logTicker.C:
-			if mt.logMod() {
-				if err := mt.ls.Record(mt.Entry.ID, mt.LogEntry); err != nil {
-					mt.log.WithError(err).Warn("Failed to record log entry.")
-				}
+			mt.countCaps()
+			if mt.recordLog() {
 				continue
 			}
 
@@ -258,6 +287,19 @@ func (mt *ManagedTransport) close() {
 	}
 }
 
+// remove stops serving the transport and deletes its log entry and history from the log store.
+func (mt *ManagedTransport) remove() {
+	mt.logMx.Lock()
+	mt.removed = true
+	mt.logMx.Unlock()
+
+	mt.close()
+
+	if err := mt.ls.Delete(mt.Entry.ID); err != nil {
+		mt.log.WithError(err).Warn("Failed to delete log entry.")
+	}
+}
+
 // disconnect stops serving the transport and ensures that transport status is updated to DOWN.
 // It also waits until mt.Serve returns if specified.
 func (mt *ManagedTransport) disconnect() {
@@ -283,16 +325,17 @@ func (mt *ManagedTransport) Accept(ctx context.Context, conn *snet.Conn) error {
 		return ErrNotServing
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	ctx, cancel := context.WithTimeout(ctx, settlementTimeout)
 	defer cancel()
 
 	mt.log.Debug("Performing settlement handshake...")
-	if err := MakeSettlementHS(false).Do(ctx, mt.dc, conn, mt.n.LocalSK()); err != nil {
+	features, err := MakeSettlementHS(false).Do(ctx, mt.dc, conn, mt.n.LocalSK())
+	if err != nil {
 		return fmt.Errorf("settlement handshake failed: %w", err)
 	}
 
 	mt.log.Debug("Setting underlying connection...")
-	return mt.setConn(conn)
+	return mt.setConn(conn, features)
 }
 
 // Dial dials a new underlying connection.
@@ -316,14 +359,15 @@ func (mt *ManagedTransport) dial(ctx context.Context) error {
 		return fmt.Errorf("snet.Dial: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	ctx, cancel := context.WithTimeout(ctx, settlementTimeout)
 	defer cancel()
 
-	if err := MakeSettlementHS(true).Do(ctx, mt.dc, tp, mt.n.LocalSK()); err != nil {
+	features, err := MakeSettlementHS(true).Do(ctx, mt.dc, tp, mt.n.LocalSK())
+	if err != nil {
 		return fmt.Errorf("settlement handshake failed: %w", err)
 	}
 
-	if err := mt.setConn(tp); err != nil {
+	if err := mt.setConn(tp, features); err != nil {
 		return fmt.Errorf("setConn: %w", err)
 	}
 
@@ -401,7 +445,7 @@ func (mt *ManagedTransport) getConn() *snet.Conn {
 
 // setConn sets 'mt.conn' (the underlying connection).
 // If 'mt.conn' is already occupied, close the newly introduced connection.
-func (mt *ManagedTransport) setConn(newConn *snet.Conn) error {
+func (mt *ManagedTransport) setConn(newConn *snet.Conn, features Features) error {
 
 	if mt.conn != nil {
 		if mt.isLeastSignificantEdge() {
@@ -425,6 +469,7 @@ func (mt *ManagedTransport) setConn(newConn *snet.Conn) error {
 
 	// Set new underlying connection.
 	mt.conn = newConn
+	mt.features = features
 	select {
 	case mt.connCh <- struct{}{}:
 		mt.log.Debug("Sent signal to 'mt.connCh'.")
@@ -546,13 +591,24 @@ func (mt *ManagedTransport) WritePacket(ctx context.Context, packet routing.Pack
 		}
 	}
 
-	n, err := mt.conn.Write(packet)
-	if err != nil {
+	return mt.write(packet)
+}
+
+// write writes a packet to the underlying connection, 'mt.connMx' should be locked.
+func (mt *ManagedTransport) write(packet routing.Packet) error {
+	// remote visors which don't support hop limits only understand legacy headers.
+	b := []byte(packet)
+	if !mt.features.Has(FeatureHopLimit) {
+		b = packet.Legacy()
+	}
+
+	if _, err := mt.conn.Write(b); err != nil {
 		mt.clearConn()
 		return err
 	}
-	if n > routing.PacketHeaderSize {
-		mt.logSent(uint64(n - routing.PacketHeaderSize))
+	// probes are not a traffic of routes, so they're neither logged nor counted against data caps.
+	if size := packet.Size(); size > 0 && packet.Type() != routing.TransportProbePacket {
+		mt.logSent(uint64(size))
 	}
 	return nil
 }
@@ -575,11 +631,18 @@ func (mt *ManagedTransport) readPacket() (packet routing.Packet, err error) {
 
 	log.Debug("Awaiting packet...")
 
-	h := make(routing.Packet, routing.PacketHeaderSize)
+	h := make(routing.Packet, routing.LegacyPacketHeaderSize, routing.PacketHeaderSize)
 	if _, err = io.ReadFull(conn, h); err != nil {
 		log.WithError(err).Debugf("Failed to read packet header.")
 		return nil, err
 	}
+	if !h.IsLegacy() {
+		h = h[:routing.PacketHeaderSize]
+		if _, err = io.ReadFull(conn, h[routing.LegacyPacketHeaderSize:]); err != nil {
+			log.WithError(err).Debugf("Failed to read packet hop limit.")
+			return nil, err
+		}
+	}
 	log.WithField("header_len", len(h)).WithField("header_raw", h).Debug("Read packet header.")
 
 	p := make([]byte, h.Size())
@@ -590,8 +653,11 @@ func (mt *ManagedTransport) readPacket() (packet routing.Packet, err error) {
 	log.WithField("payload_len", len(p)).Debug("Read packet payload.")
 
 	packet = append(h, p...)
-	if n := len(packet); n > routing.PacketHeaderSize {
-		mt.logRecv(uint64(n - routing.PacketHeaderSize))
+	if packet.IsLegacy() {
+		packet = routing.FromLegacy(packet)
+	}
+	if size := packet.Size(); size > 0 && packet.Type() != routing.TransportProbePacket {
+		mt.logRecv(uint64(size))
 	}
 
 	log.WithField("type", packet.Type().String()).
@@ -601,6 +667,95 @@ func (mt *ManagedTransport) readPacket() (packet routing.Packet, err error) {
 	return packet, nil
 }
 
+/*
+	<<< TRANSPORT PROBING >>>
+*/
+
+// probe sends a probe to the remote, unless the underlying connection is down or the remote doesn't support probes.
+func (mt *ManagedTransport) probe() {
+	mt.connMx.Lock()
+	defer mt.connMx.Unlock()
+
+	if mt.conn == nil || !mt.features.Has(FeatureProbe) {
+		return
+	}
+
+	seq := mt.probes.start(time.Now())
+	if err := mt.write(routing.MakeTransportProbePacket(seq, false)); err != nil {
+		mt.log.WithError(err).Debug("Failed to send probe.")
+	}
+}
+
+// handleProbe replies to the probes of the remote and records the replies to the local ones.
+func (mt *ManagedTransport) handleProbe(packet routing.Packet) {
+	seq, reply, err := packet.TransportProbe()
+	if err != nil {
+		mt.log.WithError(err).Debug("Failed to parse probe.")
+		return
+	}
+
+	if reply {
+		mt.probes.done(seq, time.Now())
+		return
+	}
+
+	// replies are written by a single worker, so the read loop isn't blocked by the writes.
+	// The remote counts the probes which are not replied to as lost, so they're dropped if the worker lags.
+	select {
+	case mt.probeReplies <- seq:
+	default:
+		mt.log.Debug("Dropped probe, too many replies pending.")
+	}
+}
+
+// replyProbes writes the replies to the remote probes until the transport is closed.
+func (mt *ManagedTransport) replyProbes() {
+	for {
+		select {
+		case <-mt.done:
+			return
+		case seq := <-mt.probeReplies:
+			mt.connMx.Lock()
+			if mt.conn != nil {
+				if err := mt.write(routing.MakeTransportProbePacket(seq, true)); err != nil {
+					mt.log.WithError(err).Debug("Failed to reply to probe.")
+				}
+			}
+			mt.connMx.Unlock()
+		}
+	}
+}
+
+// reportProbes reports the probe stats to the transport discovery, so the route finder may use them.
+// The report is sent in the background, a new one isn't started while the previous one is in progress.
+func (mt *ManagedTransport) reportProbes(ctx context.Context) {
+	stats := mt.ProbeStats()
+	if stats.Samples == 0 || !mt.IsUp() {
+		return
+	}
+
+	if !atomic.CompareAndSwapInt32(&mt.reporting, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&mt.reporting, 0)
+
+		ctx, cancel := context.WithTimeout(ctx, probeReportTimeout)
+		defer cancel()
+
+		if _, err := mt.dc.UpdateStatuses(ctx, &Status{ID: mt.Entry.ID, IsUp: true, Probe: &stats}); err != nil {
+			mt.log.WithError(err).Debug("Failed to report probe stats.")
+		}
+	}()
+}
+
+// ProbeStats returns the stats of the latest probes of the transport.
+// There are no samples if the remote doesn't support probes.
+func (mt *ManagedTransport) ProbeStats() ProbeStats {
+	return mt.probes.stats()
+}
+
 /*
 	<<< TRANSPORT LOGGING >>>
 */
@@ -615,6 +770,41 @@ func (mt *ManagedTransport) logRecv(b uint64) {
 	atomic.AddUint32(&mt.logUpdates, 1)
 }
 
+// countCaps counts the traffic logged since the previous call against the data caps.
+// Traffic is counted once per log write interval, so caps may be overshot by the traffic of an interval.
+func (mt *ManagedTransport) countCaps() {
+	logged := atomic.LoadUint64(&mt.LogEntry.SentBytes) + atomic.LoadUint64(&mt.LogEntry.RecvBytes)
+	if logged == mt.capsBytes {
+		return
+	}
+
+	mt.caps.add(mt.netName, mt.rPK, logged-mt.capsBytes)
+	mt.capsBytes = logged
+}
+
+// DataCapExceeded checks whether a data cap of the transport is exceeded,
+// no new route groups should go via the transport then.
+func (mt *ManagedTransport) DataCapExceeded() bool {
+	return mt.caps.exceeded(mt.netName, mt.rPK) != ""
+}
+
+// recordLog records the log entry if it's modified since the previous record and the transport isn't removed.
+// It reports whether the entry was modified.
+func (mt *ManagedTransport) recordLog() bool {
+	mt.logMx.Lock()
+	defer mt.logMx.Unlock()
+
+	if mt.removed || !mt.logMod() {
+		return false
+	}
+
+	if err := mt.ls.Record(mt.Entry.ID, mt.LogEntry); err != nil {
+		mt.log.WithError(err).Warn("Failed to record log entry.")
+	}
+
+	return true
+}
+
 func (mt *ManagedTransport) logMod() bool {
 	if ops := atomic.SwapUint32(&mt.logUpdates, 0); ops > 0 {
 		mt.log.Infof("entry log: recording %d operations", ops)
@@ -626,5 +816,8 @@ func (mt *ManagedTransport) logMod() bool {
 // Remote returns the remote public key.
 func (mt *ManagedTransport) Remote() cipher.PubKey { return mt.rPK }
 
+// TransportID returns the transport ID.
+func (mt *ManagedTransport) TransportID() uuid.UUID { return mt.Entry.ID }
+
 // Type returns the transport type.
 func (mt *ManagedTransport) Type() string { return mt.netName }
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/skycoin/dmsg"
 	"github.com/skycoin/dmsg/cipher"
 	"github.com/skycoin/skycoin/src/util/logging"
 
@@ -24,6 +26,13 @@ import (
 const (
 	// TrustedVisorsDelay defines a delay before adding transports to trusted visors.
 	TrustedVisorsDelay = 5 * time.Second
+
+	// AutoType makes SaveTransport choose the type of transport, direct types are tried
+	// in the order of preference and dmsg is the last resort.
+	AutoType = "auto"
+
+	// autoDialTimeout limits dialing of a single direct type of transport with AutoType.
+	autoDialTimeout = 10 * time.Second
 )
 
 // TPCloseCallback triggers after a session is closed.
@@ -31,11 +40,15 @@ type TPCloseCallback func(network, addr string)
 
 // ManagerConfig configures a Manager.
 type ManagerConfig struct {
-	PubKey          cipher.PubKey
-	SecKey          cipher.SecKey
-	DefaultVisors   []cipher.PubKey // Visors to automatically connect to
-	DiscoveryClient DiscoveryClient
-	LogStore        LogStore
+	PubKey               cipher.PubKey
+	SecKey               cipher.SecKey
+	DefaultVisors        []cipher.PubKey // Visors to automatically connect to
+	DiscoveryClient      DiscoveryClient
+	LogStore             LogStore
+	DataCaps             []DataCap
+	AcceptPolicy         AcceptPolicy
+	PersistentTransports []PersistentTransport // Transports to keep established once the manager is served
+	Bonds                []BondConfig
 }
 
 // Manager manages Transports.
@@ -58,6 +71,27 @@ type Manager struct {
 	done          chan struct{}
 
 	afterTPClosed TPCloseCallback
+
+	caps              *dataCaps
+	onDataCapExceeded DataCapCallback
+
+	bonds map[uuid.UUID]*Bond
+
+	policy AcceptPolicy
+
+	persistentMx sync.Mutex
+	persistent   []PersistentTransport
+	keepers      map[PersistentTransport]chan struct{} // closed to stop keeping the transport
+	keepersWg    sync.WaitGroup
+}
+
+// PacketWriter writes packets to a remote visor, it's either a managed transport or a bond.
+type PacketWriter interface {
+	WritePacket(ctx context.Context, packet routing.Packet) error
+	// TransportID returns the ID routing rules refer to the writer by.
+	TransportID() uuid.UUID
+	Remote() cipher.PubKey
+	IsUp() bool
 }
 
 // NewManager creates a Manager with the provided configuration and transport factories.
@@ -74,7 +108,19 @@ func NewManager(log *logging.Logger, n *snet.Network, config *ManagerConfig) (*M
 		n:           n,
 		readCh:      make(chan routing.Packet, 20),
 		done:        make(chan struct{}),
+		caps:        newDataCaps(config.DataCaps, config.LogStore),
+		bonds:       make(map[uuid.UUID]*Bond),
+		policy:      config.AcceptPolicy,
+	}
+	tm.caps.setCallback(tm.dataCapExceeded)
+
+	// bonds are restored before routing rules referring to them are used
+	for _, bc := range config.Bonds {
+		if _, err := tm.SaveBond(bc.Remote, bc.Types); err != nil {
+			return nil, fmt.Errorf("bond to %s: %w", bc.Remote, err)
+		}
 	}
+
 	return tm, nil
 }
 
@@ -91,6 +137,60 @@ func (tm *Manager) OnAfterTPClosed(f TPCloseCallback) {
 	}
 }
 
+// AcceptPolicy returns the policy incoming transports are accepted by.
+func (tm *Manager) AcceptPolicy() AcceptPolicy {
+	tm.mx.RLock()
+	defer tm.mx.RUnlock()
+
+	return tm.policy
+}
+
+// SetAcceptPolicy sets the policy incoming transports are accepted by.
+// Transports which are already established are kept.
+func (tm *Manager) SetAcceptPolicy(policy AcceptPolicy) {
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	tm.policy = policy
+}
+
+// OnDataCapExceeded sets callback which will fire once a data cap is exceeded.
+func (tm *Manager) OnDataCapExceeded(f DataCapCallback) {
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	tm.onDataCapExceeded = f
+}
+
+// dataCapExceeded closes the transports of the exceeded data cap if required.
+func (tm *Manager) dataCapExceeded(event DataCapEvent) {
+	tm.Logger.Warnf("Data cap exceeded: type(%s) remote(%s) period(%s) used(%d) action(%s)",
+		event.Cap.TpType, event.Cap.RemotePK, event.Period, event.Used, event.Cap.action())
+
+	tm.mx.Lock()
+	var closing []*ManagedTransport
+	if event.Cap.action() == DataCapClose {
+		for id, tp := range tm.tps {
+			if event.Cap.matches(tp.netName, tp.rPK) {
+				closing = append(closing, tp)
+				delete(tm.tps, id)
+			}
+		}
+		tm.refreshBonds()
+	}
+	onExceeded := tm.onDataCapExceeded
+	tm.mx.Unlock()
+
+	for _, tp := range closing {
+		tm.Logger.Infof("Closing transport %s due to exceeded data cap.", tp.Entry.ID)
+		tp.close()
+	}
+
+	if onExceeded != nil {
+		onExceeded(event)
+	}
+}
+
 // Serve runs listening loop across all registered factories.
 func (tm *Manager) Serve(ctx context.Context) {
 	tm.serveOnce.Do(func() {
@@ -165,6 +265,11 @@ func (tm *Manager) serve(ctx context.Context) {
 	}
 
 	tm.initTransports(ctx)
+
+	if err := tm.SetPersistentTransports(tm.Conf.PersistentTransports); err != nil {
+		tm.Logger.WithError(err).Error("Failed to set persistent transports.")
+	}
+
 	tm.Logger.Info("transport manager is serving.")
 
 	// closing logic
@@ -213,16 +318,38 @@ func (tm *Manager) acceptTransport(ctx context.Context, lis *snet.Listener) erro
 
 	tm.Logger.Infof("recv transport connection request: type(%s) remote(%s)", lis.Network(), conn.RemotePK())
 
+	tpID := tm.tpIDFromPK(conn.RemotePK(), conn.Network())
+
 	tm.mx.Lock()
-	defer tm.mx.Unlock()
 
 	if tm.isClosing() {
+		tm.mx.Unlock()
 		return errors.New("transport.Manager is closing. Skipping incoming transport")
 	}
 
 	// For transports for purpose(data).
 
-	tpID := tm.tpIDFromPK(conn.RemotePK(), conn.Network())
+	if tm.caps.exceeded(lis.Network(), conn.RemotePK()) == DataCapClose {
+		tm.mx.Unlock()
+
+		if err := conn.Close(); err != nil {
+			tm.Logger.WithError(err).Warn("Failed to close connection of capped transport.")
+		}
+
+		return fmt.Errorf("transport %s: %w", tpID, ErrDataCapExceeded)
+	}
+
+	// The policy is checked under the same lock the transport is saved with, so that concurrent transports
+	// can't exceed the limit per remote. Rejected transports are settled outside of the lock,
+	// so that the denied remotes can't hold the manager up.
+	if err := tm.checkAcceptPolicy(lis.Network(), conn.RemotePK(), tpID); err != nil {
+		tm.mx.Unlock()
+		tm.rejectTransport(ctx, conn)
+
+		return fmt.Errorf("transport %s: %w", tpID, err)
+	}
+
+	defer tm.mx.Unlock()
 
 	mTp, ok := tm.tps[tpID]
 	if !ok {
@@ -235,17 +362,18 @@ func (tm *Manager) acceptTransport(ctx context.Context, lis *snet.Listener) erro
 			RemotePK:    conn.RemotePK(),
 			NetName:     lis.Network(),
 			AfterClosed: tm.afterTPClosed,
+			caps:        tm.caps,
 		})
+		tm.caps.register(tpID, lis.Network(), conn.RemotePK())
 
 		go func() {
 			mTp.Serve(tm.readCh)
 
-			tm.mx.Lock()
-			delete(tm.tps, mTp.Entry.ID)
-			tm.mx.Unlock()
+			tm.forgetTransport(mTp)
 		}()
 
 		tm.tps[tpID] = mTp
+		tm.refreshBonds()
 	} else {
 		tm.Logger.Debugln("TP found, accepting...")
 	}
@@ -259,6 +387,42 @@ func (tm *Manager) acceptTransport(ctx context.Context, lis *snet.Listener) erro
 	return nil
 }
 
+// checkAcceptPolicy checks the incoming transport `tpID` of type `tpType` from `remote` against the accept policy.
+// NOTE: should be called under the `tm.mx` lock.
+func (tm *Manager) checkAcceptPolicy(tpType string, remote cipher.PubKey, tpID uuid.UUID) error {
+	return tm.policy.Check(tpType, remote, tm.countTransports(remote, tpID))
+}
+
+// rejectTransport rejects settlement of the incoming transport and closes its connection.
+// NOTE: shouldn't be called under the `tm.mx` lock, as the handshake may take long.
+func (tm *Manager) rejectTransport(ctx context.Context, conn *snet.Conn) {
+	ctx, cancel := context.WithTimeout(ctx, settlementTimeout)
+	defer cancel()
+
+	reject := func(*snet.Conn) error { return ErrTransportRejected }
+	if _, err := MakeRespondingSettlementHS(reject).Do(ctx, tm.Conf.DiscoveryClient, conn, tm.Conf.SecKey); err != nil &&
+		!errors.Is(err, ErrTransportRejected) {
+		tm.Logger.WithError(err).Debug("Failed to perform settlement handshake of rejected transport.")
+	}
+
+	if err := conn.Close(); err != nil {
+		tm.Logger.WithError(err).Warn("Failed to close connection of rejected transport.")
+	}
+}
+
+// countTransports returns the number of transports to `remote` except of the transport `except`.
+// NOTE: should be called under the `tm.mx` lock.
+func (tm *Manager) countTransports(remote cipher.PubKey, except uuid.UUID) int {
+	n := 0
+	for id, tp := range tm.tps {
+		if id != except && tp.Remote() == remote {
+			n++
+		}
+	}
+
+	return n
+}
+
 // SaveTransport begins to attempt to establish data transports to the given 'remote' visor.
 func (tm *Manager) SaveTransport(ctx context.Context, remote cipher.PubKey, tpType string) (*ManagedTransport, error) {
 
@@ -266,6 +430,10 @@ func (tm *Manager) SaveTransport(ctx context.Context, remote cipher.PubKey, tpTy
 		return nil, io.ErrClosedPipe
 	}
 
+	if tpType == AutoType {
+		return tm.saveAutoTransport(ctx, remote)
+	}
+
 	for {
 		mTp, err := tm.saveTransport(remote, tpType)
 		if err != nil {
@@ -304,6 +472,64 @@ func (tm *Manager) SaveTransport(ctx context.Context, remote cipher.PubKey, tpTy
 	}
 }
 
+// saveAutoTransport establishes transport of the first of `autoTypes` which gets up within `autoDialTimeout`.
+// Transports of direct types which fail to get up are removed, unless they existed before.
+// Transport of dmsg type is saved as with SaveTransport if none of the direct ones gets up.
+func (tm *Manager) saveAutoTransport(ctx context.Context, remote cipher.PubKey) (*ManagedTransport, error) {
+	for _, tpType := range tm.autoTypes(remote) {
+		existed := tm.Transport(tm.tpIDFromPK(remote, tpType)) != nil
+
+		dialCtx, cancel := context.WithTimeout(ctx, autoDialTimeout)
+		mTp, err := tm.SaveTransport(dialCtx, remote, tpType)
+		cancel()
+
+		if err != nil {
+			tm.Logger.WithError(err).Debugf("Failed to save %s transport to %s.", tpType, remote)
+		} else if mTp.IsUp() {
+			tm.Logger.Infof("Chose %s transport to %s.", tpType, remote)
+			return mTp, nil
+		} else if !existed {
+			tm.Logger.Debugf("Transport %s to %s isn't up, removing.", tpType, remote)
+			if closeErr := mTp.Close(); closeErr != nil {
+				tm.Logger.WithError(closeErr).Warn("Closing mTp returns non-nil error.")
+			}
+			tm.deleteTransport(mTp.Entry.ID)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !tm.n.IsNetworkReady(dmsg.Type) {
+		return nil, fmt.Errorf("no transport type to reach %s: %w", remote, snet.ErrUnknownNetwork)
+	}
+
+	tm.Logger.Infof("Chose %s transport to %s.", dmsg.Type, remote)
+
+	return tm.SaveTransport(ctx, remote, dmsg.Type)
+}
+
+// autoTypes returns the direct types of transports to try with AutoType, in the order of preference.
+// STCP is only tried if the remote is in the PK table.
+func (tm *Manager) autoTypes(remote cipher.PubKey) []string {
+	var types []string
+
+	for _, tpType := range []string{tptypes.SUDPH, tptypes.STCPR} {
+		if tm.n.IsNetworkReady(tpType) {
+			types = append(types, tpType)
+		}
+	}
+
+	if stcp := tm.n.Conf().NetworkConfigs.STCP; stcp != nil && tm.n.IsNetworkReady(tptypes.STCP) {
+		if _, ok := stcp.PKTable[remote]; ok {
+			types = append(types, tptypes.STCP)
+		}
+	}
+
+	return types
+}
+
 // isSTCPPKError returns true if the error is a STCP table error.
 // This occurs the requested remote public key does not exist in the STCP table.
 func isSTCPTableError(remotePK cipher.PubKey, err error) bool {
@@ -326,6 +552,10 @@ func (tm *Manager) saveTransport(remote cipher.PubKey, netName string) (*Managed
 		return oldMTp, nil
 	}
 
+	if tm.caps.exceeded(netName, remote) == DataCapClose {
+		return nil, ErrDataCapExceeded
+	}
+
 	afterTPClosed := tm.afterTPClosed
 
 	mTp := NewManagedTransport(ManagedTransportConfig{
@@ -335,7 +565,9 @@ func (tm *Manager) saveTransport(remote cipher.PubKey, netName string) (*Managed
 		RemotePK:    remote,
 		NetName:     netName,
 		AfterClosed: afterTPClosed,
+		caps:        tm.caps,
 	})
+	tm.caps.register(tpID, netName, remote)
 
 	if mTp.netName == tptypes.STCPR {
 		ar := mTp.n.Conf().ARClient
@@ -352,9 +584,10 @@ func (tm *Manager) saveTransport(remote cipher.PubKey, netName string) (*Managed
 	}
 	go func() {
 		mTp.Serve(tm.readCh)
-		tm.deleteTransport(mTp.Entry.ID)
+		tm.forgetTransport(mTp)
 	}()
 	tm.tps[tpID] = mTp
+	tm.refreshBonds()
 	tm.Logger.Infof("saved transport: remote(%s) type(%s) tpID(%s)", remote, netName, tpID)
 	return mTp, nil
 }
@@ -396,9 +629,10 @@ func (tm *Manager) DeleteTransport(id uuid.UUID) {
 			tm.Logger.Infof("De-registered transport of ID %s from discovery.", id)
 		}
 
-		// Close underlying connection.
-		tp.close()
+		// Close underlying connection and forget the traffic of the transport.
+		tp.remove()
 		delete(tm.tps, id)
+		tm.refreshBonds()
 	}
 }
 
@@ -406,6 +640,28 @@ func (tm *Manager) deleteTransport(id uuid.UUID) {
 	tm.mx.Lock()
 	defer tm.mx.Unlock()
 	delete(tm.tps, id)
+	tm.refreshBonds()
+}
+
+// forgetTransport deletes `mTp` once it stops serving. Transport of the same ID which replaced `mTp`
+// in the meantime is kept.
+func (tm *Manager) forgetTransport(mTp *ManagedTransport) {
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	if tm.tps[mTp.Entry.ID] != mTp {
+		return
+	}
+
+	delete(tm.tps, mTp.Entry.ID)
+	tm.refreshBonds()
+}
+
+// refreshBonds updates members of the bonds as transports are saved or deleted, 'tm.mx' should be locked.
+func (tm *Manager) refreshBonds() {
+	for _, b := range tm.bonds {
+		b.refresh(tm.tps)
+	}
 }
 
 // ReadPacket reads data packets from routes.
@@ -434,6 +690,99 @@ func (tm *Manager) Transport(id uuid.UUID) *ManagedTransport {
 	return tr
 }
 
+// Resolve obtains a Transport via a given Transport ID. If the ID is of a bond, one of its members is picked.
+func (tm *Manager) Resolve(id uuid.UUID) *ManagedTransport {
+	if tp := tm.Transport(id); tp != nil {
+		return tp
+	}
+
+	if b := tm.Bond(id); b != nil {
+		return b.Pick()
+	}
+
+	return nil
+}
+
+// Writer obtains a Transport or a bond via a given ID, nil is returned if there is neither.
+func (tm *Manager) Writer(id uuid.UUID) PacketWriter {
+	if tp := tm.Transport(id); tp != nil {
+		return tp
+	}
+
+	if b := tm.Bond(id); b != nil {
+		return b
+	}
+
+	return nil
+}
+
+// SaveBond bonds the transports to `remote` of `types`, transports of any type are bonded if `types` is empty.
+// There is a single bond per remote, ErrBondExists is returned if `remote` is already bonded to.
+func (tm *Manager) SaveBond(remote cipher.PubKey, types []string) (*Bond, error) {
+	for _, tpType := range types {
+		if !snet.IsKnownNetwork(tpType) {
+			return nil, fmt.Errorf("%w: %s", snet.ErrUnknownNetwork, tpType)
+		}
+	}
+
+	b := &Bond{
+		ID:     MakeBondID(tm.Conf.PubKey, remote),
+		remote: remote,
+		types:  types,
+	}
+
+	tm.mx.Lock()
+	if _, ok := tm.bonds[b.ID]; ok {
+		tm.mx.Unlock()
+		return nil, fmt.Errorf("bond %s: %w", b.ID, ErrBondExists)
+	}
+	b.refresh(tm.tps)
+	tm.bonds[b.ID] = b
+	tm.mx.Unlock()
+
+	tm.Logger.Infof("Saved bond %s to %s of types %v.", b.ID, remote, types)
+
+	return b, nil
+}
+
+// Bond obtains a bond via a given ID.
+func (tm *Manager) Bond(id uuid.UUID) *Bond {
+	tm.mx.RLock()
+	b := tm.bonds[id]
+	tm.mx.RUnlock()
+	return b
+}
+
+// Bonds returns all the bonds ordered by ID.
+func (tm *Manager) Bonds() []*Bond {
+	tm.mx.RLock()
+	bonds := make([]*Bond, 0, len(tm.bonds))
+	for _, b := range tm.bonds {
+		bonds = append(bonds, b)
+	}
+	tm.mx.RUnlock()
+
+	sort.Slice(bonds, func(i, j int) bool {
+		return bonds[i].ID.String() < bonds[j].ID.String()
+	})
+
+	return bonds
+}
+
+// DeleteBond removes a bond, its member transports are kept.
+func (tm *Manager) DeleteBond(id uuid.UUID) error {
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
+
+	if _, ok := tm.bonds[id]; !ok {
+		return ErrBondNotFound
+	}
+
+	delete(tm.bonds, id)
+
+	return nil
+}
+
 // WalkTransports ranges through all transports.
 func (tm *Manager) WalkTransports(walk func(tp *ManagedTransport) bool) {
 	tm.mx.RLock()
@@ -464,9 +813,14 @@ func (tm *Manager) close() {
 	}
 
 	tm.mx.Lock()
-	defer tm.mx.Unlock()
-
 	close(tm.done)
+	tm.mx.Unlock()
+
+	// keepers of persistent transports may still be dialing, they save transports under the lock
+	tm.keepersWg.Wait()
+
+	tm.mx.Lock()
+	defer tm.mx.Unlock()
 
 	statuses := make([]*Status, 0, len(tm.tps))
 	for _, tr := range tm.tps {
@@ -2,20 +2,29 @@ package transport_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/skycoin/dmsg"
 	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/dmsg/disc"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/snet"
+	"github.com/skycoin/skywire/pkg/snet/arclient"
+	"github.com/skycoin/skywire/pkg/snet/directtp"
+	"github.com/skycoin/skywire/pkg/snet/directtp/pktable"
+	"github.com/skycoin/skywire/pkg/snet/directtp/tptypes"
 	"github.com/skycoin/skywire/pkg/snet/snettest"
 	"github.com/skycoin/skywire/pkg/transport"
 )
@@ -156,6 +165,121 @@ func TestNewManager(t *testing.T) {
 		require.NotNil(t, err)
 		require.Contains(t, err.Error(), "not found")
 	})
+
+	// Ensure dmsg is chosen by auto type when there are no direct types.
+	t.Run("check_auto_tp", func(t *testing.T) {
+		tp, err := m2.SaveTransport(context.TODO(), pk0, transport.AutoType)
+		require.NoError(t, err)
+		assert.Equal(t, dmsg.Type, tp.Type())
+		assert.Equal(t, transport.MakeTransportID(pk0, pk1, dmsg.Type), tp.Entry.ID)
+	})
+
+	// Ensure packets written via a bond reach the remote.
+	t.Run("check_bond", func(t *testing.T) {
+		_, err := m2.SaveBond(pk0, []string{"unknown"})
+		require.Error(t, err)
+
+		tp, err := m2.SaveTransport(context.TODO(), pk0, dmsg.Type)
+		require.NoError(t, err)
+
+		bond, err := m2.SaveBond(pk0, nil)
+		require.NoError(t, err)
+		assert.Equal(t, transport.MakeBondID(pk0, pk1), bond.ID)
+		assert.Equal(t, []*transport.ManagedTransport{tp}, bond.Members())
+		assert.Equal(t, tp, m2.Resolve(bond.ID))
+
+		// remote should accept the transport before the packet is written
+		require.Eventually(t, func() bool {
+			rTp := m0.Transport(tp.Entry.ID)
+			return rTp != nil && rTp.IsUp()
+		}, 5*time.Second, 50*time.Millisecond)
+
+		packet, err := routing.MakeDataPacket(1, []byte("bond"))
+		require.NoError(t, err)
+
+		readCh := make(chan routing.Packet, 1)
+		go func() {
+			recv, err := m0.ReadPacket()
+			if err == nil {
+				readCh <- recv
+			}
+		}()
+
+		// transports of the same ID left by the previous subtests may still be redialing and replacing
+		// the connection of the remote, so the packet is written again until it's received
+		timeout := time.After(10 * time.Second)
+		for received := false; !received; {
+			if err := m2.Writer(bond.ID).WritePacket(context.TODO(), packet); err != nil {
+				t.Logf("Failed to write packet via bond: %v", err)
+			}
+
+			select {
+			case recv := <-readCh:
+				assert.Equal(t, []byte("bond"), recv.Payload())
+				received = true
+			case <-time.After(500 * time.Millisecond):
+			case <-timeout:
+				t.Fatal("packet written via bond is not received")
+			}
+		}
+
+		_, err = m2.SaveBond(pk0, []string{"stcpr"})
+		require.True(t, errors.Is(err, transport.ErrBondExists))
+
+		require.NoError(t, m2.DeleteBond(bond.ID))
+		bond, err = m2.SaveBond(pk0, []string{"stcpr"})
+		require.NoError(t, err)
+		assert.Empty(t, bond.Members())
+		assert.Nil(t, m2.Resolve(bond.ID))
+
+		require.NoError(t, m2.DeleteBond(bond.ID))
+		assert.Nil(t, m2.Writer(bond.ID))
+		assert.Equal(t, transport.ErrBondNotFound, m2.DeleteBond(bond.ID))
+	})
+
+	// Ensure members of a bond follow the transports saved and deleted.
+	t.Run("check_bond_members", func(t *testing.T) {
+		tpID := transport.MakeTransportID(pk0, pk1, dmsg.Type)
+
+		bond, err := m2.SaveBond(pk0, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []*transport.ManagedTransport{m2.Transport(tpID)}, bond.Members())
+
+		m2.DeleteTransport(tpID)
+		assert.Empty(t, bond.Members())
+		assert.False(t, bond.IsUp())
+
+		// the deleted transport may still be closing, so saving is retried until the new one is kept
+		require.Eventually(t, func() bool {
+			tp, err := m2.SaveTransport(context.TODO(), pk0, dmsg.Type)
+			members := bond.Members()
+			return err == nil && m2.Transport(tpID) == tp && len(members) == 1 && members[0] == tp
+		}, 5*time.Second, 50*time.Millisecond)
+
+		require.NoError(t, m2.DeleteBond(bond.ID))
+	})
+
+	// Ensure persistent transports are re-created once deleted.
+	t.Run("check_persistent_tp", func(t *testing.T) {
+		require.Error(t, m2.SetPersistentTransports([]transport.PersistentTransport{{PK: pk0, Type: "unknown"}}))
+
+		pt := transport.PersistentTransport{PK: pk0, Type: dmsg.Type}
+		require.NoError(t, m2.SetPersistentTransports([]transport.PersistentTransport{pt, pt}))
+		assert.Equal(t, []transport.PersistentTransport{pt}, m2.PersistentTransports())
+
+		tpID := transport.MakeTransportID(pk0, pk1, dmsg.Type)
+		require.Eventually(t, func() bool { return m2.Transport(tpID) != nil }, 5*time.Second, 50*time.Millisecond)
+
+		tp := m2.Transport(tpID)
+		m2.DeleteTransport(tpID)
+		require.Eventually(t, func() bool {
+			newTp := m2.Transport(tpID)
+			return newTp != nil && newTp != tp
+		}, 5*time.Second, 50*time.Millisecond)
+
+		require.NoError(t, m2.SetPersistentTransports(nil))
+		assert.Empty(t, m2.PersistentTransports())
+	})
 }
 
 func TestSortEdges(t *testing.T) {
@@ -181,3 +305,146 @@ func TestMakeTransportID(t *testing.T) {
 		require.NotEqual(t, transport.MakeTransportID(keyA, keyA, "a"), transport.MakeTransportID(keyA, keyA, "b"))
 	})
 }
+
+func TestManager_SaveTransport_auto(t *testing.T) {
+	keys := snettest.GenKeyPairs(3)
+	pk0, pk1, pk2 := keys[0].PK, keys[1].PK, keys[2].PK
+
+	addr1, addr2 := freeLocalAddr(t), freeLocalAddr(t)
+	table := map[cipher.PubKey]string{pk1: addr1, pk2: addr2}
+
+	// direct types which need the address resolver never reach the remote, so the manager goes on to stcp
+	ar := new(arclient.MockAPIClient)
+	ar.On("Resolve", mock.Anything, mock.Anything, mock.Anything).
+		Return(arclient.VisorData{}, errors.New("visor is not found"))
+	ar.On("Close").Return(nil)
+
+	tpDisc := transport.NewDiscoveryMock()
+
+	n0 := newAutoTestNetwork(t, keys[0], "", table, ar, true)
+	m0, err := transport.NewManager(nil, n0, &transport.ManagerConfig{
+		PubKey:          pk0,
+		SecKey:          keys[0].SK,
+		DiscoveryClient: tpDisc,
+		LogStore:        transport.InMemoryTransportLogStore(),
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, m0.Close()) }()
+
+	n1 := newAutoTestNetwork(t, keys[1], addr1, nil, ar, false)
+	m1, err := transport.NewManager(nil, n1, &transport.ManagerConfig{
+		PubKey:          pk1,
+		SecKey:          keys[1].SK,
+		DiscoveryClient: tpDisc,
+		LogStore:        transport.InMemoryTransportLogStore(),
+	})
+	require.NoError(t, err)
+	go m1.Serve(context.TODO())
+	defer func() { require.NoError(t, m1.Close()) }()
+
+	// stcp is served asynchronously
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr1)
+		if err != nil {
+			return false
+		}
+
+		return conn.Close() == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Ensure direct types are tried in the order of preference, dmsg is ready but not used.
+	t.Run("direct_types_order", func(t *testing.T) {
+		tp, err := m0.SaveTransport(context.TODO(), pk1, transport.AutoType)
+		require.NoError(t, err)
+		assert.Equal(t, tptypes.STCP, tp.Type())
+		assert.True(t, tp.IsUp())
+
+		var resolved []string
+		for _, call := range ar.Calls {
+			if call.Arguments.Get(2).(cipher.PubKey) == pk1 {
+				resolved = append(resolved, call.Arguments.String(1))
+			}
+		}
+
+		assert.Equal(t, []string{tptypes.SUDPH, tptypes.STCPR}, resolved)
+		assert.Nil(t, m0.Transport(transport.MakeTransportID(pk0, pk1, tptypes.SUDPH)))
+		assert.Nil(t, m0.Transport(transport.MakeTransportID(pk0, pk1, tptypes.STCPR)))
+	})
+
+	// Ensure saved transport of a direct type which never gets up is dropped.
+	t.Run("drop_not_up", func(t *testing.T) {
+		n := newAutoTestNetwork(t, keys[0], "", table, ar, false)
+		m, err := transport.NewManager(nil, n, &transport.ManagerConfig{
+			PubKey:          pk0,
+			SecKey:          keys[0].SK,
+			DiscoveryClient: tpDisc,
+			LogStore:        transport.InMemoryTransportLogStore(),
+		})
+		require.NoError(t, err)
+		defer func() { require.NoError(t, m.Close()) }()
+
+		// nothing listens on the address of pk2 and there's no dmsg to fall back to
+		_, err = m.SaveTransport(context.TODO(), pk2, transport.AutoType)
+		require.True(t, errors.Is(err, snet.ErrUnknownNetwork))
+		assert.Nil(t, m.Transport(transport.MakeTransportID(pk0, pk2, tptypes.STCP)))
+	})
+}
+
+// newAutoTestNetwork returns network of all the direct types, only stcp is served on `localAddr`
+// if it's not empty. Dmsg is ready but not served if `withDmsg` is true.
+func newAutoTestNetwork(t *testing.T, keys snettest.KeyPair, localAddr string, table map[cipher.PubKey]string,
+	ar arclient.APIClient, withDmsg bool) *snet.Network {
+	clients := snet.NetworkClients{
+		Direct: make(map[string]directtp.Client),
+	}
+
+	if withDmsg {
+		clients.DmsgC = dmsg.NewClient(keys.PK, keys.SK, disc.NewMock(0), nil)
+	}
+
+	for _, tpType := range []string{tptypes.SUDPH, tptypes.STCPR} {
+		clients.Direct[tpType] = directtp.NewClient(directtp.Config{
+			Type:            tpType,
+			PK:              keys.PK,
+			SK:              keys.SK,
+			AddressResolver: ar,
+		})
+	}
+
+	stcp := directtp.NewClient(directtp.Config{
+		Type:      tptypes.STCP,
+		PK:        keys.PK,
+		SK:        keys.SK,
+		Table:     pktable.NewTable(table),
+		LocalAddr: localAddr,
+	})
+	clients.Direct[tptypes.STCP] = stcp
+
+	if localAddr != "" {
+		require.NoError(t, stcp.Serve())
+	}
+
+	n := snet.NewRaw(snet.Config{
+		PubKey: keys.PK,
+		SecKey: keys.SK,
+		NetworkConfigs: snet.NetworkConfigs{
+			STCP: &snet.STCPConfig{PKTable: table, LocalAddr: localAddr},
+		},
+	}, clients)
+
+	t.Cleanup(func() {
+		if err := n.Close(); err != nil {
+			t.Logf("Failed to close network: %v", err)
+		}
+	})
+
+	return n
+}
+
+func freeLocalAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	return l.Addr().String()
+}
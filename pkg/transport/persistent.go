@@ -0,0 +1,148 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/skycoin/dmsg/cipher"
+	"github.com/skycoin/dmsg/netutil"
+
+	"github.com/skycoin/skywire/pkg/snet"
+)
+
+// PersistentTransport is a transport the visor keeps established, it's re-created once it's deleted or fails.
+// Type may be AutoType, then the best available type is chosen each time the transport is created.
+type PersistentTransport struct {
+	PK   cipher.PubKey `json:"pk"`
+	Type string        `json:"type"`
+}
+
+func (pt PersistentTransport) validate() error {
+	if pt.PK.Null() {
+		return fmt.Errorf("persistent transport of type %s: null public key", pt.Type)
+	}
+
+	if pt.Type != AutoType && !snet.IsKnownNetwork(pt.Type) {
+		return fmt.Errorf("persistent transport to %s: %w: %s", pt.PK, snet.ErrUnknownNetwork, pt.Type)
+	}
+
+	return nil
+}
+
+// PersistentTransports returns the transports the manager keeps established.
+func (tm *Manager) PersistentTransports() []PersistentTransport {
+	tm.persistentMx.Lock()
+	defer tm.persistentMx.Unlock()
+
+	return append([]PersistentTransport(nil), tm.persistent...)
+}
+
+// SetPersistentTransports sets the transports the manager keeps established. Transports which are not
+// persistent anymore are kept, but they are not re-created once they are deleted.
+func (tm *Manager) SetPersistentTransports(pts []PersistentTransport) error {
+	for _, pt := range pts {
+		if err := pt.validate(); err != nil {
+			return err
+		}
+	}
+
+	tm.persistentMx.Lock()
+	defer tm.persistentMx.Unlock()
+
+	keepers := make(map[PersistentTransport]chan struct{}, len(pts))
+	persistent := make([]PersistentTransport, 0, len(pts))
+
+	for _, pt := range pts {
+		if _, ok := keepers[pt]; ok {
+			continue
+		}
+
+		stop, ok := tm.keepers[pt]
+		if !ok {
+			stop = make(chan struct{})
+			tm.keepersWg.Add(1)
+			go tm.keepTransport(pt, stop)
+		}
+
+		keepers[pt] = stop
+		persistent = append(persistent, pt)
+	}
+
+	for pt, stop := range tm.keepers {
+		if _, ok := keepers[pt]; !ok {
+			close(stop)
+		}
+	}
+
+	tm.keepers = keepers
+	tm.persistent = persistent
+
+	return nil
+}
+
+// keepTransport establishes the persistent transport and re-creates it with back-off once it's closed,
+// until `stop` or the manager is closed.
+func (tm *Manager) keepTransport(pt PersistentTransport, stop <-chan struct{}) {
+	defer tm.keepersWg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stop:
+		case <-tm.done:
+		}
+		cancel()
+	}()
+
+	log := tm.Logger.WithField("remote", pt.PK).WithField("type", pt.Type)
+	retry := netutil.NewRetrier(log, tpInitBO, tpMaxBO, tpTries, tpFactor).
+		WithErrWhitelist(context.Canceled, io.ErrClosedPipe)
+
+	bo := tpInitBO
+
+	for {
+		var mTp *ManagedTransport
+
+		err := retry.Do(ctx, func() (err error) {
+			mTp, err = tm.SaveTransport(ctx, pt.PK, pt.Type)
+			if err != nil {
+				log.WithError(err).Warn("Failed to create persistent transport.")
+			}
+
+			return err
+		})
+		if err != nil {
+			return
+		}
+
+		log.WithField("tp_id", mTp.Entry.ID).Info("Persistent transport is created.")
+		created := time.Now()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-mTp.done:
+		}
+
+		// back-off is reset for transports which were up for a while, it grows for the unstable ones.
+		if time.Since(created) > tpMaxBO {
+			bo = tpInitBO
+		}
+
+		log.WithField("tp_id", mTp.Entry.ID).Infof("Persistent transport is closed, re-creating in %s.", bo)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(bo):
+		}
+
+		if bo = time.Duration(float64(bo) * tpFactor); bo > tpMaxBO {
+			bo = tpMaxBO
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// probeInterval is the interval transports are probed at.
+	probeInterval = 10 * time.Second
+	// probeTimeout is the time after which a probe that isn't replied to is considered lost.
+	probeTimeout = 5 * time.Second
+	// probeWindow is the number of the latest probes the stats are computed of.
+	probeWindow = 30
+	// probeReportInterval is the interval the stats are reported to the transport discovery at.
+	probeReportInterval = 5 * time.Minute
+	// probeReportTimeout is the time a report of the stats may take.
+	probeReportTimeout = 30 * time.Second
+	// probeRepliesSize is the number of the replies to the remote probes which may be pending.
+	probeRepliesSize = 8
+	// maxWeightLoss caps the loss used to compute the weight, so lossy transports still have a finite weight.
+	maxWeightLoss = 0.99
+)
+
+// ProbeStats are the rolling statistics of the latest probes of a transport.
+type ProbeStats struct {
+	RTT     time.Duration `json:"rtt"`
+	Jitter  time.Duration `json:"jitter"`
+	Loss    float64       `json:"loss"` // fraction of the lost probes
+	Samples int           `json:"samples"`
+}
+
+// Weight returns the weight of the transport as an edge of the visor graph, that is the round trip time
+// increased by the expected number of retransmissions. The lower is the better.
+func (s ProbeStats) Weight() time.Duration {
+	rtt := s.RTT
+	if rtt == 0 {
+		// none of the probes is replied to
+		rtt = probeTimeout
+	}
+
+	return time.Duration(float64(rtt) / (1 - math.Min(s.Loss, maxWeightLoss)))
+}
+
+type probeSample struct {
+	rtt  time.Duration
+	lost bool
+}
+
+// prober keeps track of the probes sent and the latest samples.
+type prober struct {
+	mx      sync.Mutex
+	seq     uint32
+	pending map[uint32]time.Time
+	samples []probeSample
+}
+
+func newProber() *prober {
+	return &prober{
+		pending: make(map[uint32]time.Time),
+	}
+}
+
+// start returns the sequence number of a new probe sent at `now`.
+func (p *prober) start(now time.Time) uint32 {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.expire(now)
+
+	p.seq++
+	p.pending[p.seq] = now
+
+	return p.seq
+}
+
+// done records the reply to probe `seq` received at `now`. Replies to unknown or expired probes are ignored.
+func (p *prober) done(seq uint32, now time.Time) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	sent, ok := p.pending[seq]
+	if !ok {
+		return
+	}
+
+	delete(p.pending, seq)
+
+	if rtt := now.Sub(sent); rtt <= probeTimeout {
+		p.add(probeSample{rtt: rtt})
+	} else {
+		p.add(probeSample{lost: true})
+	}
+}
+
+// expire records the probes which aren't replied to in time as lost.
+func (p *prober) expire(now time.Time) {
+	for seq, sent := range p.pending {
+		if now.Sub(sent) > probeTimeout {
+			delete(p.pending, seq)
+			p.add(probeSample{lost: true})
+		}
+	}
+}
+
+func (p *prober) add(s probeSample) {
+	p.samples = append(p.samples, s)
+
+	if len(p.samples) > probeWindow {
+		p.samples = p.samples[len(p.samples)-probeWindow:]
+	}
+}
+
+// stats computes the stats of the latest samples. Jitter is the mean difference of the consecutive RTTs.
+func (p *prober) stats() ProbeStats {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	var (
+		stats      = ProbeStats{Samples: len(p.samples)}
+		rtts, lost int
+		sum, diffs time.Duration
+		prev       time.Duration
+	)
+
+	for _, s := range p.samples {
+		if s.lost {
+			lost++
+			continue
+		}
+
+		if rtts > 0 {
+			diff := s.rtt - prev
+			if diff < 0 {
+				diff = -diff
+			}
+
+			diffs += diff
+		}
+
+		sum += s.rtt
+		prev = s.rtt
+		rtts++
+	}
+
+	if rtts > 0 {
+		stats.RTT = sum / time.Duration(rtts)
+	}
+
+	if rtts > 1 {
+		stats.Jitter = diffs / time.Duration(rtts-1)
+	}
+
+	if stats.Samples > 0 {
+		stats.Loss = float64(lost) / float64(stats.Samples)
+	}
+
+	return stats
+}
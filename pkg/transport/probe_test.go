@@ -0,0 +1,50 @@
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProber(t *testing.T) {
+	p := newProber()
+	require.Equal(t, ProbeStats{}, p.stats())
+
+	now := time.Now()
+
+	for _, rtt := range []time.Duration{10, 30, 20} {
+		seq := p.start(now)
+		p.done(seq, now.Add(rtt*time.Millisecond))
+	}
+
+	// Replies to unknown probes are ignored.
+	p.done(100, now)
+
+	// Probe is lost once it's not replied to in time.
+	lost := p.start(now)
+	now = now.Add(probeTimeout + time.Second)
+	p.start(now)
+	p.done(lost, now)
+
+	stats := p.stats()
+	require.Equal(t, 20*time.Millisecond, stats.RTT)
+	require.Equal(t, 15*time.Millisecond, stats.Jitter)
+	require.Equal(t, 0.25, stats.Loss)
+	require.Equal(t, 4, stats.Samples)
+	require.InDelta(t, float64(20*time.Millisecond)/0.75, float64(stats.Weight()), 1)
+
+	// Only the latest probes are taken into account.
+	for i := 0; i < probeWindow; i++ {
+		seq := p.start(now)
+		p.done(seq, now.Add(time.Millisecond))
+	}
+
+	stats = p.stats()
+	require.Equal(t, time.Millisecond, stats.RTT)
+	require.Zero(t, stats.Jitter)
+	require.Zero(t, stats.Loss)
+	require.Equal(t, probeWindow, stats.Samples)
+
+	require.InDelta(t, float64(probeTimeout*100), float64(ProbeStats{Loss: 1, Samples: 1}.Weight()), float64(time.Millisecond))
+}
@@ -2,7 +2,6 @@ package visor
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -45,20 +44,32 @@ type API interface {
 	GetAppConnectionsSummary(appName string) ([]appserver.ConnectionSummary, error)
 
 	TransportTypes() ([]string, error)
-	Transports(types []string, pks []cipher.PubKey, logs bool) ([]*TransportSummary, error)
+	Transports(types []string, pks []cipher.PubKey, logs bool, since time.Time) ([]*TransportSummary, error)
 	Transport(tid uuid.UUID) (*TransportSummary, error)
+	TransportHistory(tid uuid.UUID, since, until time.Time) ([]transport.LogBucket, error)
 	AddTransport(remote cipher.PubKey, tpType string, public bool, timeout time.Duration) (*TransportSummary, error)
 	RemoveTransport(tid uuid.UUID) error
 
+	Bonds() ([]*BondSummary, error)
+	AddBond(remote cipher.PubKey, types []string) (*BondSummary, error)
+	RemoveBond(id uuid.UUID) error
+
+	TransportPolicy() (transport.AcceptPolicy, error)
+	SetTransportPolicy(policy transport.AcceptPolicy) error
+
+	PersistentTransports() ([]transport.PersistentTransport, error)
+	SetPersistentTransports(pts []transport.PersistentTransport) error
+
 	DiscoverTransportsByPK(pk cipher.PubKey) ([]*transport.EntryWithStatus, error)
 	DiscoverTransportByID(id uuid.UUID) (*transport.EntryWithStatus, error)
 
-	RoutingRules() ([]routing.Rule, error)
+	RoutingRules() ([]routing.RuleEntry, error)
 	RoutingRule(key routing.RouteID) (routing.Rule, error)
 	SaveRoutingRule(rule routing.Rule) error
 	RemoveRoutingRule(key routing.RouteID) error
 
 	RouteGroups() ([]RouteGroupInfo, error)
+	TraceRoute(rid routing.RouteID) ([]routing.TraceHop, error)
 
 	Restart() error
 	Exec(command string) ([]byte, error)
@@ -96,7 +107,7 @@ func (v *Visor) Summary() (*Summary, error) {
 	}
 	v.tpM.WalkTransports(func(tp *transport.ManagedTransport) bool {
 		summaries = append(summaries,
-			newTransportSummary(v.tpM, tp, true, v.router.SetupIsTrusted(tp.Remote())))
+			newTransportSummary(v.tpM, tp, true, v.router.IsSetupNode(tp.Remote())))
 		return true
 	})
 
@@ -145,11 +156,7 @@ func (v *Visor) ExtraSummary() (*ExtraSummary, error) {
 
 	extraRoutes := make([]routingRuleResp, 0, len(routes))
 	for _, route := range routes {
-		extraRoutes = append(extraRoutes, routingRuleResp{
-			Key:     route.KeyRouteID(),
-			Rule:    hex.EncodeToString(route),
-			Summary: route.Summary(),
-		})
+		extraRoutes = append(extraRoutes, makeRoutingRuleEntryResp(route, true))
 	}
 
 	extraSummary := &ExtraSummary{
@@ -427,7 +434,8 @@ func (v *Visor) TransportTypes() ([]string, error) {
 }
 
 // Transports implements API.
-func (v *Visor) Transports(types []string, pks []cipher.PubKey, logs bool) ([]*TransportSummary, error) {
+// Traffic history since `since` is included to the summaries unless `since` is zero.
+func (v *Visor) Transports(types []string, pks []cipher.PubKey, logs bool, since time.Time) ([]*TransportSummary, error) {
 	var result []*TransportSummary
 
 	typeIncluded := func(tType string) bool {
@@ -454,11 +462,25 @@ func (v *Visor) Transports(types []string, pks []cipher.PubKey, logs bool) ([]*T
 	}
 	v.tpM.WalkTransports(func(tp *transport.ManagedTransport) bool {
 		if typeIncluded(tp.Type()) && pkIncluded(v.tpM.Local(), tp.Remote()) {
-			result = append(result, newTransportSummary(v.tpM, tp, logs, v.router.SetupIsTrusted(tp.Remote())))
+			result = append(result, newTransportSummary(v.tpM, tp, logs, v.router.IsSetupNode(tp.Remote())))
 		}
 		return true
 	})
 
+	if since.IsZero() {
+		return result, nil
+	}
+
+	now := time.Now()
+	for _, summary := range result {
+		history, err := v.TransportHistory(summary.ID, since, now)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.History = history
+	}
+
 	return result, nil
 }
 
@@ -469,7 +491,12 @@ func (v *Visor) Transport(tid uuid.UUID) (*TransportSummary, error) {
 		return nil, ErrNotFound
 	}
 
-	return newTransportSummary(v.tpM, tp, true, v.router.SetupIsTrusted(tp.Remote())), nil
+	return newTransportSummary(v.tpM, tp, true, v.router.IsSetupNode(tp.Remote())), nil
+}
+
+// TransportHistory implements API.
+func (v *Visor) TransportHistory(tid uuid.UUID, since, until time.Time) ([]transport.LogBucket, error) {
+	return v.tpM.Conf.LogStore.History(tid, since, until)
 }
 
 // AddTransport implements API.
@@ -489,9 +516,9 @@ func (v *Visor) AddTransport(remote cipher.PubKey, tpType string, public bool, t
 		return nil, err
 	}
 
-	v.log.Debugf("Saved transport to %v via %v", remote, tpType)
+	v.log.Debugf("Saved transport to %v via %v", remote, tp.Type())
 
-	return newTransportSummary(v.tpM, tp, false, v.router.SetupIsTrusted(tp.Remote())), nil
+	return newTransportSummary(v.tpM, tp, false, v.router.IsSetupNode(tp.Remote())), nil
 }
 
 // RemoveTransport implements API.
@@ -500,6 +527,55 @@ func (v *Visor) RemoveTransport(tid uuid.UUID) error {
 	return nil
 }
 
+// Bonds implements API.
+func (v *Visor) Bonds() ([]*BondSummary, error) {
+	bonds := v.tpM.Bonds()
+
+	summaries := make([]*BondSummary, 0, len(bonds))
+	for _, b := range bonds {
+		summaries = append(summaries, newBondSummary(b))
+	}
+
+	return summaries, nil
+}
+
+// AddBond implements API.
+func (v *Visor) AddBond(remote cipher.PubKey, types []string) (*BondSummary, error) {
+	b, err := v.conf.SaveBond(v.tpM, remote, types)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBondSummary(b), nil
+}
+
+// RemoveBond implements API.
+func (v *Visor) RemoveBond(id uuid.UUID) error {
+	return v.conf.DeleteBond(v.tpM, id)
+}
+
+// TransportPolicy implements API.
+func (v *Visor) TransportPolicy() (transport.AcceptPolicy, error) {
+	return v.tpM.AcceptPolicy(), nil
+}
+
+// SetTransportPolicy implements API.
+func (v *Visor) SetTransportPolicy(policy transport.AcceptPolicy) error {
+	v.log.Infof("Saving transport accept policy %+v to config", policy)
+	return v.conf.UpdateAcceptPolicy(v.tpM, policy)
+}
+
+// PersistentTransports implements API.
+func (v *Visor) PersistentTransports() ([]transport.PersistentTransport, error) {
+	return v.tpM.PersistentTransports(), nil
+}
+
+// SetPersistentTransports implements API.
+func (v *Visor) SetPersistentTransports(pts []transport.PersistentTransport) error {
+	v.log.Infof("Saving %d persistent transports to config", len(pts))
+	return v.conf.UpdatePersistentTransports(v.tpM, pts)
+}
+
 // DiscoverTransportsByPK implements API.
 func (v *Visor) DiscoverTransportsByPK(pk cipher.PubKey) ([]*transport.EntryWithStatus, error) {
 	tpD := v.tpDiscClient()
@@ -525,8 +601,18 @@ func (v *Visor) DiscoverTransportByID(id uuid.UUID) (*transport.EntryWithStatus,
 }
 
 // RoutingRules implements API.
-func (v *Visor) RoutingRules() ([]routing.Rule, error) {
-	return v.router.Rules(), nil
+func (v *Visor) RoutingRules() ([]routing.RuleEntry, error) {
+	rules := v.router.Rules()
+
+	entries := make([]routing.RuleEntry, 0, len(rules))
+	for _, rule := range rules {
+		entries = append(entries, routing.RuleEntry{
+			Rule:  rule,
+			Stats: v.router.RuleStats(rule.KeyRouteID()),
+		})
+	}
+
+	return entries, nil
 }
 
 // RoutingRule implements API.
@@ -555,6 +641,8 @@ func (v *Visor) RouteGroups() ([]RouteGroupInfo, error) {
 			continue
 		}
 
+		setupPK, _ := v.router.RouteGroupSetupNode(rule.RouteDescriptor())
+
 		fwdRID := rule.NextRouteID()
 		rule, err := v.router.Rule(fwdRID)
 		if err != nil {
@@ -564,12 +652,21 @@ func (v *Visor) RouteGroups() ([]RouteGroupInfo, error) {
 		routegroups = append(routegroups, RouteGroupInfo{
 			ConsumeRule: rule,
 			FwdRule:     rule,
+			SetupNode:   setupPK,
 		})
 	}
 
 	return routegroups, nil
 }
 
+// TraceRoute implements API.
+func (v *Visor) TraceRoute(rid routing.RouteID) ([]routing.TraceHop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), TraceRouteTimeout)
+	defer cancel()
+
+	return v.router.TraceRoute(ctx, rid)
+}
+
 // Restart implements API.
 func (v *Visor) Restart() error {
 	if v.restartCtx == nil {
@@ -241,6 +241,7 @@ func (hv *Hypervisor) makeMux() chi.Router {
 				r.Get("/visors/{pk}/transports", hv.getTransports())
 				r.Post("/visors/{pk}/transports", hv.postTransport())
 				r.Get("/visors/{pk}/transports/{tid}", hv.getTransport())
+				r.Get("/visors/{pk}/transports/{tid}/history", hv.getTransportHistory())
 				r.Delete("/visors/{pk}/transports/{tid}", hv.deleteTransport())
 				r.Delete("/visors/{pk}/transports/", hv.deleteTransports())
 				r.Get("/visors/{pk}/routes", hv.getRoutes())
@@ -681,7 +682,13 @@ func (hv *Hypervisor) getTransports() http.HandlerFunc {
 			return
 		}
 
-		transports, err := ctx.API.Transports(qTypes, qPKs, qLogs)
+		qSince, err := timeFromQuery(r, "since", time.Time{})
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		transports, err := ctx.API.Transports(qTypes, qPKs, qLogs, qSince)
 		if err != nil {
 			httputil.WriteJSON(w, r, http.StatusInternalServerError, err)
 			return
@@ -725,6 +732,30 @@ func (hv *Hypervisor) getTransport() http.HandlerFunc {
 	})
 }
 
+func (hv *Hypervisor) getTransportHistory() http.HandlerFunc {
+	return hv.withCtx(hv.tpCtx, func(w http.ResponseWriter, r *http.Request, ctx *httpCtx) {
+		qSince, err := timeFromQuery(r, "since", time.Unix(0, 0))
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		qUntil, err := timeFromQuery(r, "until", time.Now())
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		history, err := ctx.API.TransportHistory(ctx.Tp.ID, qSince, qUntil)
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, history)
+	})
+}
+
 func (hv *Hypervisor) deleteTransport() http.HandlerFunc {
 	return hv.withCtx(hv.tpCtx, func(w http.ResponseWriter, r *http.Request, ctx *httpCtx) {
 		if err := ctx.API.RemoveTransport(ctx.Tp.ID); err != nil {
@@ -785,6 +816,7 @@ type routingRuleResp struct {
 	Key     routing.RouteID      `json:"key"`
 	Rule    string               `json:"rule"`
 	Summary *routing.RuleSummary `json:"rule_summary,omitempty"`
+	Stats   *routing.RuleStats   `json:"stats,omitempty"`
 }
 
 func makeRoutingRuleResp(key routing.RouteID, rule routing.Rule, summary bool) routingRuleResp {
@@ -800,6 +832,15 @@ func makeRoutingRuleResp(key routing.RouteID, rule routing.Rule, summary bool) r
 	return resp
 }
 
+func makeRoutingRuleEntryResp(entry routing.RuleEntry, summary bool) routingRuleResp {
+	resp := makeRoutingRuleResp(entry.Rule.KeyRouteID(), entry.Rule, summary)
+
+	stats := entry.Stats
+	resp.Stats = &stats
+
+	return resp
+}
+
 func (hv *Hypervisor) getRoutes() http.HandlerFunc {
 	return hv.withCtx(hv.visorCtx, func(w http.ResponseWriter, r *http.Request, ctx *httpCtx) {
 		qSummary, err := httputil.BoolFromQuery(r, "summary", false)
@@ -816,7 +857,7 @@ func (hv *Hypervisor) getRoutes() http.HandlerFunc {
 
 		resp := make([]routingRuleResp, len(rules))
 		for i, rule := range rules {
-			resp[i] = makeRoutingRuleResp(rule.KeyRouteID(), rule, qSummary)
+			resp[i] = makeRoutingRuleEntryResp(rule, qSummary)
 		}
 
 		httputil.WriteJSON(w, r, http.StatusOK, resp)
@@ -933,8 +974,8 @@ func (hv *Hypervisor) deleteRoutes() http.HandlerFunc {
 			}
 			routeID := routing.RouteID(ridUint64)
 			contains := false
-			for _, rule := range rules {
-				if rule.KeyRouteID() == routeID {
+			for _, entry := range rules {
+				if entry.Rule.KeyRouteID() == routeID {
 					contains = true
 				}
 			}
@@ -964,7 +1005,8 @@ func (hv *Hypervisor) deleteRoutes() http.HandlerFunc {
 
 type routeGroupResp struct {
 	routing.RuleConsumeFields
-	FwdRule routing.RuleForwardFields `json:"resp"`
+	FwdRule   routing.RuleForwardFields `json:"resp"`
+	SetupNode *cipher.PubKey            `json:"setup_node,omitempty"`
 }
 
 func makeRouteGroupResp(info RouteGroupInfo) routeGroupResp {
@@ -972,10 +1014,16 @@ func makeRouteGroupResp(info RouteGroupInfo) routeGroupResp {
 		return routeGroupResp{}
 	}
 
-	return routeGroupResp{
+	resp := routeGroupResp{
 		RuleConsumeFields: *info.ConsumeRule.Summary().ConsumeFields,
 		FwdRule:           *info.FwdRule.Summary().ForwardFields,
 	}
+
+	if !info.SetupNode.Null() {
+		resp.SetupNode = &info.SetupNode
+	}
+
+	return resp
 }
 
 func (hv *Hypervisor) getRouteGroups() http.HandlerFunc {
@@ -1370,6 +1418,18 @@ func strSliceFromQuery(r *http.Request, key string, defaultVal []string) []strin
 	return slice
 }
 
+// timeFromQuery parses RFC3339Nano-formatted timestamp of the query.
+func timeFromQuery(r *http.Request, key string, defaultVal time.Time) (time.Time, error) {
+	q := r.URL.Query().Get(key)
+	if q == "" {
+		return defaultVal, nil
+	}
+
+	q = strings.Replace(q, " ", "+", 1) // we need to put '+' again that was replaced in the query string
+
+	return time.Parse(time.RFC3339Nano, q)
+}
+
 func pkSliceFromQuery(r *http.Request, key string, defaultVal []cipher.PubKey) ([]cipher.PubKey, error) {
 	qPKs, ok := r.URL.Query()[key]
 	if !ok {
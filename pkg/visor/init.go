@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"sync"
@@ -26,6 +27,8 @@ import (
 	"github.com/skycoin/skywire/pkg/app/launcher"
 	"github.com/skycoin/skywire/pkg/routefinder/rfclient"
 	"github.com/skycoin/skywire/pkg/router"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/setup"
 	"github.com/skycoin/skywire/pkg/setup/setupclient"
 	"github.com/skycoin/skywire/pkg/skyenv"
 	"github.com/skycoin/skywire/pkg/snet"
@@ -166,10 +169,15 @@ func initTransport(v *Visor) bool {
 	var logS transport.LogStore
 	switch conf.LogStore.Type {
 	case visorconfig.FileLogStore:
-		logS, err = transport.FileTransportLogStore(conf.LogStore.Location)
+		logS, err = transport.FileTransportLogStore(conf.LogStore.Location, time.Duration(conf.LogStore.Retention))
 		if err != nil {
 			return report(fmt.Errorf("failed to create %s log store: %w", visorconfig.FileLogStore, err))
 		}
+	case visorconfig.BoltLogStore:
+		logS, err = transport.BoltTransportLogStore(conf.LogStore.Location, time.Duration(conf.LogStore.Retention))
+		if err != nil {
+			return report(fmt.Errorf("failed to create %s log store: %w", visorconfig.BoltLogStore, err))
+		}
 	case visorconfig.MemoryLogStore:
 		logS = transport.InMemoryTransportLogStore()
 	default:
@@ -177,15 +185,25 @@ func initTransport(v *Visor) bool {
 	}
 
 	tpMConf := transport.ManagerConfig{
-		PubKey:          v.conf.PK,
-		SecKey:          v.conf.SK,
-		DefaultVisors:   conf.TrustedVisors,
-		DiscoveryClient: tpdC,
-		LogStore:        logS,
+		PubKey:               v.conf.PK,
+		SecKey:               v.conf.SK,
+		DefaultVisors:        conf.TrustedVisors,
+		DiscoveryClient:      tpdC,
+		LogStore:             logS,
+		DataCaps:             conf.DataCaps,
+		PersistentTransports: conf.PersistentTransports,
+		Bonds:                conf.Bonds,
+	}
+	if conf.AcceptPolicy != nil {
+		tpMConf.AcceptPolicy = *conf.AcceptPolicy
 	}
 
 	tpM, err := transport.NewManager(v.MasterLogger().PackageLogger("transport_manager"), v.net, &tpMConf)
 	if err != nil {
+		if c, isCloser := logS.(io.Closer); isCloser {
+			v.log.WithError(c.Close()).Debug("Closed transport log store.")
+		}
+
 		return report(fmt.Errorf("failed to start transport manager: %w", err))
 	}
 
@@ -199,6 +217,30 @@ func initTransport(v *Visor) bool {
 		}
 	})
 
+	tpM.OnDataCapExceeded(func(e transport.DataCapEvent) {
+		data := appevent.DataCapExceededData{
+			TpType: e.Cap.TpType,
+			Period: e.Period,
+			Used:   e.Used,
+			Limit:  e.Cap.Daily,
+			Action: e.Cap.Action,
+		}
+		if e.Period == transport.DataCapMonthly {
+			data.Limit = e.Cap.Monthly
+		}
+		if !e.Cap.RemotePK.Null() {
+			data.RemotePK = e.Cap.RemotePK.Hex()
+		}
+		if data.Action == "" {
+			data.Action = transport.DataCapDenyRoutes
+		}
+
+		event := appevent.NewEvent(appevent.DataCapExceeded, data)
+		if err := v.ebc.Broadcast(context.Background(), event); err != nil {
+			v.log.WithError(err).Errorln("Failed to broadcast DataCapExceeded event")
+		}
+	})
+
 	ctx, cancel := context.WithCancel(context.Background())
 	wg := new(sync.WaitGroup)
 	wg.Add(1)
@@ -212,6 +254,12 @@ func initTransport(v *Visor) bool {
 		cancel()
 		ok := report(tpM.Close())
 		wg.Wait()
+
+		// pending logs of the closed transports are written by the log store on close
+		if c, isCloser := logS.(io.Closer); isCloser {
+			ok = report(c.Close()) && ok
+		}
+
 		return ok
 	})
 
@@ -224,20 +272,35 @@ func initRouter(v *Visor) bool {
 	report := v.makeReporter("router")
 	conf := v.conf.Routing
 	rfClient := rfclient.NewHTTP(conf.RouteFinder, time.Duration(conf.RouteFinderTimeout))
+	if conf.LocalRouteFinder {
+		rfClient = rfclient.NewWithFallback(rfClient, makeLocalRouteFinder(v))
+	}
+
+	rt, err := makeRoutingTable(v, conf.Table)
+	if err != nil {
+		return report(err)
+	}
 
 	rConf := router.Config{
-		Logger:           v.MasterLogger().PackageLogger("router"),
-		PubKey:           v.conf.PK,
-		SecKey:           v.conf.SK,
-		TransportManager: v.tpM,
-		RouteFinder:      rfClient,
-		RouteGroupDialer: setupclient.NewSetupNodeDialer(),
-		SetupNodes:       conf.SetupNodes,
-		RulesGCInterval:  0, // TODO
+		Logger:            v.MasterLogger().PackageLogger("router"),
+		PubKey:            v.conf.PK,
+		SecKey:            v.conf.SK,
+		TransportManager:  v.tpM,
+		RouteFinder:       rfClient,
+		RouteGroupDialer:  setupclient.NewSetupNodeDialer(),
+		SetupNodes:        conf.SetupNodes,
+		RulesGCInterval:   0, // TODO
+		RoutingTable:      rt,
+		RouteSetupTrusted: conf.RouteSetupTrusted,
+	}
+
+	if conf.DirectSetup {
+		rConf.DirectSetup = setup.NewDirectDialer()
 	}
 
 	r, err := router.New(v.net, &rConf)
 	if err != nil {
+		closeRoutingTable(v, rt)
 		return report(fmt.Errorf("failed to create router: %w", err))
 	}
 
@@ -256,6 +319,7 @@ func initRouter(v *Visor) bool {
 		cancel()
 		ok := report(r.Close())
 		wg.Wait()
+		closeRoutingTable(v, rt)
 		return ok
 	})
 
@@ -265,6 +329,66 @@ func initRouter(v *Visor) bool {
 	return report(nil)
 }
 
+// makeLocalRouteFinder makes route finder client which computes routes out of
+// the transports of the visor and the transports known to the transport discovery.
+func makeLocalRouteFinder(v *Visor) rfclient.Client {
+	localTps := func() []*transport.EntryWithStatus {
+		var entries []*transport.EntryWithStatus
+
+		v.tpM.WalkTransports(func(tp *transport.ManagedTransport) bool {
+			if tp.IsUp() {
+				entry := tp.Entry
+				e := &transport.EntryWithStatus{Entry: &entry, IsUp: true}
+
+				if stats := tp.ProbeStats(); stats.Samples > 0 {
+					e.Probe = &stats
+				}
+
+				entries = append(entries, e)
+			}
+
+			return true
+		})
+
+		return entries
+	}
+
+	return rfclient.NewLocal(v.conf.PK, localTps, v.tpM.Conf.DiscoveryClient)
+}
+
+func makeRoutingTable(v *Visor, conf *visorconfig.V1RoutingTable) (routing.Table, error) {
+	if conf == nil {
+		return routing.NewTable(), nil
+	}
+
+	switch conf.Type {
+	case visorconfig.MemoryRoutingTable, "":
+		return routing.NewTable(), nil
+	case visorconfig.BoltRoutingTable:
+		location := conf.Location
+		if location == "" {
+			location = skyenv.DefaultRoutingTableDB
+		}
+
+		rt, err := routing.NewBoltTable(v.MasterLogger().PackageLogger("routing_table"), location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s routing table: %w", visorconfig.BoltRoutingTable, err)
+		}
+
+		return rt, nil
+	default:
+		return nil, fmt.Errorf("invalid routing table type: %s", conf.Type)
+	}
+}
+
+func closeRoutingTable(v *Visor, rt routing.Table) {
+	if c, ok := rt.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			v.log.WithError(err).Warn("Failed to close routing table.")
+		}
+	}
+}
+
 func initDiscovery(v *Visor) bool {
 	report := v.makeReporter("discovery")
 
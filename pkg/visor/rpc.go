@@ -1,7 +1,6 @@
 package visor
 
 import (
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/rpc"
@@ -28,6 +27,9 @@ const (
 	// We keep it is less than the `HealthTimeout`, so that the outer call would
 	// definitely complete.
 	InnerHealthTimeout = 3 * time.Second
+	// TraceRouteTimeout defines timeout for tracing a route group. It's less than
+	// the `skyenv.DefaultRPCTimeout`, so that the RPC call would complete.
+	TraceRouteTimeout = 10 * time.Second
 )
 
 var (
@@ -119,13 +121,17 @@ func (r *RPC) LogsSince(in *AppLogsRequest, out *[]string) (err error) {
 
 // TransportSummary summarizes a Transport.
 type TransportSummary struct {
-	ID      uuid.UUID           `json:"id"`
-	Local   cipher.PubKey       `json:"local_pk"`
-	Remote  cipher.PubKey       `json:"remote_pk"`
-	Type    string              `json:"type"`
-	Log     *transport.LogEntry `json:"log,omitempty"`
-	IsSetup bool                `json:"is_setup"`
-	IsUp    bool                `json:"is_up"`
+	ID      uuid.UUID             `json:"id"`
+	Local   cipher.PubKey         `json:"local_pk"`
+	Remote  cipher.PubKey         `json:"remote_pk"`
+	Type    string                `json:"type"`
+	Log     *transport.LogEntry   `json:"log,omitempty"`
+	History []transport.LogBucket `json:"history,omitempty"`
+	IsSetup bool                  `json:"is_setup"`
+	IsUp    bool                  `json:"is_up"`
+
+	DataCapExceeded bool                  `json:"data_cap_exceeded,omitempty"`
+	Probe           *transport.ProbeStats `json:"probe,omitempty"`
 }
 
 func newTransportSummary(tm *transport.Manager, tp *transport.ManagedTransport, includeLogs, isSetup bool) *TransportSummary {
@@ -136,6 +142,11 @@ func newTransportSummary(tm *transport.Manager, tp *transport.ManagedTransport,
 		Type:    tp.Type(),
 		IsSetup: isSetup,
 		IsUp:    tp.IsUp(),
+
+		DataCapExceeded: tp.DataCapExceeded(),
+	}
+	if stats := tp.ProbeStats(); stats.Samples > 0 {
+		summary.Probe = &stats
 	}
 	if includeLogs {
 		summary.Log = tp.LogEntry
@@ -143,6 +154,29 @@ func newTransportSummary(tm *transport.Manager, tp *transport.ManagedTransport,
 	return summary
 }
 
+// BondSummary summarizes a bond of transports to a remote visor.
+type BondSummary struct {
+	ID      uuid.UUID     `json:"id"`
+	Remote  cipher.PubKey `json:"remote_pk"`
+	Types   []string      `json:"types,omitempty"`
+	Members []uuid.UUID   `json:"members"`
+	IsUp    bool          `json:"is_up"`
+}
+
+func newBondSummary(b *transport.Bond) *BondSummary {
+	summary := &BondSummary{
+		ID:      b.ID,
+		Remote:  b.Remote(),
+		Types:   b.Types(),
+		Members: make([]uuid.UUID, 0),
+	}
+	for _, tp := range b.Members() {
+		summary.Members = append(summary.Members, tp.Entry.ID)
+		summary.IsUp = summary.IsUp || tp.IsUp()
+	}
+	return summary
+}
+
 // ExtraSummary provides an extra summary of the AppNode.
 func (r *RPC) ExtraSummary(_ *struct{}, out *ExtraSummary) (err error) {
 	summary, err := r.visor.Summary()
@@ -167,11 +201,7 @@ func (r *RPC) ExtraSummary(_ *struct{}, out *ExtraSummary) (err error) {
 
 	extraRoutes := make([]routingRuleResp, 0, len(routes))
 	for _, route := range routes {
-		extraRoutes = append(extraRoutes, routingRuleResp{
-			Key:     route.KeyRouteID(),
-			Rule:    hex.EncodeToString(route),
-			Summary: route.Summary(),
-		})
+		extraRoutes = append(extraRoutes, makeRoutingRuleEntryResp(route, true))
 	}
 
 	*out = ExtraSummary{
@@ -321,13 +351,14 @@ type TransportsIn struct {
 	FilterTypes   []string
 	FilterPubKeys []cipher.PubKey
 	ShowLogs      bool
+	Since         time.Time
 }
 
 // Transports lists Transports of the Visor and provides a summary of each.
 func (r *RPC) Transports(in *TransportsIn, out *[]*TransportSummary) (err error) {
 	defer rpcutil.LogCall(r.log, "Transports", in)(out, &err)
 
-	transports, err := r.visor.Transports(in.FilterTypes, in.FilterPubKeys, in.ShowLogs)
+	transports, err := r.visor.Transports(in.FilterTypes, in.FilterPubKeys, in.ShowLogs, in.Since)
 	*out = transports
 
 	return err
@@ -345,6 +376,23 @@ func (r *RPC) Transport(in *uuid.UUID, out *TransportSummary) (err error) {
 	return err
 }
 
+// TransportHistoryIn is input for TransportHistory.
+type TransportHistoryIn struct {
+	ID    uuid.UUID
+	Since time.Time
+	Until time.Time
+}
+
+// TransportHistory obtains traffic history of Transport of given Transport ID within the time range.
+func (r *RPC) TransportHistory(in *TransportHistoryIn, out *[]transport.LogBucket) (err error) {
+	defer rpcutil.LogCall(r.log, "TransportHistory", in)(out, &err)
+
+	history, err := r.visor.TransportHistory(in.ID, in.Since, in.Until)
+	*out = history
+
+	return err
+}
+
 // AddTransportIn is input for AddTransport.
 type AddTransportIn struct {
 	RemotePK cipher.PubKey
@@ -372,6 +420,75 @@ func (r *RPC) RemoveTransport(tid *uuid.UUID, _ *struct{}) (err error) {
 	return r.visor.RemoveTransport(*tid)
 }
 
+// Bonds lists the bonds of transports of the visor.
+func (r *RPC) Bonds(_ *struct{}, out *[]*BondSummary) (err error) {
+	defer rpcutil.LogCall(r.log, "Bonds", nil)(out, &err)
+
+	bonds, err := r.visor.Bonds()
+	*out = bonds
+
+	return err
+}
+
+// AddBondIn is input for AddBond.
+type AddBondIn struct {
+	RemotePK cipher.PubKey
+	Types    []string
+}
+
+// AddBond bonds transports of the visor to a remote visor.
+func (r *RPC) AddBond(in *AddBondIn, out *BondSummary) (err error) {
+	defer rpcutil.LogCall(r.log, "AddBond", in)(out, &err)
+
+	b, err := r.visor.AddBond(in.RemotePK, in.Types)
+	if b != nil {
+		*out = *b
+	}
+
+	return err
+}
+
+// RemoveBond removes a bond of transports from the visor.
+func (r *RPC) RemoveBond(id *uuid.UUID, _ *struct{}) (err error) {
+	defer rpcutil.LogCall(r.log, "RemoveBond", id)(nil, &err)
+
+	return r.visor.RemoveBond(*id)
+}
+
+// TransportPolicy obtains the policy incoming transports are accepted by.
+func (r *RPC) TransportPolicy(_ *struct{}, out *transport.AcceptPolicy) (err error) {
+	defer rpcutil.LogCall(r.log, "TransportPolicy", nil)(out, &err)
+
+	policy, err := r.visor.TransportPolicy()
+	*out = policy
+
+	return err
+}
+
+// SetTransportPolicy sets the policy incoming transports are accepted by.
+func (r *RPC) SetTransportPolicy(in *transport.AcceptPolicy, _ *struct{}) (err error) {
+	defer rpcutil.LogCall(r.log, "SetTransportPolicy", in)(nil, &err)
+
+	return r.visor.SetTransportPolicy(*in)
+}
+
+// PersistentTransports obtains the transports the visor keeps established.
+func (r *RPC) PersistentTransports(_ *struct{}, out *[]transport.PersistentTransport) (err error) {
+	defer rpcutil.LogCall(r.log, "PersistentTransports", nil)(out, &err)
+
+	pts, err := r.visor.PersistentTransports()
+	*out = pts
+
+	return err
+}
+
+// SetPersistentTransports sets the transports the visor keeps established.
+func (r *RPC) SetPersistentTransports(in *[]transport.PersistentTransport, _ *struct{}) (err error) {
+	defer rpcutil.LogCall(r.log, "SetPersistentTransports", in)(nil, &err)
+
+	return r.visor.SetPersistentTransports(*in)
+}
+
 /*
 	<<< AVAILABLE TRANSPORTS >>>
 */
@@ -402,8 +519,8 @@ func (r *RPC) DiscoverTransportByID(id *uuid.UUID, out *transport.EntryWithStatu
 	<<< ROUTES MANAGEMENT >>>
 */
 
-// RoutingRules obtains all routing rules of the RoutingTable.
-func (r *RPC) RoutingRules(_ *struct{}, out *[]routing.Rule) (err error) {
+// RoutingRules obtains all routing rules of the RoutingTable along with their forwarding statistics.
+func (r *RPC) RoutingRules(_ *struct{}, out *[]routing.RuleEntry) (err error) {
 	defer rpcutil.LogCall(r.log, "RoutingRules", nil)(out, &err)
 
 	*out, err = r.visor.RoutingRules()
@@ -438,9 +555,11 @@ func (r *RPC) RemoveRoutingRule(key *routing.RouteID, _ *struct{}) (err error) {
 */
 
 // RouteGroupInfo is a human-understandable representation of a RouteGroup.
+// 'SetupNode' is the setup node which set the route group up, it's empty for the accepted route groups.
 type RouteGroupInfo struct {
 	ConsumeRule routing.Rule
 	FwdRule     routing.Rule
+	SetupNode   cipher.PubKey
 }
 
 // RouteGroups retrieves routegroups via rules of the routing table.
@@ -453,6 +572,16 @@ func (r *RPC) RouteGroups(_ *struct{}, out *[]RouteGroupInfo) (err error) {
 	return err
 }
 
+// TraceRoute traces a route group via the route ID of its consume rule.
+func (r *RPC) TraceRoute(rid *routing.RouteID, out *[]routing.TraceHop) (err error) {
+	defer rpcutil.LogCall(r.log, "TraceRoute", rid)(out, &err)
+
+	hops, err := r.visor.TraceRoute(*rid)
+	*out = hops
+
+	return err
+}
+
 /*
 	<<< VISOR MANAGEMENT >>>
 */
@@ -3,7 +3,6 @@ package visor
 import (
 	"context"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -216,12 +215,13 @@ func (rc *rpcClient) TransportTypes() ([]string, error) {
 }
 
 // Transports calls Transports.
-func (rc *rpcClient) Transports(types []string, pks []cipher.PubKey, logs bool) ([]*TransportSummary, error) {
+func (rc *rpcClient) Transports(types []string, pks []cipher.PubKey, logs bool, since time.Time) ([]*TransportSummary, error) {
 	transports := make([]*TransportSummary, 0)
 	err := rc.Call("Transports", &TransportsIn{
 		FilterTypes:   types,
 		FilterPubKeys: pks,
 		ShowLogs:      logs,
+		Since:         since,
 	}, &transports)
 	return transports, err
 }
@@ -233,6 +233,17 @@ func (rc *rpcClient) Transport(tid uuid.UUID) (*TransportSummary, error) {
 	return &summary, err
 }
 
+// TransportHistory calls TransportHistory.
+func (rc *rpcClient) TransportHistory(tid uuid.UUID, since, until time.Time) ([]transport.LogBucket, error) {
+	var history []transport.LogBucket
+	err := rc.Call("TransportHistory", &TransportHistoryIn{
+		ID:    tid,
+		Since: since,
+		Until: until,
+	}, &history)
+	return history, err
+}
+
 // AddTransport calls AddTransport.
 func (rc *rpcClient) AddTransport(remote cipher.PubKey, tpType string, public bool, timeout time.Duration) (*TransportSummary, error) {
 	var summary TransportSummary
@@ -251,6 +262,53 @@ func (rc *rpcClient) RemoveTransport(tid uuid.UUID) error {
 	return rc.Call("RemoveTransport", &tid, &struct{}{})
 }
 
+// Bonds calls Bonds.
+func (rc *rpcClient) Bonds() ([]*BondSummary, error) {
+	var bonds []*BondSummary
+	err := rc.Call("Bonds", &struct{}{}, &bonds)
+	return bonds, err
+}
+
+// AddBond calls AddBond.
+func (rc *rpcClient) AddBond(remote cipher.PubKey, types []string) (*BondSummary, error) {
+	var summary BondSummary
+	err := rc.Call("AddBond", &AddBondIn{
+		RemotePK: remote,
+		Types:    types,
+	}, &summary)
+
+	return &summary, err
+}
+
+// RemoveBond calls RemoveBond.
+func (rc *rpcClient) RemoveBond(id uuid.UUID) error {
+	return rc.Call("RemoveBond", &id, &struct{}{})
+}
+
+// TransportPolicy calls TransportPolicy.
+func (rc *rpcClient) TransportPolicy() (transport.AcceptPolicy, error) {
+	var policy transport.AcceptPolicy
+	err := rc.Call("TransportPolicy", &struct{}{}, &policy)
+	return policy, err
+}
+
+// SetTransportPolicy calls SetTransportPolicy.
+func (rc *rpcClient) SetTransportPolicy(policy transport.AcceptPolicy) error {
+	return rc.Call("SetTransportPolicy", &policy, &struct{}{})
+}
+
+// PersistentTransports calls PersistentTransports.
+func (rc *rpcClient) PersistentTransports() ([]transport.PersistentTransport, error) {
+	var pts []transport.PersistentTransport
+	err := rc.Call("PersistentTransports", &struct{}{}, &pts)
+	return pts, err
+}
+
+// SetPersistentTransports calls SetPersistentTransports.
+func (rc *rpcClient) SetPersistentTransports(pts []transport.PersistentTransport) error {
+	return rc.Call("SetPersistentTransports", &pts, &struct{}{})
+}
+
 func (rc *rpcClient) DiscoverTransportsByPK(pk cipher.PubKey) ([]*transport.EntryWithStatus, error) {
 	entries := make([]*transport.EntryWithStatus, 0)
 	err := rc.Call("DiscoverTransportsByPK", &pk, &entries)
@@ -264,8 +322,8 @@ func (rc *rpcClient) DiscoverTransportByID(id uuid.UUID) (*transport.EntryWithSt
 }
 
 // RoutingRules calls RoutingRules.
-func (rc *rpcClient) RoutingRules() ([]routing.Rule, error) {
-	entries := make([]routing.Rule, 0)
+func (rc *rpcClient) RoutingRules() ([]routing.RuleEntry, error) {
+	entries := make([]routing.RuleEntry, 0)
 	err := rc.Call("RoutingRules", &struct{}{}, &entries)
 	return entries, err
 }
@@ -294,6 +352,13 @@ func (rc *rpcClient) RouteGroups() ([]RouteGroupInfo, error) {
 	return routegroups, err
 }
 
+// TraceRoute calls TraceRoute.
+func (rc *rpcClient) TraceRoute(rid routing.RouteID) ([]routing.TraceHop, error) {
+	var hops []routing.TraceHop
+	err := rc.Call("TraceRoute", &rid, &hops)
+	return hops, err
+}
+
 // Restart calls Restart.
 func (rc *rpcClient) Restart() error {
 	return rc.Call("Restart", &struct{}{}, &struct{}{})
@@ -562,11 +627,7 @@ func (mc *mockRPCClient) ExtraSummary() (*ExtraSummary, error) {
 
 	extraRoutes := make([]routingRuleResp, 0, len(routes))
 	for _, route := range routes {
-		extraRoutes = append(extraRoutes, routingRuleResp{
-			Key:     route.KeyRouteID(),
-			Rule:    hex.EncodeToString(route),
-			Summary: route.Summary(),
-		})
+		extraRoutes = append(extraRoutes, makeRoutingRuleEntryResp(route, true))
 	}
 
 	extraSummary := &ExtraSummary{
@@ -714,7 +775,7 @@ func (mc *mockRPCClient) TransportTypes() ([]string, error) {
 }
 
 // Transports implements API.
-func (mc *mockRPCClient) Transports(types []string, pks []cipher.PubKey, logs bool) ([]*TransportSummary, error) {
+func (mc *mockRPCClient) Transports(types []string, pks []cipher.PubKey, logs bool, _ time.Time) ([]*TransportSummary, error) {
 	var summaries []*TransportSummary
 	err := mc.do(false, func() error {
 		for _, tp := range mc.s.Transports {
@@ -765,6 +826,15 @@ func (mc *mockRPCClient) Transport(tid uuid.UUID) (*TransportSummary, error) {
 	return &summary, err
 }
 
+// TransportHistory implements API. Mock transports carry no traffic.
+func (mc *mockRPCClient) TransportHistory(tid uuid.UUID, _, _ time.Time) ([]transport.LogBucket, error) {
+	if _, err := mc.Transport(tid); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
 // AddTransport implements API.
 func (mc *mockRPCClient) AddTransport(remote cipher.PubKey, tpType string, _ bool, _ time.Duration) (*TransportSummary, error) {
 	summary := &TransportSummary{
@@ -793,6 +863,41 @@ func (mc *mockRPCClient) RemoveTransport(tid uuid.UUID) error {
 	})
 }
 
+// Bonds implements API.
+func (mc *mockRPCClient) Bonds() ([]*BondSummary, error) {
+	return nil, ErrNotImplemented
+}
+
+// AddBond implements API.
+func (mc *mockRPCClient) AddBond(cipher.PubKey, []string) (*BondSummary, error) {
+	return nil, ErrNotImplemented
+}
+
+// RemoveBond implements API.
+func (mc *mockRPCClient) RemoveBond(uuid.UUID) error {
+	return ErrNotImplemented
+}
+
+// TransportPolicy implements API.
+func (mc *mockRPCClient) TransportPolicy() (transport.AcceptPolicy, error) {
+	return transport.AcceptPolicy{}, ErrNotImplemented
+}
+
+// SetTransportPolicy implements API.
+func (mc *mockRPCClient) SetTransportPolicy(transport.AcceptPolicy) error {
+	return ErrNotImplemented
+}
+
+// PersistentTransports implements API.
+func (mc *mockRPCClient) PersistentTransports() ([]transport.PersistentTransport, error) {
+	return nil, ErrNotImplemented
+}
+
+// SetPersistentTransports implements API.
+func (mc *mockRPCClient) SetPersistentTransports([]transport.PersistentTransport) error {
+	return ErrNotImplemented
+}
+
 func (mc *mockRPCClient) DiscoverTransportsByPK(cipher.PubKey) ([]*transport.EntryWithStatus, error) {
 	return nil, ErrNotImplemented
 }
@@ -802,8 +907,18 @@ func (mc *mockRPCClient) DiscoverTransportByID(uuid.UUID) (*transport.EntryWithS
 }
 
 // RoutingRules implements API.
-func (mc *mockRPCClient) RoutingRules() ([]routing.Rule, error) {
-	return mc.rt.AllRules(), nil
+func (mc *mockRPCClient) RoutingRules() ([]routing.RuleEntry, error) {
+	rules := mc.rt.AllRules()
+
+	entries := make([]routing.RuleEntry, 0, len(rules))
+	for _, rule := range rules {
+		entries = append(entries, routing.RuleEntry{
+			Rule:  rule,
+			Stats: mc.rt.RuleStats(rule.KeyRouteID()),
+		})
+	}
+
+	return entries, nil
 }
 
 // RoutingRule implements API.
@@ -846,6 +961,11 @@ func (mc *mockRPCClient) RouteGroups() ([]RouteGroupInfo, error) {
 	return routeGroups, nil
 }
 
+// TraceRoute implements API.
+func (mc *mockRPCClient) TraceRoute(routing.RouteID) ([]routing.TraceHop, error) {
+	return nil, ErrNotImplemented
+}
+
 // Restart implements API.
 func (mc *mockRPCClient) Restart() error {
 	return nil
@@ -33,6 +33,9 @@ func MakeBaseConfig(common *Common) *V1 {
 		SetupNodes:         []cipher.PubKey{skyenv.MustPK(skyenv.DefaultSetupPK)},
 		RouteFinder:        skyenv.DefaultRouteFinderAddr,
 		RouteFinderTimeout: DefaultTimeout,
+		Table: &V1RoutingTable{
+			Type: MemoryRoutingTable,
+		},
 	}
 	conf.Launcher = &V1Launcher{
 		Discovery: &V1AppDisc{
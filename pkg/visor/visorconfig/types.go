@@ -10,6 +10,13 @@ import (
 const (
 	FileLogStore   = "file"
 	MemoryLogStore = "memory"
+	BoltLogStore   = "bbolt"
+)
+
+// Routing table types.
+const (
+	MemoryRoutingTable = "memory"
+	BoltRoutingTable   = "bbolt"
 )
 
 const (
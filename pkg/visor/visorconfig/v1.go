@@ -5,10 +5,12 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/skycoin/dmsg/cipher"
 
 	"github.com/skycoin/skywire/pkg/app/launcher"
 	"github.com/skycoin/skywire/pkg/snet"
+	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/visor/hypervisorconfig"
 )
 
@@ -63,13 +65,24 @@ type V1Transport struct {
 	AddressResolver string          `json:"address_resolver"`
 	LogStore        *V1LogStore     `json:"log_store"`
 	TrustedVisors   []cipher.PubKey `json:"trusted_visors"`
+	// DataCaps limit daily and monthly traffic of transports.
+	DataCaps []transport.DataCap `json:"data_caps,omitempty"`
+	// AcceptPolicy determines which incoming transports are accepted.
+	AcceptPolicy *transport.AcceptPolicy `json:"accept_policy,omitempty"`
+	// PersistentTransports are kept established, they are re-created once they are deleted or fail.
+	PersistentTransports []transport.PersistentTransport `json:"persistent_transports,omitempty"`
+	// Bonds bond the transports to remote visors, routing rules may refer to them.
+	Bonds []transport.BondConfig `json:"bonds,omitempty"`
 }
 
 // V1LogStore configures a LogStore.
 type V1LogStore struct {
-	// Type defines the log store type. Valid values: file, memory.
+	// Type defines the log store type. Valid values: file, memory, bbolt.
+	// The bbolt log store moves log files of the file log store of the same location to its database.
 	Type     string `json:"type"`
 	Location string `json:"location"`
+	// Retention is the time the hourly traffic history of transports is kept for, 30 days if unset.
+	Retention Duration `json:"retention,omitempty"`
 }
 
 // V1Routing configures routing.
@@ -77,6 +90,21 @@ type V1Routing struct {
 	SetupNodes         []cipher.PubKey `json:"setup_nodes,omitempty"`
 	RouteFinder        string          `json:"route_finder"`
 	RouteFinderTimeout Duration        `json:"route_finder_timeout,omitempty"`
+	// LocalRouteFinder enables computing routes locally whenever the route finder is unreachable.
+	LocalRouteFinder bool `json:"local_route_finder,omitempty"`
+	// RouteSetupTrusted may set routes up via the visor without a setup node.
+	RouteSetupTrusted []cipher.PubKey `json:"route_setup_trusted,omitempty"`
+	// DirectSetup makes the visor set its routes up by itself instead of using setup nodes.
+	// Routes only go via intermediaries of RouteSetupTrusted, they and the remote have to trust the visor too.
+	DirectSetup bool            `json:"direct_setup,omitempty"`
+	Table       *V1RoutingTable `json:"table,omitempty"`
+}
+
+// V1RoutingTable configures a routing table.
+type V1RoutingTable struct {
+	// Type defines the routing table type. Valid values: memory, bbolt.
+	Type     string `json:"type"`
+	Location string `json:"location,omitempty"`
 }
 
 // V1UptimeTracker configures uptime tracker.
@@ -167,6 +195,75 @@ func (v1 *V1) UpdateAppArg(launch *launcher.Launcher, appName, argName string, v
 	return v1.flush(v1)
 }
 
+// UpdateAcceptPolicy sets the policy incoming transports are accepted by within the config and also the given
+// transport manager. The updated config gets flushed to file.
+func (v1 *V1) UpdateAcceptPolicy(tpM *transport.Manager, policy transport.AcceptPolicy) error {
+	v1.mu.Lock()
+	defer v1.mu.Unlock()
+
+	v1.Transport.AcceptPolicy = &policy
+	tpM.SetAcceptPolicy(policy)
+
+	return v1.flush(v1)
+}
+
+// UpdatePersistentTransports sets the transports to keep established within the config and also the given
+// transport manager. The updated config gets flushed to file.
+func (v1 *V1) UpdatePersistentTransports(tpM *transport.Manager, pts []transport.PersistentTransport) error {
+	v1.mu.Lock()
+	defer v1.mu.Unlock()
+
+	if err := tpM.SetPersistentTransports(pts); err != nil {
+		return err
+	}
+
+	v1.Transport.PersistentTransports = tpM.PersistentTransports()
+
+	return v1.flush(v1)
+}
+
+// SaveBond saves the bond to `remote` of `types` within the given transport manager and also the config.
+// The updated config gets flushed to file.
+func (v1 *V1) SaveBond(tpM *transport.Manager, remote cipher.PubKey, types []string) (*transport.Bond, error) {
+	v1.mu.Lock()
+	defer v1.mu.Unlock()
+
+	b, err := tpM.SaveBond(remote, types)
+	if err != nil {
+		return nil, err
+	}
+
+	v1.Transport.Bonds = bondConfigs(tpM)
+
+	return b, v1.flush(v1)
+}
+
+// DeleteBond deletes the bond of the given ID within the given transport manager and also the config.
+// The updated config gets flushed to file.
+func (v1 *V1) DeleteBond(tpM *transport.Manager, id uuid.UUID) error {
+	v1.mu.Lock()
+	defer v1.mu.Unlock()
+
+	if err := tpM.DeleteBond(id); err != nil {
+		return err
+	}
+
+	v1.Transport.Bonds = bondConfigs(tpM)
+
+	return v1.flush(v1)
+}
+
+func bondConfigs(tpM *transport.Manager) []transport.BondConfig {
+	bonds := tpM.Bonds()
+	confs := make([]transport.BondConfig, 0, len(bonds))
+
+	for _, b := range bonds {
+		confs = append(confs, b.Config())
+	}
+
+	return confs
+}
+
 // updateStringArg updates the cli non-boolean flag of the specified app config and also within the launcher.
 // It removes argName from app args if value is an empty string.
 // The updated config gets flushed to file if there are any changes.